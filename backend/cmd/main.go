@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/Cheasezz/anSpace/backend/config"
 	"github.com/Cheasezz/anSpace/backend/internal/app"
@@ -30,5 +31,7 @@ func main() {
 		log.Fatalf("Config error: %s", err)
 	}
 
-	app.Run(cfg)
+	if err := app.Execute(cfg, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -9,11 +9,18 @@ import (
 
 type Config struct {
 	HTTP         `yaml:"http"`
+	Storage      `yaml:"storage"`
 	PG           `yaml:"pg"`
 	Hasher       `yaml:"hasher"`
 	TokenManager `yaml:"token_manager"`
 	Log          `yaml:"logger"`
 	EmailSender  `yaml:"email_sender"`
+	Sweeper      `yaml:"sweeper"`
+	Revocation   `yaml:"revocation"`
+	Lockout      `yaml:"lockout"`
+	RateLimit    `yaml:"rate_limit"`
+	OAuth        `yaml:"oauth"`
+	Users        `yaml:"users"`
 }
 
 type HTTP struct {
@@ -23,20 +30,51 @@ type HTTP struct {
 	CookieHost      string   `env-required:"true" yaml:"cookie_host" env:"COOKIE_HOST"`
 }
 
+type Storage struct {
+	// postgres or memory. Memory storage loses data on restart and suits
+	// single instance only, it is meant for local runs and tests without database.
+	// It isn't for concurrent use: every repo call waits for running unit of work,
+	// including email sent inside it.
+	Driver string `yaml:"driver" env:"STORAGE_DRIVER" env-default:"postgres"`
+}
+
 type PG struct {
-	PoolMax    int    `env-required:"true" yaml:"pool_max" env:"PG_POOL_MAX"`
-	URL        string `env-required:"true" yaml:"pg_url"   env:"PG_URL"`
-	Schema_Url string `env-required:"true" yaml:"schema_url"   env:"SCHEMA_URL"`
+	PoolMax int    `env-required:"true" yaml:"pool_max" env:"PG_POOL_MAX"`
+	URL     string `env-required:"true" yaml:"pg_url"   env:"PG_URL"`
+	// Directory with migrations to run instead of ones embedded into binary.
+	SchemaDir string `yaml:"schema_dir" env:"SCHEMA_DIR"`
+	// Apply pending migrations on server start. Disable to run migrate command separately.
+	AutoMigrate bool `yaml:"auto_migrate" env:"PG_AUTO_MIGRATE" env-default:"true"`
 }
 
 type Hasher struct {
-	Salt string `env-required:"true" yaml:"salt" env:"PASS_SALT"`
+	Algorithm         string `yaml:"algorithm" env:"PASS_HASH_ALG" env-default:"argon2id"`
+	Argon2Memory      uint32 `yaml:"argon2_memory" env:"PASS_ARGON2_MEMORY"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env:"PASS_ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env:"PASS_ARGON2_PARALLELISM"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env:"PASS_BCRYPT_COST"`
+	// Salt of legacy sha1 hashes, used only to verify and upgrade them.
+	Salt string `yaml:"salt" env:"PASS_SALT"`
 }
 
 type TokenManager struct {
-	SigningKey      string        `env-required:"true" yaml:"signing_key" env:"SIGNING_KEY"`
+	// HS256, RS256 or EdDSA.
+	SigningMethod string `yaml:"signing_method" env:"SIGNING_METHOD" env-default:"HS256"`
+	// Shared secret for HS256. With asymmetric method it is used only to verify old HS256 tokens.
+	SigningKey string `yaml:"signing_key" env:"SIGNING_KEY"`
+	// PEM file with active private key for RS256/EdDSA.
+	PrivateKeyFile string `yaml:"private_key_file" env:"PRIVATE_KEY_FILE"`
+	// PEM files with public keys of retired signing keys. Tokens signed by them are still accepted.
+	PublicKeyFiles []string `yaml:"public_key_files" env:"PUBLIC_KEY_FILES"`
+	// iss and aud claims of access tokens, tokens with other values are rejected.
+	Issuer          string        `yaml:"issuer" env:"TOKEN_ISSUER" env-default:"anspace"`
+	Audience        string        `yaml:"audience" env:"TOKEN_AUDIENCE" env-default:"anspace"`
 	AccessTokenTTL  time.Duration `env-required:"true" yaml:"atttl" env:"ATTL"`
 	RefreshTokenTTL time.Duration `env-required:"true" yaml:"rtttl" env:"RTTL"`
+	// TTL of mfa pending token, time to enter second factor code after password.
+	MFATokenTTL time.Duration `yaml:"mfa_token_ttl" env:"MFA_TOKEN_TTL" env-default:"5m"`
+	// Oldest sessions of user are evicted on login when cap is exceeded. Zero disables cap.
+	MaxSessionsPerUser int `yaml:"max_sessions_per_user" env:"MAX_SESSIONS_PER_USER" env-default:"10"`
 }
 
 type Log struct {
@@ -51,6 +89,78 @@ type EmailSender struct {
 	AltSenderName string `env-required:"true" yaml:"alt_sender_name" env:"ALT_SENDER_NAME"`
 }
 
+type Sweeper struct {
+	Interval time.Duration `yaml:"interval" env:"SWEEP_INTERVAL" env-default:"1h"`
+}
+
+type Revocation struct {
+	// How often revoked sessions written by other instances are loaded from db.
+	SyncInterval time.Duration `yaml:"sync_interval" env:"REVOCATION_SYNC_INTERVAL" env-default:"10s"`
+}
+
+// Lockout of sign in and password reset code requests after repeated attempts
+// from one email or ip. Lock lasts BaseDelay and doubles with every next failure up to MaxDelay.
+type Lockout struct {
+	// memory (single instance) or postgres.
+	Storage          string        `yaml:"storage" env:"LOCKOUT_STORAGE" env-default:"memory"`
+	MaxAttempts      int           `yaml:"max_attempts" env:"LOCKOUT_MAX_ATTEMPTS" env-default:"5"`
+	MaxAttemptsPerIP int           `yaml:"max_attempts_per_ip" env:"LOCKOUT_MAX_ATTEMPTS_PER_IP" env-default:"20"`
+	BaseDelay        time.Duration `yaml:"base_delay" env:"LOCKOUT_BASE_DELAY" env-default:"1m"`
+	MaxDelay         time.Duration `yaml:"max_delay" env:"LOCKOUT_MAX_DELAY" env-default:"1h"`
+	// Failures older than window are forgotten.
+	Window time.Duration `yaml:"window" env:"LOCKOUT_WINDOW" env-default:"1h"`
+}
+
+// Token bucket limits of http requests by route group name.
+// Requests are counted per user when authenticated and per ip otherwise,
+// token refresh ("refresh" group) is counted per session.
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"true"`
+	// Group without rule isn't limited.
+	Groups map[string]RateLimitRule `yaml:"groups"`
+}
+
+type RateLimitRule struct {
+	// Tokens added to bucket per second.
+	Rate float64 `yaml:"rate"`
+	// Bucket capacity, max requests at once.
+	Burst int `yaml:"burst"`
+}
+
+type OAuth struct {
+	// Time for user to authorize on provider page.
+	StateTTL  time.Duration `yaml:"state_ttl" env:"OAUTH_STATE_TTL" env-default:"10m"`
+	Shikimori OAuthProvider `yaml:"shikimori" env-prefix:"SHIKIMORI_"`
+	Kinopoisk OAuthProvider `yaml:"kinopoisk" env-prefix:"KINOPOISK_"`
+	Litres    OAuthProvider `yaml:"litres" env-prefix:"LITRES_"`
+}
+
+// OAuth2 or OIDC client of identity provider. Provider without client id is disabled.
+type OAuthProvider struct {
+	ClientID     string `yaml:"client_id" env:"CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"CLIENT_SECRET"`
+	AuthURL      string `yaml:"auth_url" env:"AUTH_URL"`
+	TokenURL     string `yaml:"token_url" env:"TOKEN_URL"`
+	// Api url of user authorized the client, userinfo endpoint for OIDC.
+	ProfileURL string `yaml:"profile_url" env:"PROFILE_URL"`
+	// Fields of profile JSON with user id and name, sub and preferred_username for OIDC.
+	IdField       string `yaml:"id_field" env:"ID_FIELD" env-default:"id"`
+	UsernameField string `yaml:"username_field" env:"USERNAME_FIELD" env-default:"username"`
+	// Frontend page that passes code and state to callback endpoint.
+	RedirectURL string   `yaml:"redirect_url" env:"REDIRECT_URL"`
+	Scopes      []string `yaml:"scopes" env:"SCOPES"`
+	UserAgent   string   `yaml:"user_agent" env:"USER_AGENT"`
+}
+
+type Users struct {
+	// Min time between username changes. First change of default username isn't limited.
+	UsernameChangeInterval time.Duration `yaml:"username_change_interval" env:"USERNAME_CHANGE_INTERVAL" env-default:"720h"`
+	// Time account deleted by user can be restored, after it account is deleted by sweeper.
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" env:"DELETION_GRACE_PERIOD" env-default:"720h"`
+	// Frontend page cancellation link from email leads to, email and code are added as query params.
+	DeletionCancelURL string `yaml:"deletion_cancel_url" env:"DELETION_CANCEL_URL" env-default:"http://localhost:5173/account/restore"`
+}
+
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
 
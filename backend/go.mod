@@ -80,7 +80,7 @@ require (
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	github.com/vgarvardt/pgx-google-uuid/v5 v5.6.0
 	golang.org/x/arch v0.12.0 // indirect
-	golang.org/x/crypto v0.29.0 // indirect
+	golang.org/x/crypto v0.29.0
 	golang.org/x/net v0.31.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
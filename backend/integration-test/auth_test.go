@@ -8,7 +8,7 @@ import (
 )
 
 func (s *APITestSuite) TestSignUp() {
-	_, err := s.db.Pool.Exec(context.Background(), "truncate users, users_sessions")
+	_, err := s.db.Pool.Exec(context.Background(), "truncate users, users_sessions, codes, revoked_sessions")
 	if err != nil {
 		s.logger.Error("db exec error: %s", err.Error())
 	}
@@ -35,6 +35,31 @@ func (s *APITestSuite) TestSignUp() {
 
 }
 
+func (s *APITestSuite) TestSignUpEmailTaken() {
+	inputBody := `{"Email": "Cheasezz@gmail.com", "Password": "qwerty123456"}`
+	r := s.Require()
+
+	// User is created by SetupTest
+	resp, err := http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signup", "json", bytes.NewBufferString(inputBody))
+	r.NoError(err)
+	st, _ := io.ReadAll(resp.Body)
+
+	r.Equal(http.StatusConflict, resp.StatusCode)
+	r.Equal(`{"code":"email_taken","message":"email is already taken"}`, string(st))
+}
+
+func (s *APITestSuite) TestSignInWrongPassword() {
+	inputSignIn := `{"Email": "Cheasezz@gmail.com", "Password": "qwerty1234567"}`
+	r := s.Require()
+
+	resp, err := http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signin", "json", bytes.NewBufferString(inputSignIn))
+	r.NoError(err)
+	st, _ := io.ReadAll(resp.Body)
+
+	r.Equal(http.StatusUnauthorized, resp.StatusCode)
+	r.Equal(`{"code":"invalid_credentials","message":"invalid email or password"}`, string(st))
+}
+
 func (s *APITestSuite) TestSignIn() {
 	inputSignIn := `{"Email": "Cheasezz@gmail.com", "Password": "qwerty123456"}`
 	r := s.Require()
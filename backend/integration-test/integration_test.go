@@ -23,6 +23,10 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+type emailSenderStub struct{}
+
+func (emailSenderStub) Send(to, message string) error { return nil }
+
 type APITestSuite struct {
 	suite.Suite
 
@@ -53,27 +57,42 @@ func (s *APITestSuite) SetupSuite() {
 		l.Fatal("failed initialize db: %s", err.Error())
 	}
 
-	app.DBMigrate(cfg.PG, l)
+	if err := app.DBMigrate(cfg.PG, l); err != nil {
+		l.Fatal("failed migrate db: %s", err.Error())
+	}
 
-	hasher := hasher.NewSHA1Hasher(cfg.Hasher)
+	hasher, err := hasher.NewHasher(cfg.Hasher)
+	if err != nil {
+		l.Fatal("failed initialize hasher: %s", err.Error())
+	}
 	tokenManager, err := auth.NewManager(cfg.TokenManager)
 	if err != nil {
 		l.Fatal("failed initialize tokenManager: %s", err.Error())
 	}
 
-	repos := repositories.NewRepositories(psql)
+	repos, err := repositories.NewRepositories(cfg.Storage.Driver, psql, cfg.Lockout.Storage)
+	if err != nil {
+		l.Fatal("failed initialize repositories: %s", err.Error())
+	}
 
 	services := service.NewServices(service.Deps{
-		Repos:        repos,
-		Hasher:       hasher,
-		TokenManager: tokenManager,
+		Repos:              repos,
+		Hasher:             hasher,
+		TokenManager:       tokenManager,
+		EmailSender:        emailSenderStub{},
+		MaxSessionsPerUser: cfg.TokenManager.MaxSessionsPerUser,
+		AccessTokenTTL:     cfg.TokenManager.AccessTokenTTL,
+		Lockout:            cfg.Lockout,
+		OAuth:              cfg.OAuth,
+		Users:              cfg.Users,
 	})
 
 	handlers := httpHandlers.NewHandlers(v1.Deps{
-		Services:     services,
-		TokenManager: tokenManager,
-		ConfigHTTP:   cfg.HTTP,
-		Log:          l,
+		Services:        services,
+		TokenManager:    tokenManager,
+		ConfigHTTP:      cfg.HTTP,
+		ConfigRateLimit: cfg.RateLimit,
+		Log:             l,
 	})
 
 	server := httpserver.NewServer(cfg.HTTP, handlers.Init())
@@ -88,7 +107,7 @@ func (s *APITestSuite) SetupSuite() {
 
 }
 func (s *APITestSuite) SetupTest() {
-	_, err := s.db.Pool.Exec(context.Background(), "truncate users, users_sessions")
+	_, err := s.db.Pool.Exec(context.Background(), "truncate users, users_sessions, codes, revoked_sessions, login_lockouts, linked_accounts, oauth_states, used_mfa_tokens")
 	if err != nil {
 		s.logger.Error("db exec error: %s", err.Error())
 	}
@@ -116,6 +135,19 @@ func (s *APITestSuite) SetupTest() {
 	r.Contains(string(st), `{"accessToken":`)
 	r.Equal(resp.Cookies()[0].Name, "RefreshToken")
 
+	// Most routes require verified email. Email state is carried in
+	// access token, so tokens are refreshed after verification.
+	_, err = s.db.Pool.Exec(context.Background(), `update users set email_verified=true where email='Cheasezz@gmail.com'`)
+	r.NoError(err)
+	req, err := http.NewRequest("POST", "http://"+s.server.HttpServer.Addr+"/api/v1/auth/refresh", nil)
+	r.NoError(err)
+	req.AddCookie(&http.Cookie{Name: "RefreshToken", Value: s.userCookie})
+	resp, err = http.DefaultClient.Do(req)
+	r.NoError(err)
+	r.Equal(http.StatusOK, resp.StatusCode)
+	st, _ = io.ReadAll(resp.Body)
+	s.userCookie = resp.Cookies()[0].Value
+	s.accessToken = fmt.Sprintf("Bearer %s", strings.Split(strings.Split(string(st), ":")[1], `"`)[1])
 }
 func (s *APITestSuite) TearDownTest() {
 
@@ -0,0 +1,20 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/repotest"
+)
+
+// Postgres repositories must pass the same conformance suite as memory ones.
+func (s *APITestSuite) TestRepositoryConformance() {
+	repotest.Run(s.T(), func(t *testing.T) *repositories.Repositories {
+		_, err := s.db.Pool.Exec(context.Background(), "truncate users, users_sessions, codes, revoked_sessions, login_lockouts, linked_accounts, oauth_states, used_mfa_tokens")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.repos
+	})
+}
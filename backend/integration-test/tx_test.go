@@ -0,0 +1,65 @@
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+)
+
+func (s *APITestSuite) TestTxManager() {
+	r := s.Require()
+	ctx := context.Background()
+
+	txManager := repositories.NewTxManager(s.db)
+	repo := psql.NewAuthPostgres(s.db)
+	errAbort := errors.New("abort")
+
+	createUser := func(ctx context.Context, email string) {
+		_, err := repo.CreateUser(ctx, core.AuthCredentials{Email: email, Password: "hash"})
+		r.NoError(err)
+	}
+	userExists := func(email string) bool {
+		_, err := repo.GetUserByEmail(ctx, email)
+		if errors.Is(err, psql.ErrNotFound) {
+			return false
+		}
+		r.NoError(err)
+		return true
+	}
+
+	// Error rolls back every call of unit of work
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		createUser(ctx, "Rollback@gmail.com")
+		r.True(userExists("Cheasezz@gmail.com"))
+		return errAbort
+	})
+	r.ErrorIs(err, errAbort)
+	r.False(userExists("Rollback@gmail.com"))
+
+	r.PanicsWithValue("boom", func() {
+		txManager.WithinTx(ctx, func(ctx context.Context) error {
+			createUser(ctx, "Panic@gmail.com")
+			panic("boom")
+		})
+	})
+	r.False(userExists("Panic@gmail.com"))
+
+	// Nested unit of work joins outer one
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		createUser(ctx, "Commit@gmail.com")
+		return txManager.WithinTx(ctx, func(ctx context.Context) error {
+			return repo.SetCode(ctx, core.CodeCredentials{
+				Email:     "Commit@gmail.com",
+				Code:      "code",
+				CodeType:  core.CodeTypeEmailVerify,
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+		})
+	})
+	r.NoError(err)
+	r.True(userExists("Commit@gmail.com"))
+}
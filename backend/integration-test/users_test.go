@@ -0,0 +1,199 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func (s *APITestSuite) TestUpdateMe() {
+	r := s.Require()
+
+	updateMe := func(inputBody string) (*http.Response, string) {
+		req, err := http.NewRequest("PATCH", "http://"+s.server.HttpServer.Addr+"/api/v1/users/me", bytes.NewBufferString(inputBody))
+		if err != nil {
+			s.logger.Error("http patch error: %s", err.Error())
+		}
+		req.Header.Add("Authorization", s.accessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		r.NoError(err)
+		st, _ := io.ReadAll(resp.Body)
+		return resp, string(st)
+	}
+
+	// First change of default username is free
+	resp, st := updateMe(`{"username": "Cheasezz"}`)
+	r.Equal(http.StatusOK, resp.StatusCode)
+	r.Contains(st, `"username":"Cheasezz"`)
+
+	// Same username is no-op
+	resp, _ = updateMe(`{"username": "Cheasezz"}`)
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, _ = updateMe(`{"username": "Cheasezz2"}`)
+	r.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	r.NotEmpty(resp.Header.Get("Retry-After"))
+}
+
+func (s *APITestSuite) TestChangePassword() {
+	r := s.Require()
+
+	inputBody := `{"currentPassword": "qwerty123456", "newPassword": "qwerty1234567"}`
+	req, err := http.NewRequest("POST", "http://"+s.server.HttpServer.Addr+"/api/v1/users/me/password", bytes.NewBufferString(inputBody))
+	if err != nil {
+		s.logger.Error("http post error: %s", err.Error())
+	}
+	req.Header.Add("Authorization", s.accessToken)
+	req.AddCookie(&http.Cookie{
+		Name:  "RefreshToken",
+		Value: s.userCookie,
+	})
+
+	resp, err := http.DefaultClient.Do(req)
+	r.NoError(err)
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	inputSignIn := `{"Email": "Cheasezz@gmail.com", "Password": "qwerty1234567"}`
+	resp, err = http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signin", "json", bytes.NewBufferString(inputSignIn))
+	r.NoError(err)
+	r.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *APITestSuite) TestChangeEmail() {
+	r := s.Require()
+
+	post := func(path, inputBody string) *http.Response {
+		req, err := http.NewRequest("POST", "http://"+s.server.HttpServer.Addr+path, bytes.NewBufferString(inputBody))
+		if err != nil {
+			s.logger.Error("http post error: %s", err.Error())
+		}
+		req.Header.Add("Authorization", s.accessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		r.NoError(err)
+		return resp
+	}
+
+	resp := post("/api/v1/users/me/email", `{"email": "Kappa@gmail.com", "password": "qwerty123456"}`)
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	// Email sender is stubbed, code is taken from db
+	var code string
+	err := s.db.Scany.Get(context.Background(), s.db.Pool, &code, `select code from codes where code_type='emailChange'`)
+	r.NoError(err)
+
+	resp = post("/api/v1/users/me/email/confirm", `{"code": "`+code+`"}`)
+	st, _ := io.ReadAll(resp.Body)
+	r.Equal(http.StatusOK, resp.StatusCode)
+	r.Contains(string(st), `{"user":{"email":"Kappa@gmail.com",`)
+	r.Contains(string(st), `"emailVerified":true`)
+}
+
+func (s *APITestSuite) TestDeleteAccount() {
+	r := s.Require()
+
+	do := func(method, path, inputBody string) (*http.Response, string) {
+		req, err := http.NewRequest(method, "http://"+s.server.HttpServer.Addr+path, bytes.NewBufferString(inputBody))
+		if err != nil {
+			s.logger.Error("http %s error: %s", method, err.Error())
+		}
+		req.Header.Add("Authorization", s.accessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		r.NoError(err)
+		st, _ := io.ReadAll(resp.Body)
+		return resp, string(st)
+	}
+	signIn := func() int {
+		inputSignIn := `{"Email": "Cheasezz@gmail.com", "Password": "qwerty123456"}`
+		resp, err := http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signin", "json", bytes.NewBufferString(inputSignIn))
+		r.NoError(err)
+		return resp.StatusCode
+	}
+
+	resp, st := do("GET", "/api/v1/users/me/export", "")
+	r.Equal(http.StatusOK, resp.StatusCode)
+	r.Contains(resp.Header.Get("Content-Disposition"), "attachment")
+	r.Contains(st, `"email":"Cheasezz@gmail.com"`)
+	r.NotContains(st, "passwordHash")
+
+	resp, st = do("DELETE", "/api/v1/users/me", "")
+	r.Equal(http.StatusAccepted, resp.StatusCode)
+	r.Contains(st, `"deleteAt":`)
+	r.Equal(http.StatusForbidden, signIn())
+
+	// Email sender is stubbed, code is taken from db
+	var code string
+	err := s.db.Scany.Get(context.Background(), s.db.Pool, &code, `select code from codes where code_type='deletionCancel'`)
+	r.NoError(err)
+
+	resp, _ = do("POST", "/api/v1/users/deletion/cancel", `{"email": "Cheasezz@gmail.com", "code": "`+code+`"}`)
+	r.Equal(http.StatusOK, resp.StatusCode)
+	r.Equal(http.StatusOK, signIn())
+}
+
+func (s *APITestSuite) TestAdminBan() {
+	r := s.Require()
+
+	do := func(method, path, token, inputBody string) (*http.Response, string) {
+		req, err := http.NewRequest(method, "http://"+s.server.HttpServer.Addr+path, bytes.NewBufferString(inputBody))
+		if err != nil {
+			s.logger.Error("http %s error: %s", method, err.Error())
+		}
+		req.Header.Add("Authorization", token)
+
+		resp, err := http.DefaultClient.Do(req)
+		r.NoError(err)
+		st, _ := io.ReadAll(resp.Body)
+		return resp, string(st)
+	}
+	signIn := func(email string) (*http.Response, string) {
+		inputSignIn := `{"Email": "` + email + `", "Password": "qwerty123456"}`
+		resp, err := http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signin", "json", bytes.NewBufferString(inputSignIn))
+		r.NoError(err)
+		st, _ := io.ReadAll(resp.Body)
+		return resp, string(st)
+	}
+
+	resp, _ := do("GET", "/api/v1/admin/users", s.accessToken, "")
+	r.Equal(http.StatusForbidden, resp.StatusCode)
+
+	// Role is granted in db, it is carried in tokens issued after that.
+	// Admin routes also require verified email.
+	resp, err := http.Post("http://"+s.server.HttpServer.Addr+"/api/v1/auth/signup", "json",
+		bytes.NewBufferString(`{"Email": "Admin@gmail.com", "Password": "qwerty123456"}`))
+	r.NoError(err)
+	r.Equal(http.StatusOK, resp.StatusCode)
+	_, err = s.db.Pool.Exec(context.Background(), `update users set role='admin', email_verified=true where email='Admin@gmail.com'`)
+	r.NoError(err)
+	resp, st := signIn("Admin@gmail.com")
+	r.Equal(http.StatusOK, resp.StatusCode)
+	adminToken := "Bearer " + strings.Split(strings.Split(st, ":")[1], `"`)[1]
+
+	resp, st = do("GET", "/api/v1/admin/users?query=cheasezz", adminToken, "")
+	r.Equal(http.StatusOK, resp.StatusCode)
+	r.Contains(st, `"email":"Cheasezz@gmail.com"`)
+	r.NotContains(st, "Admin@gmail.com")
+
+	var userId string
+	err = s.db.Scany.Get(context.Background(), s.db.Pool, &userId, `select id from users where email='Cheasezz@gmail.com'`)
+	r.NoError(err)
+
+	resp, _ = do("POST", "/api/v1/admin/users/"+userId+"/ban", adminToken, `{"reason": "spam"}`)
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	// Sessions of banned user are revoked and sign in is rejected
+	resp, _ = do("PATCH", "/api/v1/users/me", s.accessToken, `{"username": "Cheasezz"}`)
+	r.Equal(http.StatusUnauthorized, resp.StatusCode)
+	resp, st = signIn("Cheasezz@gmail.com")
+	r.Equal(http.StatusForbidden, resp.StatusCode)
+	r.Equal(`{"code":"user_banned","message":"user is banned"}`, st)
+
+	resp, _ = do("DELETE", "/api/v1/admin/users/"+userId+"/ban", adminToken, "")
+	r.Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = signIn("Cheasezz@gmail.com")
+	r.Equal(http.StatusOK, resp.StatusCode)
+}
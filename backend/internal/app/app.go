@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -25,34 +26,63 @@ func Run(cfg *config.Config) {
 		l.Fatal("failed initialize emailSender: %s", err.Error())
 	}
 
-	psql, err := postgres.NewPostgressDB(cfg.PG)
-	if err != nil {
-		l.Fatal("failed initialize db: %s", err.Error())
-	}
-	defer psql.Close()
+	var psql *postgres.Postgres
+	if cfg.Storage.Driver == repositories.StoragePostgres {
+		psql, err = postgres.NewPostgressDB(cfg.PG)
+		if err != nil {
+			l.Fatal("failed initialize db: %s", err.Error())
+		}
+		defer psql.Close()
 
-	DBMigrate(cfg.PG, l)
+		if cfg.PG.AutoMigrate {
+			if err := DBMigrate(cfg.PG, l); err != nil {
+				l.Fatal("failed migrate db: %s", err.Error())
+			}
+		}
+	}
 
-	hasher := hasher.NewSHA1Hasher(cfg.Hasher)
+	hasher, err := hasher.NewHasher(cfg.Hasher)
+	if err != nil {
+		l.Fatal("failed initialize hasher: %s", err.Error())
+	}
 	tokenManager, err := auth.NewManager(cfg.TokenManager)
 	if err != nil {
 		l.Fatal("failed initialize tokenManager: %s", err.Error())
 	}
 
-	repos := repositories.NewRepositories(psql)
+	repos, err := repositories.NewRepositories(cfg.Storage.Driver, psql, cfg.Lockout.Storage)
+	if err != nil {
+		l.Fatal("failed initialize repositories: %s", err.Error())
+	}
+
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go runSweeper(sweeperCtx, repos, cfg.Sweeper.Interval, l)
 
 	services := service.NewServices(service.Deps{
-		Repos:        repos,
-		Hasher:       hasher,
-		TokenManager: tokenManager,
-		EmailSender:  es,
+		Repos:              repos,
+		Hasher:             hasher,
+		TokenManager:       tokenManager,
+		EmailSender:        es,
+		MaxSessionsPerUser: cfg.TokenManager.MaxSessionsPerUser,
+		AccessTokenTTL:     cfg.TokenManager.AccessTokenTTL,
+		Lockout:            cfg.Lockout,
+		OAuth:              cfg.OAuth,
+		Users:              cfg.Users,
 	})
 
+	if err := services.Revocation.Sync(context.Background()); err != nil {
+		l.Fatal("failed load revoked sessions: %s", err.Error())
+	}
+	go runRevocationSync(sweeperCtx, services.Revocation, cfg.Revocation.SyncInterval, l)
+
 	handlers := httpHandlers.NewHandlers(v1.Deps{
-		Services:     services,
-		TokenManager: tokenManager,
-		ConfigHTTP:   cfg.HTTP,
-		Log:          l,
+		Services:        services,
+		TokenManager:    tokenManager,
+		ConfigHTTP:      cfg.HTTP,
+		ConfigRateLimit: cfg.RateLimit,
+		AccessTokenTTL:  cfg.TokenManager.AccessTokenTTL,
+		Log:             l,
 	})
 
 	srv := httpserver.NewServer(cfg.HTTP, handlers.Init())
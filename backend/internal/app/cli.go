@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+)
+
+const usage = `Usage:
+  app [serve]                 start http server
+  app migrate up              apply every pending migration
+  app migrate down [N]        roll back N migrations, one by default
+  app migrate to N            migrate up or down to version N
+  app migrate status          print applied and latest versions
+  app migrate force N         set version N without running migrations, clears dirty state
+  app migrate create NAME     create empty up and down files of next migration in pg.schema_dir (SCHEMA_DIR)`
+
+var (
+	errUsage = errors.New(usage)
+	// Embedded migrations can't be written, so create needs directory set explicitly.
+	errNoSchemaDir = errors.New("migrate create: pg.schema_dir (SCHEMA_DIR) isn't set")
+)
+
+// Execute command given by args, server is started without args.
+func Execute(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		Run(cfg)
+		return nil
+	}
+
+	switch args[0] {
+	case "serve":
+		if len(args) != 1 {
+			return errUsage
+		}
+		Run(cfg)
+		return nil
+	case "migrate":
+		return migrateCommand(cfg, args[1:], os.Stdout)
+	}
+
+	return errUsage
+}
+
+func migrateCommand(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	// create doesn't need database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errUsage
+		}
+		if cfg.PG.SchemaDir == "" {
+			return errNoSchemaDir
+		}
+		paths, err := CreateMigration(cfg.PG.SchemaDir, args[1])
+		for _, path := range paths {
+			fmt.Fprintln(out, path)
+		}
+		return err
+	}
+
+	cmd, err := parseMigrateArgs(args, out)
+	if err != nil {
+		return err
+	}
+
+	m, err := NewMigrator(cfg.PG, logger.New(cfg.Log.Level))
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return cmd(m)
+}
+
+// Parse args before connecting, so typo doesn't wait for database.
+func parseMigrateArgs(args []string, out io.Writer) (func(m *Migrator) error, error) {
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		return (*Migrator).Up, nil
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("down steps must be positive number, got %q", args[1])
+			}
+			steps = n
+		}
+		return func(m *Migrator) error { return m.Down(steps) }, nil
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("version must be number, got %q", args[1])
+		}
+		return func(m *Migrator) error { return m.To(uint(version)) }, nil
+	case args[0] == "force" && len(args) == 2:
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("version must be number, got %q", args[1])
+		}
+		return func(m *Migrator) error { return m.Force(version) }, nil
+	case args[0] == "status" && len(args) == 1:
+		return func(m *Migrator) error { return printStatus(m, out) }, nil
+	}
+
+	return nil, errUsage
+}
+
+func printStatus(m *Migrator, out io.Writer) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	var latest uint
+	pending := 0
+	for _, v := range m.Versions() {
+		latest = max(latest, v)
+		if v > version {
+			pending++
+		}
+	}
+
+	fmt.Fprintf(out, "version: %d\ndirty: %t\nlatest: %d\npending: %d\n", version, dirty, latest, pending)
+	return nil
+}
@@ -2,14 +2,18 @@ package app
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/pkg/iofs"
 	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+	"github.com/Cheasezz/anSpace/backend/schema"
 	"github.com/golang-migrate/migrate"
 	_ "github.com/golang-migrate/migrate/database/postgres"
-	_ "github.com/golang-migrate/migrate/source/file"
-	_ "github.com/golang-migrate/migrate/source/github"
 )
 
 const (
@@ -17,21 +21,44 @@ const (
 	_defaultTimeout  = time.Second
 )
 
-func DBMigrate(cfg config.PG, l logger.Logger) {
+var migrationNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Migrator applies migrations embedded into binary or ones of
+// external directory to postgres.
+type Migrator struct {
+	m   *migrate.Migrate
+	src *iofs.Driver
+	l   logger.Logger
+}
+
+// Embedded migrations unless cfg.SchemaDir points to external directory.
+func migrationSource(cfg config.PG) (*iofs.Driver, error) {
+	if cfg.SchemaDir != "" {
+		return iofs.New(os.DirFS(cfg.SchemaDir), ".")
+	}
+	return iofs.New(schema.Migrations, ".")
+}
+
+// Connect to postgres, retrying while it starts up.
+func NewMigrator(cfg config.PG, l logger.Logger) (*Migrator, error) {
 	if len(cfg.URL) == 0 {
-		l.Fatal("migrate: cfg.url is empty")
+		return nil, errors.New("migrate: cfg.url is empty")
+	}
+
+	src, err := migrationSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: source error: %w", err)
 	}
 
 	var (
 		attempts = _defaultAttempts
-		err      error
 		m        *migrate.Migrate
 	)
 
 	for attempts > 0 {
-		m, err = migrate.New("file://"+cfg.Schema_Url, cfg.URL)
+		m, err = migrate.NewWithSourceInstance("iofs", src, cfg.URL)
 		if err == nil {
-			break
+			return &Migrator{m: m, src: src, l: l}, nil
 		}
 
 		l.Info("Migrate: postgres is trying to connect, attempts left: %d", attempts)
@@ -40,20 +67,103 @@ func DBMigrate(cfg config.PG, l logger.Logger) {
 		attempts--
 	}
 
+	return nil, fmt.Errorf("migrate: postgres connect error: %w", err)
+}
+
+func (m *Migrator) Close() {
+	m.m.Close()
+}
+
+// Apply every pending migration.
+func (m *Migrator) Up() error {
+	return m.result("up", m.m.Up())
+}
+
+// Roll back given number of applied migrations.
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("migrate: down steps must be positive, got %d", steps)
+	}
+	return m.result("down", m.m.Steps(-steps))
+}
+
+// Migrate up or down to given version.
+func (m *Migrator) To(version uint) error {
+	return m.result("to", m.m.Migrate(version))
+}
+
+// Set version without running migrations, used to clear dirty state
+// after failed migration was fixed by hand.
+func (m *Migrator) Force(version int) error {
+	return m.result("force", m.m.Force(version))
+}
+
+// Return applied version. Version is zero if no migration was applied.
+// Dirty is true if last migration failed halfway.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Return versions of migrations in source, oldest first.
+func (m *Migrator) Versions() []uint {
+	return m.src.Versions()
+}
+
+func (m *Migrator) result(op string, err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		m.l.Info("Migrate: no change")
+		return nil
+	}
 	if err != nil {
-		l.Fatal("Migrate: postgres connect error: %s", err)
+		return fmt.Errorf("migrate: %s error: %w", op, err)
 	}
 
-	err = m.Up()
+	m.l.Info("Migrate: %s success", op)
+	return nil
+}
+
+// Apply every pending migration, used on server start.
+func DBMigrate(cfg config.PG, l logger.Logger) error {
+	m, err := NewMigrator(cfg, l)
+	if err != nil {
+		return err
+	}
 	defer m.Close()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		l.Fatal("Migrate: up error: %s", err)
+
+	return m.Up()
+}
+
+// Create empty up and down files of migration with next version in dir.
+// Return paths of created files.
+func CreateMigration(dir, name string) ([]string, error) {
+	if !migrationNameRe.MatchString(name) {
+		return nil, fmt.Errorf("migration name must be lowercase letters, digits and underscores, got %q", name)
 	}
 
-	if errors.Is(err, migrate.ErrNoChange) {
-		l.Info("Migrate: no change")
-		return
+	src, err := iofs.New(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, err
+	}
+	versions := src.Versions()
+	next := uint(1)
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		f.Close()
+		paths = append(paths, path)
 	}
 
-	l.Info("Migrate: up success")
+	return paths, nil
 }
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+// Every migration is embedded and can be rolled back.
+func TestEmbeddedMigrations(t *testing.T) {
+	src, err := migrationSource(config.PG{})
+	require.NoError(t, err)
+
+	versions := src.Versions()
+	require.NotEmpty(t, versions)
+	for i, v := range versions {
+		require.EqualValues(t, i+1, v)
+		up, _, err := src.ReadUp(v)
+		require.NoError(t, err)
+		up.Close()
+		down, _, err := src.ReadDown(v)
+		require.NoError(t, err)
+		down.Close()
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"000001_init.up.sql", "000001_init.down.sql", "000012_email_change.up.sql", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	paths, err := CreateMigration(dir, "add_avatars")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "000013_add_avatars.up.sql"),
+		filepath.Join(dir, "000013_add_avatars.down.sql"),
+	}, paths)
+
+	_, err = CreateMigration(dir, "Add avatars")
+	require.Error(t, err)
+}
+
+func TestMigrateCommand_createWithoutSchemaDir(t *testing.T) {
+	err := migrateCommand(&config.Config{}, []string{"create", "add_avatars"}, io.Discard)
+	require.ErrorIs(t, err, errNoSchemaDir)
+}
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"up", []string{"up"}, false},
+		{"down default", []string{"down"}, false},
+		{"down steps", []string{"down", "2"}, false},
+		{"down zero steps", []string{"down", "0"}, true},
+		{"to", []string{"to", "12"}, false},
+		{"to not number", []string{"to", "latest"}, true},
+		{"force", []string{"force", "3"}, false},
+		{"status", []string{"status"}, false},
+		{"unknown", []string{"redo"}, true},
+		{"extra args", []string{"up", "1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseMigrateArgs(tt.args, nil)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, cmd)
+		})
+	}
+}
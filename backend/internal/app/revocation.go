@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+)
+
+const _defaultRevocationSyncInterval = 10 * time.Second
+
+// Periodically load revoked sessions from db until ctx is done,
+// so revocations made by other instances are applied here too.
+func runRevocationSync(ctx context.Context, rv service.Revocation, interval time.Duration, l logger.Logger) {
+	if interval <= 0 {
+		interval = _defaultRevocationSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := rv.Sync(ctx); err != nil {
+			l.Error("Revocation sync error: %s", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+)
+
+const _defaultSweepInterval = time.Hour
+
+// Periodically delete expired sessions, codes, revocations, lockouts, oauth states
+// and users whose deletion grace period is over until ctx is done.
+func runSweeper(ctx context.Context, repos *repositories.Repositories, interval time.Duration, l logger.Logger) {
+	if interval <= 0 {
+		interval = _defaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sweep(ctx, repos, l)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sweep(ctx context.Context, repos *repositories.Repositories, l logger.Logger) {
+	now := time.Now().UTC()
+
+	sessions, err := repos.Storage.DeleteExpiredSessions(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired sessions error: %s", err)
+	}
+
+	codes, err := repos.Storage.DeleteExpiredCodes(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired codes error: %s", err)
+	}
+
+	revocations, err := repos.Storage.DeleteExpiredRevocations(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired revocations error: %s", err)
+	}
+
+	lockouts, err := repos.Lockout.DeleteExpiredLockouts(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired lockouts error: %s", err)
+	}
+
+	states, err := repos.Storage.DeleteExpiredOAuthStates(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired oauth states error: %s", err)
+	}
+
+	mfaTokens, err := repos.Storage.DeleteExpiredMFATokens(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete expired mfa tokens error: %s", err)
+	}
+
+	users, err := repos.Storage.DeleteScheduledUsers(ctx, now)
+	if err != nil {
+		l.Error("Sweeper: delete scheduled users error: %s", err)
+	}
+
+	l.Debug("Sweeper: deleted %d expired sessions, %d expired codes, %d expired revocations, %d expired lockouts, %d expired oauth states, %d expired mfa tokens and %d scheduled users",
+		sessions, codes, revocations, lockouts, states, mfaTokens, users)
+}
@@ -1,12 +1,38 @@
 package core
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// Session is one refresh token. Tokens issued by rotation of
+// each other share FamilyId, only the last one is not Rotated.
+// FamilyId is an id of user login, it is used as session id in api.
 type Session struct {
 	UserId       uuid.UUID `db:"user_id"`
 	RefreshToken string    `db:"refresh_token"`
+	FamilyId     uuid.UUID `db:"family_id"`
+	ParentToken  string    `db:"parent_token"`
+	Rotated      bool      `db:"rotated"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+	LastUsedAt   time.Time `db:"last_used_at"`
+	UserAgent    string    `db:"user_agent"`
+	IP           string    `db:"ip"`
+}
+
+// Device is a client that signs in or refreshes tokens.
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+// RevokedSession is a session whose access tokens are rejected until ExpiresAt,
+// when the last of them expires.
+type RevokedSession struct {
+	SessionId uuid.UUID `db:"session_id"`
+	ExpiresAt time.Time `db:"expires_at"`
 }
 
 type AuthCredentials struct {
@@ -19,3 +45,13 @@ type Email struct {
 type Password struct {
 	Password string `json:"password" binding:"required" db:"password_hash" example:"qwerty123456"`
 }
+
+// Lockout counts failed attempts of one key (email or client ip).
+// Attempts are rejected until LockedUntil.
+type Lockout struct {
+	Key           string    `json:"key" db:"key"`
+	Failures      int       `json:"failures" db:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt" db:"last_failure_at"`
+	LockedUntil   time.Time `json:"lockedUntil" db:"locked_until"`
+	ExpiresAt     time.Time `json:"-" db:"expires_at"`
+}
@@ -0,0 +1,55 @@
+package core
+
+import "time"
+
+// ErrorKind is a class of domain error. Transport layer maps kind to status code,
+// so services know nothing about it. Kinds are errors themselves:
+// errors.Is(err, ErrNotFound) is true for every *Error of that kind.
+type ErrorKind string
+
+func (k ErrorKind) Error() string {
+	return string(k)
+}
+
+const (
+	ErrValidation   ErrorKind = "validation failed"
+	ErrUnauthorized ErrorKind = "unauthorized"
+	ErrForbidden    ErrorKind = "forbidden"
+	ErrNotFound     ErrorKind = "not found"
+	ErrConflict     ErrorKind = "conflict"
+	ErrRateLimited  ErrorKind = "rate limited"
+)
+
+// Error is a domain error with machine readable code. Code and message are shown
+// to clients, so they must stay stable and never carry internal details.
+// Errors that aren't *Error are internal ones.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Cause of error, never shown to clients.
+	Err error
+}
+
+func NewError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == e.Kind
+}
+
+// Retryable is implemented by rate limited errors that know when request may be retried.
+type Retryable interface {
+	error
+	RetryIn() time.Duration
+}
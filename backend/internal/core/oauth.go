@@ -0,0 +1,49 @@
+package core
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OAuthProviderShikimori = "shikimori"
+	OAuthProviderKinopoisk = "kinopoisk"
+	OAuthProviderLitres    = "litres"
+)
+
+// OAuthState is authorization request waiting for provider callback.
+// CodeVerifier is PKCE secret of request. UserId is set when request
+// links provider to signed in user and is uuid.Nil for sign in.
+type OAuthState struct {
+	State        string    `db:"state"`
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"`
+	UserId       uuid.UUID `db:"user_id"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// LinkedAccount is user identity at external provider with provider tokens.
+// TokenExpiresAt is zero when provider tokens don't expire.
+type LinkedAccount struct {
+	UserId         uuid.UUID `db:"user_id"`
+	Provider       string    `db:"provider"`
+	ProviderUserId string    `db:"provider_user_id"`
+	Username       string    `db:"username"`
+	AccessToken    string    `db:"access_token"`
+	RefreshToken   string    `db:"refresh_token"`
+	TokenExpiresAt time.Time `db:"token_expires_at"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// OAuthProfile is user profile returned by provider.
+type OAuthProfile struct {
+	Id       string
+	Username string
+}
+
+type OAuthCallback struct {
+	Code  string `form:"code" binding:"required" example:"9838c59cff93e21"`
+	State string `form:"state" binding:"required" example:"s8hOQjBtA0"`
+}
@@ -1,12 +1,108 @@
 package core
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// Roles of users. Each role has every permission of roles ranked below it.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Return rank of role, unknown role has the lowest one.
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
+// Actor is signed in user performing moderation action.
+type Actor struct {
+	Id   uuid.UUID
+	Role string
+}
+
 type User struct {
 	Id            uuid.UUID `json:"-" db:"id"`
 	Email         string    `json:"email" db:"email"`
 	Username      string    `json:"username" db:"username"`
-	PasswordHash  string    `json:"passwordHash" db:"password_hash"`
+	PasswordHash  string    `json:"-" db:"password_hash"`
+	EmailVerified bool      `json:"emailVerified" db:"email_verified"`
+	TOTPSecret    string    `json:"-" db:"totp_secret"`
+	TOTPEnabled   bool      `json:"totpEnabled" db:"totp_enabled"`
+	// Time step of last accepted TOTP code, zero if none.
+	TOTPLastStep int64 `json:"-" db:"totp_last_step"`
+	// Epoch until user changes random default username.
+	UsernameChangedAt time.Time `json:"-" db:"username_changed_at"`
+	// Epoch unless user scheduled account deletion.
+	DeleteAt time.Time `json:"-" db:"delete_at"`
+	Role     string    `json:"role" db:"role"`
+	// Epoch unless user is banned.
+	BannedAt  time.Time `json:"-" db:"banned_at"`
+	BanReason string    `json:"-" db:"ban_reason"`
+}
+
+func (u User) DeletionScheduled() bool {
+	return u.DeleteAt.After(time.Unix(0, 0))
+}
+
+func (u User) Banned() bool {
+	return u.BannedAt.After(time.Unix(0, 0))
+}
+
+type UserUpdate struct {
+	Username string `json:"username" binding:"required" example:"cheasezz"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword" binding:"required" example:"qwerty123456"`
+	NewPassword     string `json:"newPassword" binding:"required" example:"qwerty1234567"`
+}
+
+type EmailChange struct {
+	Email string `json:"email" binding:"required" example:"example@gmail.com"`
+	// Current password, not required for user without password (created by oauth sign in).
+	Password string `json:"password" example:"qwerty123456"`
+}
+
+type EmailChangeConfirm struct {
+	Code string `json:"code" binding:"required" example:"9838c59cff93e21"`
+}
+
+type DeletionCancel struct {
+	Email string `json:"email" binding:"required" example:"example@gmail.com"`
+	Code  string `json:"code" binding:"required" example:"9838c59cff93e21"`
+}
+
+// UserExport is everything stored about user. Secrets are left out
+// when it is shown to user.
+type UserExport struct {
+	User           User
+	Sessions       []Session
+	LinkedAccounts []LinkedAccount
+	// Pending codes sent to user and mfa recovery codes.
+	Codes []CodeCredentials
+}
+
+// UserSearch is a page of users whose email or username contains query.
+type UserSearch struct {
+	Query  string `form:"query" example:"cheasezz"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100" example:"20"`
+	Offset int    `form:"offset" binding:"omitempty,min=0" example:"0"`
+}
+
+type Ban struct {
+	Reason string `json:"reason" binding:"max=512" example:"spam"`
+}
+
+type RoleChange struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin" example:"moderator"`
 }
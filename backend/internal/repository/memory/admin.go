@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+type AdminRepo struct {
+	s *Store
+}
+
+func NewAdminRepo(s *Store) *AdminRepo {
+	return &AdminRepo{s: s}
+}
+
+// Return page of users whose email or username contains query ignoring case, ordered by email.
+// Empty query matches every user.
+func (r *AdminRepo) SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error) {
+	defer r.s.lock(ctx)()
+
+	query := strings.ToLower(search.Query)
+	var users []core.User
+	for _, u := range r.s.data.users {
+		if strings.Contains(strings.ToLower(u.Email), query) || strings.Contains(strings.ToLower(u.Username), query) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Email) < strings.ToLower(users[j].Email)
+	})
+
+	if search.Offset >= len(users) {
+		return nil, nil
+	}
+	users = users[search.Offset:]
+	if search.Limit < len(users) {
+		users = users[:search.Limit]
+	}
+	return users, nil
+}
+
+// Mark user as banned and delete all user sessions. Banning banned user replaces
+// reason and keeps ban time. Return ids of deleted sessions.
+// Return ErrNotFound if there is no such user.
+func (r *AdminRepo) BanUser(ctx context.Context, userId uuid.UUID, reason string, now time.Time) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return nil, psql.ErrNotFound
+	}
+	user.BanReason = reason
+	if !user.Banned() {
+		user.BannedAt = ts(now)
+	}
+	r.s.data.users[userId] = user
+
+	return r.s.data.deleteSessions(func(s core.Session) bool { return s.UserId == userId }), nil
+}
+
+// Lift ban of user. Return ErrNotFound if there is no such user.
+func (r *AdminRepo) UnbanUser(ctx context.Context, userId uuid.UUID) error {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return psql.ErrNotFound
+	}
+	user.BannedAt = epoch
+	user.BanReason = ""
+	r.s.data.users[userId] = user
+
+	return nil
+}
+
+// Delete all sessions of user. Return ids of deleted sessions.
+func (r *AdminRepo) DeleteUserSessions(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	return r.s.data.deleteSessions(func(s core.Session) bool { return s.UserId == userId }), nil
+}
+
+// Set role of user. Return ErrNotFound if there is no such user.
+func (r *AdminRepo) SetRole(ctx context.Context, userId uuid.UUID, role string) (core.User, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return core.User{}, psql.ErrNotFound
+	}
+	if core.RoleRank(role) == 0 {
+		return core.User{}, errRoleCheck
+	}
+	user.Role = role
+	r.s.data.users[userId] = user
+
+	return user, nil
+}
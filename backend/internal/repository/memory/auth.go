@@ -0,0 +1,372 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+type AuthRepo struct {
+	s *Store
+}
+
+func NewAuthRepo(s *Store) *AuthRepo {
+	return &AuthRepo{s: s}
+}
+
+func (r *AuthRepo) CreateUser(ctx context.Context, signUp core.AuthCredentials) (uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	return r.s.data.createUser(signUp.Email, signUp.Password)
+}
+
+func (r *AuthRepo) GetUserById(ctx context.Context, userId uuid.UUID) (core.User, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return core.User{}, psql.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *AuthRepo) GetUserByEmail(ctx context.Context, email string) (core.User, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.userByEmail(email)
+	if !ok {
+		return core.User{}, psql.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *AuthRepo) UpdatePasswordHash(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	defer r.s.lock(ctx)()
+
+	if user, ok := r.s.data.users[userId]; ok {
+		user.PasswordHash = passwordHash
+		r.s.data.users[userId] = user
+	}
+	return nil
+}
+
+func (r *AuthRepo) SetCode(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	code.NewEmail = ""
+	return r.s.data.insertCode(code)
+}
+
+func (r *AuthRepo) GetCode(ctx context.Context, code core.CodeCredentials) (core.CodeCredentials, error) {
+	defer r.s.lock(ctx)()
+
+	c, ok := r.s.data.codes[code.Code]
+	if !ok || !sameEmail(c.Email, code.Email) || c.CodeType != code.CodeType {
+		return core.CodeCredentials{}, psql.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *AuthRepo) DeleteCode(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	if c, ok := r.s.data.codes[code.Code]; ok && sameEmail(c.Email, code.Email) {
+		delete(r.s.data.codes, code.Code)
+	}
+	return nil
+}
+
+func (r *AuthRepo) DeleteCodesByType(ctx context.Context, email, codeType string) error {
+	defer r.s.lock(ctx)()
+
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, email) && c.CodeType == codeType
+	})
+	return nil
+}
+
+// Consume email verification code and mark user email as verified.
+// Return ErrNotFound if code already consumed.
+func (r *AuthRepo) VerifyEmail(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.consumeCode(code); !ok {
+		return psql.ErrNotFound
+	}
+	if user, ok := r.s.data.userByEmail(code.Email); ok {
+		user.EmailVerified = true
+		r.s.data.users[user.Id] = user
+	}
+	return nil
+}
+
+// Consume password reset code, set new password hash and delete all user sessions.
+// Return ids of deleted sessions (token families).
+// Return ErrNotFound if code already consumed.
+func (r *AuthRepo) ResetPassword(ctx context.Context, code core.CodeCredentials, passwordHash string) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.consumeCode(code); !ok {
+		return nil, psql.ErrNotFound
+	}
+	// Code references user by email, so user exists.
+	user, _ := r.s.data.userByEmail(code.Email)
+	user.PasswordHash = passwordHash
+	r.s.data.users[user.Id] = user
+
+	return r.s.data.deleteSessions(func(s core.Session) bool { return s.UserId == user.Id }), nil
+}
+
+// Delete code of given type. Return ErrNotFound if there is no such code.
+func (r *AuthRepo) ConsumeCode(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.consumeCode(code); !ok {
+		return psql.ErrNotFound
+	}
+	return nil
+}
+
+// Set new not yet enabled TOTP secret of user.
+// Return ErrNotFound if user has TOTP enabled already.
+func (r *AuthRepo) SetTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok || user.TOTPEnabled {
+		return psql.ErrNotFound
+	}
+	user.TOTPSecret = secret
+	r.s.data.users[userId] = user
+
+	return nil
+}
+
+// Enable TOTP of user and replace user recovery codes. Recovery codes have
+// no expiry. Return ErrNotFound if TOTP is already enabled or secret isn't set.
+func (r *AuthRepo) EnableTOTP(ctx context.Context, userId uuid.UUID, recoveryCodes []core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok || user.TOTPEnabled || user.TOTPSecret == "" {
+		return psql.ErrNotFound
+	}
+	for _, code := range recoveryCodes {
+		if c, ok := r.s.data.codes[code.Code]; ok && !(sameEmail(c.Email, user.Email) && c.CodeType == core.CodeTypeMFARecovery) {
+			return psql.ErrDuplicate
+		}
+	}
+
+	user.TOTPEnabled = true
+	r.s.data.users[userId] = user
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, user.Email) && c.CodeType == core.CodeTypeMFARecovery
+	})
+	for _, code := range recoveryCodes {
+		r.s.data.codes[code.Code] = core.CodeCredentials{
+			Email:    user.Email,
+			Code:     code.Code,
+			CodeType: core.CodeTypeMFARecovery,
+		}
+	}
+
+	return nil
+}
+
+// Disable TOTP of user, clear secret and delete recovery codes.
+func (r *AuthRepo) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return psql.ErrNotFound
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	r.s.data.users[userId] = user
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, user.Email) && c.CodeType == core.CodeTypeMFARecovery
+	})
+
+	return nil
+}
+
+// Set time step of last accepted TOTP code of user.
+// Return ErrNotFound unless step is after stored one, so code is accepted once.
+func (r *AuthRepo) SetTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok || user.TOTPLastStep >= step {
+		return psql.ErrNotFound
+	}
+	user.TOTPLastStep = step
+	r.s.data.users[userId] = user
+
+	return nil
+}
+
+// Mark mfa pending token as used until it expires.
+// Return ErrNotFound if token is already used.
+func (r *AuthRepo) UseMFAToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.usedMFATokens[jti]; ok {
+		return psql.ErrNotFound
+	}
+	r.s.data.usedMFATokens[jti] = ts(expiresAt)
+
+	return nil
+}
+
+func (r *AuthRepo) DeleteExpiredMFATokens(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	var deleted int64
+	for jti, expiresAt := range r.s.data.usedMFATokens {
+		if expiresAt.Before(now) {
+			delete(r.s.data.usedMFATokens, jti)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Write new session and evict oldest user sessions (token families) above maxSessions.
+// maxSessions <= 0 disables eviction. Return ids of evicted sessions.
+func (r *AuthRepo) SetSession(ctx context.Context, session core.Session, maxSessions int) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	now := now()
+	session.Rotated = false
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	if err := r.s.data.insertSession(session); err != nil {
+		return nil, err
+	}
+
+	if maxSessions <= 0 {
+		return nil, nil
+	}
+
+	sessions := r.s.data.activeSessions(session.UserId)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+		}
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	if len(sessions) <= maxSessions {
+		return nil, nil
+	}
+
+	evicted := make(map[uuid.UUID]bool)
+	for _, s := range sessions[maxSessions:] {
+		evicted[s.FamilyId] = true
+	}
+	return r.s.data.deleteSessions(func(s core.Session) bool {
+		return s.UserId == session.UserId && evicted[s.FamilyId]
+	}), nil
+}
+
+// Mark old session as rotated and write new one.
+// Return ErrNotFound if old session already rotated.
+func (r *AuthRepo) RotateSession(ctx context.Context, old core.Session, new core.Session) error {
+	defer r.s.lock(ctx)()
+
+	stored, ok := r.s.data.sessions[old.RefreshToken]
+	if !ok || stored.Rotated {
+		return psql.ErrNotFound
+	}
+
+	new.Rotated = false
+	new.CreatedAt = ts(new.CreatedAt)
+	new.LastUsedAt = now()
+	if err := r.s.data.insertSession(new); err != nil {
+		return err
+	}
+	stored.Rotated = true
+	r.s.data.sessions[old.RefreshToken] = stored
+
+	return nil
+}
+
+// Delete session with all tokens of its family.
+func (r *AuthRepo) DeleteSession(ctx context.Context, session core.Session) error {
+	defer r.s.lock(ctx)()
+
+	r.s.data.deleteSessions(func(s core.Session) bool {
+		return s.FamilyId == session.FamilyId && s.UserId == session.UserId
+	})
+	return nil
+}
+
+// Delete session with all tokens of its family.
+// Return ErrNotFound if user has no session with such id.
+func (r *AuthRepo) DeleteSessionById(ctx context.Context, userId, sessionId uuid.UUID) error {
+	defer r.s.lock(ctx)()
+
+	deleted := r.s.data.deleteSessions(func(s core.Session) bool {
+		return s.FamilyId == sessionId && s.UserId == userId
+	})
+	if len(deleted) == 0 {
+		return psql.ErrNotFound
+	}
+	return nil
+}
+
+// Delete all user sessions except given one. Return ids of deleted sessions.
+func (r *AuthRepo) DeleteSessionsExcept(ctx context.Context, userId, sessionId uuid.UUID) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	return r.s.data.deleteSessions(func(s core.Session) bool {
+		return s.UserId == userId && s.FamilyId != sessionId
+	}), nil
+}
+
+// Return last token of every user token family, recently used first.
+func (r *AuthRepo) GetUserSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error) {
+	defer r.s.lock(ctx)()
+
+	sessions := r.s.data.activeSessions(userId)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (r *AuthRepo) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	var deleted int64
+	for token, s := range r.s.data.sessions {
+		if s.ExpiresAt.Before(now) {
+			delete(r.s.data.sessions, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Codes without expiry (recovery codes) are kept.
+func (r *AuthRepo) DeleteExpiredCodes(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	return r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return !c.ExpiresAt.IsZero() && c.ExpiresAt.Before(now)
+	}), nil
+}
+
+func (r *AuthRepo) GetUserSessionByRefreshToken(ctx context.Context, refreshToken string) (core.Session, error) {
+	defer r.s.lock(ctx)()
+
+	session, ok := r.s.data.sessions[refreshToken]
+	if !ok {
+		return core.Session{}, psql.ErrNotFound
+	}
+	return session, nil
+}
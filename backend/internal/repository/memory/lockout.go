@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+)
+
+// LockoutRepo keeps lockouts in process memory. It is suitable only for single instance,
+// use Postgres one when several instances serve requests.
+type LockoutRepo struct {
+	mu       sync.Mutex
+	lockouts map[string]core.Lockout
+}
+
+func NewLockoutRepo() *LockoutRepo {
+	return &LockoutRepo{lockouts: make(map[string]core.Lockout)}
+}
+
+// Count failure of key. Failures older than window are forgotten, counter starts again.
+// Return updated lockout.
+func (r *LockoutRepo) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (core.Lockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lockouts[key]
+	if !ok || l.LastFailureAt.Before(now.Add(-window)) {
+		l.Key = key
+		l.Failures = 0
+	}
+	l.Failures++
+	l.LastFailureAt = now
+	l.ExpiresAt = now.Add(window)
+	if l.LockedUntil.After(l.ExpiresAt) {
+		l.ExpiresAt = l.LockedUntil
+	}
+	r.lockouts[key] = l
+
+	return l, nil
+}
+
+// Take back one failure of key. Return ErrNotFound if key has no lockout, as Postgres repo does.
+func (r *LockoutRepo) RemoveFailure(ctx context.Context, key string) (core.Lockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lockouts[key]
+	if !ok {
+		return core.Lockout{}, psql.ErrNotFound
+	}
+	l.Failures = max(l.Failures-1, 0)
+	r.lockouts[key] = l
+
+	return l, nil
+}
+
+func (r *LockoutRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lockouts[key]
+	if !ok {
+		return nil
+	}
+	l.LockedUntil = until
+	if until.After(l.ExpiresAt) {
+		l.ExpiresAt = until
+	}
+	r.lockouts[key] = l
+
+	return nil
+}
+
+// Return ErrNotFound if key has no lockout, as Postgres repo does.
+func (r *LockoutRepo) GetLockout(ctx context.Context, key string) (core.Lockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lockouts[key]
+	if !ok {
+		return core.Lockout{}, psql.ErrNotFound
+	}
+	return l, nil
+}
+
+// Return keys locked at now, longest lock first.
+func (r *LockoutRepo) GetLockouts(ctx context.Context, now time.Time) ([]core.Lockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lockouts []core.Lockout
+	for _, l := range r.lockouts {
+		if l.LockedUntil.After(now) {
+			lockouts = append(lockouts, l)
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil.After(lockouts[j].LockedUntil)
+	})
+
+	return lockouts, nil
+}
+
+func (r *LockoutRepo) DeleteLockout(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.lockouts, key)
+	return nil
+}
+
+func (r *LockoutRepo) DeleteExpiredLockouts(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, l := range r.lockouts {
+		if l.ExpiresAt.Before(now) {
+			delete(r.lockouts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
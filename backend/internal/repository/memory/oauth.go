@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+type OAuthRepo struct {
+	s *Store
+}
+
+func NewOAuthRepo(s *Store) *OAuthRepo {
+	return &OAuthRepo{s: s}
+}
+
+// Write state. State with uuid.Nil user id belongs to no user.
+func (r *OAuthRepo) SetOAuthState(ctx context.Context, state core.OAuthState) error {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.states[state.State]; ok {
+		return psql.ErrDuplicate
+	}
+	if _, ok := r.s.data.users[state.UserId]; state.UserId != uuid.Nil && !ok {
+		return errForeignKey
+	}
+	state.ExpiresAt = ts(state.ExpiresAt)
+	r.s.data.states[state.State] = state
+
+	return nil
+}
+
+// Delete state and return it. Return ErrNotFound if there is no such state
+// or it's expired, so one state can't be used twice.
+func (r *OAuthRepo) ConsumeOAuthState(ctx context.Context, state string, now time.Time) (core.OAuthState, error) {
+	defer r.s.lock(ctx)()
+
+	st, ok := r.s.data.states[state]
+	if !ok || !st.ExpiresAt.After(now) {
+		return core.OAuthState{}, psql.ErrNotFound
+	}
+	delete(r.s.data.states, state)
+
+	return st, nil
+}
+
+func (r *OAuthRepo) GetLinkedAccount(ctx context.Context, provider, providerUserId string) (core.LinkedAccount, error) {
+	defer r.s.lock(ctx)()
+
+	account, ok := r.s.data.accounts[accountKey{provider, providerUserId}]
+	if !ok {
+		return core.LinkedAccount{}, psql.ErrNotFound
+	}
+	return account, nil
+}
+
+func (r *OAuthRepo) GetUserLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) (core.LinkedAccount, error) {
+	defer r.s.lock(ctx)()
+
+	account, ok := r.s.data.userAccount(userId, provider)
+	if !ok {
+		return core.LinkedAccount{}, psql.ErrNotFound
+	}
+	return account, nil
+}
+
+// Return accounts linked to user, oldest link first.
+func (r *OAuthRepo) GetUserLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error) {
+	defer r.s.lock(ctx)()
+
+	var accounts []core.LinkedAccount
+	for _, a := range r.s.data.accounts {
+		if a.UserId == userId {
+			accounts = append(accounts, a)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].CreatedAt.Before(accounts[j].CreatedAt)
+	})
+	return accounts, nil
+}
+
+// Create user without password and link account to it.
+func (r *OAuthRepo) CreateUserWithAccount(ctx context.Context, email string, account core.LinkedAccount) (uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.accounts[accountKey{account.Provider, account.ProviderUserId}]; ok {
+		return uuid.UUID{}, psql.ErrDuplicate
+	}
+	id, err := r.s.data.createUser(email, "")
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	account.UserId = id
+	if err := r.s.data.insertAccount(account); err != nil {
+		delete(r.s.data.users, id)
+		return uuid.UUID{}, err
+	}
+
+	return id, nil
+}
+
+// Link account to existing user.
+func (r *OAuthRepo) LinkAccount(ctx context.Context, account core.LinkedAccount) error {
+	defer r.s.lock(ctx)()
+
+	return r.s.data.insertAccount(account)
+}
+
+// Update provider username and tokens of linked account.
+// Return ErrNotFound if account isn't linked.
+func (r *OAuthRepo) UpdateLinkedAccount(ctx context.Context, account core.LinkedAccount) error {
+	defer r.s.lock(ctx)()
+
+	key := accountKey{account.Provider, account.ProviderUserId}
+	stored, ok := r.s.data.accounts[key]
+	if !ok {
+		return psql.ErrNotFound
+	}
+	stored.Username = account.Username
+	stored.AccessToken = account.AccessToken
+	stored.RefreshToken = account.RefreshToken
+	stored.TokenExpiresAt = ts(account.TokenExpiresAt)
+	stored.UpdatedAt = now()
+	r.s.data.accounts[key] = stored
+
+	return nil
+}
+
+// Unlink provider from user. Return ErrNotFound if provider isn't linked.
+func (r *OAuthRepo) DeleteLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) error {
+	defer r.s.lock(ctx)()
+
+	account, ok := r.s.data.userAccount(userId, provider)
+	if !ok {
+		return psql.ErrNotFound
+	}
+	delete(r.s.data.accounts, accountKey{account.Provider, account.ProviderUserId})
+
+	return nil
+}
+
+func (r *OAuthRepo) DeleteExpiredOAuthStates(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	var deleted int64
+	for key, st := range r.s.data.states {
+		if st.ExpiresAt.Before(now) {
+			delete(r.s.data.states, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/google/uuid"
+)
+
+type RevocationRepo struct {
+	s *Store
+}
+
+func NewRevocationRepo(s *Store) *RevocationRepo {
+	return &RevocationRepo{s: s}
+}
+
+// Write revoked session ids. Already revoked session gets later expiry of two.
+func (r *RevocationRepo) RevokeSessions(ctx context.Context, sessionIds []uuid.UUID, expiresAt time.Time) error {
+	defer r.s.lock(ctx)()
+
+	expiresAt = ts(expiresAt)
+	for _, id := range sessionIds {
+		if expiresAt.After(r.s.data.revoked[id]) {
+			r.s.data.revoked[id] = expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *RevocationRepo) GetRevokedSessions(ctx context.Context, now time.Time) ([]core.RevokedSession, error) {
+	defer r.s.lock(ctx)()
+
+	var revoked []core.RevokedSession
+	for id, expiresAt := range r.s.data.revoked {
+		if expiresAt.After(now) {
+			revoked = append(revoked, core.RevokedSession{SessionId: id, ExpiresAt: expiresAt})
+		}
+	}
+	return revoked, nil
+}
+
+func (r *RevocationRepo) DeleteExpiredRevocations(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	var deleted int64
+	for id, expiresAt := range r.s.data.revoked {
+		if !expiresAt.After(now) {
+			delete(r.s.data.revoked, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
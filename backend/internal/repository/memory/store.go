@@ -0,0 +1,344 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+var (
+	// Returned where Postgres fails on foreign key, e.g. code of unknown email.
+	errForeignKey = errors.New("referenced row doesn't exist")
+	// Returned where Postgres fails on users_role_check constraint.
+	errRoleCheck = errors.New("unknown role")
+)
+
+// epoch is "not set" value of timestamps, as 'epoch' default in schema.
+var epoch = time.Unix(0, 0).UTC()
+
+// Store is in-memory database shared by memory repos. It keeps the same
+// constraints as schema: unique emails and usernames ignoring case, and rows
+// referencing user are deleted with it or follow its email change.
+// Every repo method is atomic. Data is lost on restart, so store suits
+// local runs and tests of single instance only. Units of work serialize
+// all traffic, slow step inside one (e.g. email sending) stalls every request.
+type Store struct {
+	mu   sync.Mutex
+	data data
+	// Held by unit of work for its whole run and by every repo method
+	// called outside of it, see TxManager.
+	txMu sync.Mutex
+}
+
+// Lock store for repo method and return unlock func. Method called outside
+// of unit of work waits for running one to finish, so its write can't be
+// undone by rollback of other unit of work.
+func (s *Store) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) != nil {
+		s.mu.Lock()
+		return s.mu.Unlock
+	}
+
+	s.txMu.Lock()
+	s.mu.Lock()
+	return func() {
+		s.mu.Unlock()
+		s.txMu.Unlock()
+	}
+}
+
+// Return repository of store. Lockouts are kept apart from store,
+// they aren't part of units of work.
+func NewRepository(s *Store) *psql.Repository {
+	return &psql.Repository{
+		Auth:       NewAuthRepo(s),
+		Revocation: NewRevocationRepo(s),
+		Lockout:    NewLockoutRepo(),
+		OAuth:      NewOAuthRepo(s),
+		Users:      NewUsersRepo(s),
+		Admin:      NewAdminRepo(s),
+	}
+}
+
+type data struct {
+	users map[uuid.UUID]core.User
+	// By refresh token.
+	sessions map[string]core.Session
+	// By code, codes are unique across users and types as primary key in schema.
+	codes map[string]core.CodeCredentials
+	// By provider and provider user id.
+	accounts map[accountKey]core.LinkedAccount
+	states   map[string]core.OAuthState
+	// Expiry of revoked session ids.
+	revoked map[uuid.UUID]time.Time
+	// Expiry of used mfa pending tokens by jti.
+	usedMFATokens map[string]time.Time
+}
+
+type accountKey struct {
+	provider       string
+	providerUserId string
+}
+
+func NewStore() *Store {
+	return &Store{data: data{
+		users:         make(map[uuid.UUID]core.User),
+		sessions:      make(map[string]core.Session),
+		codes:         make(map[string]core.CodeCredentials),
+		accounts:      make(map[accountKey]core.LinkedAccount),
+		states:        make(map[string]core.OAuthState),
+		revoked:       make(map[uuid.UUID]time.Time),
+		usedMFATokens: make(map[string]time.Time),
+	}}
+}
+
+// Rows are stored by value, so copy of maps is a full snapshot.
+func (d data) clone() data {
+	return data{
+		users:         maps.Clone(d.users),
+		sessions:      maps.Clone(d.sessions),
+		codes:         maps.Clone(d.codes),
+		accounts:      maps.Clone(d.accounts),
+		states:        maps.Clone(d.states),
+		revoked:       maps.Clone(d.revoked),
+		usedMFATokens: maps.Clone(d.usedMFATokens),
+	}
+}
+
+// Emails are CITEXT in schema.
+func sameEmail(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// Timestamps are stored as Postgres does: UTC with microsecond precision.
+func ts(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func now() time.Time {
+	return ts(time.Now())
+}
+
+func (d data) userByEmail(email string) (core.User, bool) {
+	for _, u := range d.users {
+		if sameEmail(u.Email, email) {
+			return u, true
+		}
+	}
+	return core.User{}, false
+}
+
+// Insert user with schema defaults. Return ErrDuplicate if email is taken.
+func (d data) createUser(email, passwordHash string) (uuid.UUID, error) {
+	if _, ok := d.userByEmail(email); ok {
+		return uuid.UUID{}, psql.ErrDuplicate
+	}
+
+	username, err := randomUsername()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	user := core.User{
+		Id:                uuid.New(),
+		Email:             email,
+		Username:          username,
+		PasswordHash:      passwordHash,
+		Role:              core.RoleUser,
+		UsernameChangedAt: epoch,
+		DeleteAt:          epoch,
+		BannedAt:          epoch,
+	}
+	d.users[user.Id] = user
+
+	return user.Id, nil
+}
+
+// Random default username, as md5 substring default in schema.
+func randomUsername() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", raw)[:23], nil
+}
+
+func (d data) usernameTaken(username string, exceptId uuid.UUID) bool {
+	for _, u := range d.users {
+		if u.Id != exceptId && strings.EqualFold(u.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete user and cascade to rows referencing it.
+func (d data) deleteUser(user core.User) {
+	delete(d.users, user.Id)
+	d.deleteSessions(func(s core.Session) bool { return s.UserId == user.Id })
+	d.deleteCodes(func(c core.CodeCredentials) bool { return sameEmail(c.Email, user.Email) })
+	for key, a := range d.accounts {
+		if a.UserId == user.Id {
+			delete(d.accounts, key)
+		}
+	}
+	for key, s := range d.states {
+		if s.UserId == user.Id {
+			delete(d.states, key)
+		}
+	}
+}
+
+// Insert session. Session must belong to existing user and refresh token be unique.
+func (d data) insertSession(session core.Session) error {
+	if _, ok := d.users[session.UserId]; !ok {
+		return errForeignKey
+	}
+	if _, ok := d.sessions[session.RefreshToken]; ok {
+		return psql.ErrDuplicate
+	}
+	session.ExpiresAt = ts(session.ExpiresAt)
+	d.sessions[session.RefreshToken] = session
+	return nil
+}
+
+// Return not rotated sessions of user, one per token family.
+func (d data) activeSessions(userId uuid.UUID) []core.Session {
+	var sessions []core.Session
+	for _, s := range d.sessions {
+		if s.UserId == userId && !s.Rotated {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions
+}
+
+// Delete sessions matching filter. Return distinct ids of their families.
+func (d data) deleteSessions(match func(core.Session) bool) []uuid.UUID {
+	var familyIds []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for token, s := range d.sessions {
+		if !match(s) {
+			continue
+		}
+		delete(d.sessions, token)
+		if !seen[s.FamilyId] {
+			seen[s.FamilyId] = true
+			familyIds = append(familyIds, s.FamilyId)
+		}
+	}
+	return familyIds
+}
+
+// Delete codes matching filter. Return number of deleted codes.
+func (d data) deleteCodes(match func(core.CodeCredentials) bool) int64 {
+	var deleted int64
+	for key, c := range d.codes {
+		if match(c) {
+			delete(d.codes, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Insert code. Code must belong to existing user and be unique.
+func (d data) insertCode(code core.CodeCredentials) error {
+	if _, ok := d.userByEmail(code.Email); !ok {
+		return errForeignKey
+	}
+	if _, ok := d.codes[code.Code]; ok {
+		return psql.ErrDuplicate
+	}
+	code.ExpiresAt = ts(code.ExpiresAt)
+	d.codes[code.Code] = code
+	return nil
+}
+
+// Delete code with given value, email and type. Return false if there is no such code.
+func (d data) consumeCode(code core.CodeCredentials) (core.CodeCredentials, bool) {
+	c, ok := d.codes[code.Code]
+	if !ok || !sameEmail(c.Email, code.Email) || c.CodeType != code.CodeType {
+		return core.CodeCredentials{}, false
+	}
+	delete(d.codes, code.Code)
+	return c, true
+}
+
+func (d data) userAccount(userId uuid.UUID, provider string) (core.LinkedAccount, bool) {
+	for _, a := range d.accounts {
+		if a.UserId == userId && a.Provider == provider {
+			return a, true
+		}
+	}
+	return core.LinkedAccount{}, false
+}
+
+// Link account to existing user. One account of provider per user.
+func (d data) insertAccount(account core.LinkedAccount) error {
+	if _, ok := d.users[account.UserId]; !ok {
+		return errForeignKey
+	}
+	key := accountKey{account.Provider, account.ProviderUserId}
+	if _, ok := d.accounts[key]; ok {
+		return psql.ErrDuplicate
+	}
+	if _, ok := d.userAccount(account.UserId, account.Provider); ok {
+		return psql.ErrDuplicate
+	}
+	now := now()
+	account.TokenExpiresAt = ts(account.TokenExpiresAt)
+	account.CreatedAt = now
+	account.UpdatedAt = now
+	d.accounts[key] = account
+	return nil
+}
+
+type txKey struct{}
+
+// TxManager runs units of work on store. They are serialized with each other
+// and with repo methods called outside of them, and data is restored from
+// snapshot taken at start if unit of work fails.
+type TxManager struct {
+	store *Store
+}
+
+func NewTxManager(s *Store) *TxManager {
+	return &TxManager{store: s}
+}
+
+// Run fn as unit of work. Nested call joins outer one.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	m.store.txMu.Lock()
+	defer m.store.txMu.Unlock()
+
+	m.store.mu.Lock()
+	snapshot := m.store.data.clone()
+	m.store.mu.Unlock()
+
+	defer func() {
+		p := recover()
+		if p != nil || err != nil {
+			m.store.mu.Lock()
+			m.store.data = snapshot
+			m.store.mu.Unlock()
+		}
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, true))
+}
@@ -0,0 +1,18 @@
+package memory_test
+
+import (
+	"testing"
+
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repositories.Repositories {
+		repos, err := repositories.NewRepositories(repositories.StorageMemory, nil, repositories.StoragePostgres)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repos
+	})
+}
@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+type UsersRepo struct {
+	s *Store
+}
+
+func NewUsersRepo(s *Store) *UsersRepo {
+	return &UsersRepo{s: s}
+}
+
+// Set username of user if it was last changed before changedBefore.
+// Return ErrNotFound if it was changed later and ErrDuplicate
+// if other user has same username ignoring case.
+func (r *UsersRepo) UpdateUsername(ctx context.Context, userId uuid.UUID, username string, now, changedBefore time.Time) (core.User, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok || user.UsernameChangedAt.After(changedBefore) {
+		return core.User{}, psql.ErrNotFound
+	}
+	if r.s.data.usernameTaken(username, userId) {
+		return core.User{}, psql.ErrDuplicate
+	}
+	user.Username = username
+	user.UsernameChangedAt = ts(now)
+	r.s.data.users[userId] = user
+
+	return user, nil
+}
+
+// Set new password hash, delete password reset codes and all user sessions
+// except sessionId (token family). Return ids of deleted sessions.
+func (r *UsersRepo) ChangePassword(ctx context.Context, userId, sessionId uuid.UUID, passwordHash string) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok {
+		return nil, psql.ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	r.s.data.users[userId] = user
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, user.Email) && c.CodeType == core.CodeTypePassReset
+	})
+
+	return r.s.data.deleteSessions(func(s core.Session) bool {
+		return s.UserId == userId && s.FamilyId != sessionId
+	}), nil
+}
+
+// Replace email change codes of user with new one,
+// so only last requested new email can be confirmed.
+func (r *UsersRepo) SetEmailChangeCode(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	if _, ok := r.s.data.userByEmail(code.Email); !ok {
+		return errForeignKey
+	}
+	if c, ok := r.s.data.codes[code.Code]; ok && !(sameEmail(c.Email, code.Email) && c.CodeType == core.CodeTypeEmailChange) {
+		return psql.ErrDuplicate
+	}
+
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, code.Email) && c.CodeType == core.CodeTypeEmailChange
+	})
+	code.CodeType = core.CodeTypeEmailChange
+	return r.s.data.insertCode(code)
+}
+
+// Consume email change code and set its new email as verified user email.
+// Codes sent to old email are deleted, remaining codes follow user.
+// Return ErrNotFound if code already consumed and ErrDuplicate if new email is taken.
+func (r *UsersRepo) ChangeEmail(ctx context.Context, code core.CodeCredentials) (core.User, error) {
+	defer r.s.lock(ctx)()
+
+	code.CodeType = core.CodeTypeEmailChange
+	c, ok := r.s.data.codes[code.Code]
+	if !ok || !sameEmail(c.Email, code.Email) || c.CodeType != code.CodeType {
+		return core.User{}, psql.ErrNotFound
+	}
+	user, ok := r.s.data.userByEmail(code.Email)
+	if !ok {
+		return core.User{}, psql.ErrNotFound
+	}
+	if other, ok := r.s.data.userByEmail(c.NewEmail); ok && other.Id != user.Id {
+		return core.User{}, psql.ErrDuplicate
+	}
+
+	delete(r.s.data.codes, c.Code)
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, user.Email) &&
+			(c.CodeType == core.CodeTypePassReset || c.CodeType == core.CodeTypeEmailVerify)
+	})
+	for key, rest := range r.s.data.codes {
+		if sameEmail(rest.Email, user.Email) {
+			rest.Email = c.NewEmail
+			r.s.data.codes[key] = rest
+		}
+	}
+	user.Email = c.NewEmail
+	user.EmailVerified = true
+	r.s.data.users[user.Id] = user
+
+	return user, nil
+}
+
+// Schedule deletion of user at code expiry, replace deletion cancel codes of user
+// with new one and delete all user sessions. Return ids of deleted sessions.
+// Return ErrNotFound if deletion is already scheduled.
+func (r *UsersRepo) ScheduleDeletion(ctx context.Context, userId uuid.UUID, code core.CodeCredentials) ([]uuid.UUID, error) {
+	defer r.s.lock(ctx)()
+
+	user, ok := r.s.data.users[userId]
+	if !ok || user.DeletionScheduled() {
+		return nil, psql.ErrNotFound
+	}
+	if c, ok := r.s.data.codes[code.Code]; ok && !(sameEmail(c.Email, code.Email) && c.CodeType == core.CodeTypeDeletionCancel) {
+		return nil, psql.ErrDuplicate
+	}
+
+	user.DeleteAt = ts(code.ExpiresAt)
+	r.s.data.users[userId] = user
+	r.s.data.deleteCodes(func(c core.CodeCredentials) bool {
+		return sameEmail(c.Email, code.Email) && c.CodeType == core.CodeTypeDeletionCancel
+	})
+	code.CodeType = core.CodeTypeDeletionCancel
+	code.NewEmail = ""
+	if err := r.s.data.insertCode(code); err != nil {
+		return nil, err
+	}
+
+	return r.s.data.deleteSessions(func(s core.Session) bool { return s.UserId == userId }), nil
+}
+
+// Consume deletion cancel code and unschedule deletion of user.
+// Return ErrNotFound if code already consumed.
+func (r *UsersRepo) CancelDeletion(ctx context.Context, code core.CodeCredentials) error {
+	defer r.s.lock(ctx)()
+
+	code.CodeType = core.CodeTypeDeletionCancel
+	if _, ok := r.s.data.consumeCode(code); !ok {
+		return psql.ErrNotFound
+	}
+	if user, ok := r.s.data.userByEmail(code.Email); ok {
+		user.DeleteAt = epoch
+		r.s.data.users[user.Id] = user
+	}
+	return nil
+}
+
+// Delete users whose deletion grace period is over with all their data.
+func (r *UsersRepo) DeleteScheduledUsers(ctx context.Context, now time.Time) (int64, error) {
+	defer r.s.lock(ctx)()
+
+	var deleted int64
+	for _, user := range r.s.data.users {
+		if user.DeletionScheduled() && !user.DeleteAt.After(now) {
+			r.s.data.deleteUser(user)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Return every code of email ordered by type and expiry.
+// Code without expiry has epoch one, as in Postgres repo.
+func (r *UsersRepo) GetUserCodes(ctx context.Context, email string) ([]core.CodeCredentials, error) {
+	defer r.s.lock(ctx)()
+
+	var codes []core.CodeCredentials
+	for _, c := range r.s.data.codes {
+		if !sameEmail(c.Email, email) {
+			continue
+		}
+		if c.ExpiresAt.IsZero() {
+			c.ExpiresAt = epoch
+		}
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i].CodeType != codes[j].CodeType {
+			return codes[i].CodeType < codes[j].CodeType
+		}
+		if !codes[i].ExpiresAt.Equal(codes[j].ExpiresAt) {
+			return codes[i].ExpiresAt.Before(codes[j].ExpiresAt)
+		}
+		return codes[i].Code < codes[j].Code
+	})
+
+	return codes, nil
+}
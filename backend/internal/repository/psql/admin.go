@@ -0,0 +1,110 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type Admin interface {
+	SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error)
+	BanUser(ctx context.Context, userId uuid.UUID, reason string, now time.Time) ([]uuid.UUID, error)
+	UnbanUser(ctx context.Context, userId uuid.UUID) error
+	DeleteUserSessions(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, error)
+	SetRole(ctx context.Context, userId uuid.UUID, role string) (core.User, error)
+}
+
+type AdminRepo struct {
+	db *postgres.Postgres
+}
+
+func NewAdminPostgres(db *postgres.Postgres) *AdminRepo {
+	return &AdminRepo{db: db}
+}
+
+// Return page of users whose email or username contains query ignoring case, ordered by email.
+// Empty query matches every user.
+func (r *AdminRepo) SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error) {
+	var users []core.User
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE email ILIKE $1 OR username ILIKE $1
+		ORDER BY email LIMIT $2 OFFSET $3`, userTable)
+	pattern := "%" + escapeLike(search.Query) + "%"
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &users, query, pattern, search.Limit, search.Offset)
+
+	return users, err
+}
+
+// Mark user as banned and delete all user sessions in one transaction.
+// Banning banned user replaces reason and keeps ban time. Return ids of deleted sessions.
+// Return ErrNotFound if there is no such user.
+func (r *AdminRepo) BanUser(ctx context.Context, userId uuid.UUID, reason string, now time.Time) ([]uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf(`UPDATE %s SET ban_reason=$2,
+		banned_at=CASE WHEN banned_at > 'epoch' THEN banned_at ELSE $3 END WHERE id=$1`, userTable)
+	tag, err := tx.Exec(ctx, query, userId, reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	var sessionIds []uuid.UUID
+	query = fmt.Sprintf("WITH d AS (DELETE FROM %s WHERE user_id=$1 RETURNING family_id) SELECT DISTINCT family_id FROM d", userSessionTable)
+	if err := r.db.Scany.Select(ctx, tx, &sessionIds, query, userId); err != nil {
+		return nil, err
+	}
+
+	return sessionIds, tx.Commit(ctx)
+}
+
+// Lift ban of user. Return ErrNotFound if there is no such user.
+func (r *AdminRepo) UnbanUser(ctx context.Context, userId uuid.UUID) error {
+	query := fmt.Sprintf("UPDATE %s SET banned_at='epoch', ban_reason='' WHERE id=$1", userTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete all sessions of user. Return ids of deleted sessions.
+func (r *AdminRepo) DeleteUserSessions(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, error) {
+	var sessionIds []uuid.UUID
+
+	query := fmt.Sprintf("WITH d AS (DELETE FROM %s WHERE user_id=$1 RETURNING family_id) SELECT DISTINCT family_id FROM d", userSessionTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &sessionIds, query, userId)
+
+	return sessionIds, err
+}
+
+// Set role of user. Return ErrNotFound if there is no such user.
+func (r *AdminRepo) SetRole(ctx context.Context, userId uuid.UUID, role string) (core.User, error) {
+	var user core.User
+
+	query := fmt.Sprintf("UPDATE %s SET role=$2 WHERE id=$1 RETURNING *", userTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &user, query, userId, role)
+
+	return user, pgError(err)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Escape LIKE wildcards, so query is matched literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
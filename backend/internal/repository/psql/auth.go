@@ -3,6 +3,7 @@ package psql
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Cheasezz/anSpace/backend/internal/core"
 	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
@@ -11,13 +12,30 @@ import (
 
 type Auth interface {
 	CreateUser(ctx context.Context, signUp core.AuthCredentials) (uuid.UUID, error)
-	GetUserIdByLogPas(ctx context.Context, signIn core.AuthCredentials) (uuid.UUID, error)
 	GetUserById(ctx context.Context, userId uuid.UUID) (core.User, error)
 	GetUserByEmail(ctx context.Context, email string) (core.User, error)
-	SetPassResetCode(ctx context.Context, code core.CodeCredentials) error
-	DeletePassResetCode(ctx context.Context, code core.CodeCredentials) error
-	SetSession(ctx context.Context, session core.Session) error
+	UpdatePasswordHash(ctx context.Context, userId uuid.UUID, passwordHash string) error
+	SetCode(ctx context.Context, code core.CodeCredentials) error
+	GetCode(ctx context.Context, code core.CodeCredentials) (core.CodeCredentials, error)
+	DeleteCode(ctx context.Context, code core.CodeCredentials) error
+	DeleteCodesByType(ctx context.Context, email, codeType string) error
+	VerifyEmail(ctx context.Context, code core.CodeCredentials) error
+	ResetPassword(ctx context.Context, code core.CodeCredentials, passwordHash string) ([]uuid.UUID, error)
+	ConsumeCode(ctx context.Context, code core.CodeCredentials) error
+	SetTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userId uuid.UUID, recoveryCodes []core.CodeCredentials) error
+	DisableTOTP(ctx context.Context, userId uuid.UUID) error
+	SetTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error
+	UseMFAToken(ctx context.Context, jti string, expiresAt time.Time) error
+	DeleteExpiredMFATokens(ctx context.Context, now time.Time) (int64, error)
+	SetSession(ctx context.Context, session core.Session, maxSessions int) ([]uuid.UUID, error)
+	RotateSession(ctx context.Context, old core.Session, new core.Session) error
 	DeleteSession(ctx context.Context, session core.Session) error
+	DeleteSessionById(ctx context.Context, userId, sessionId uuid.UUID) error
+	DeleteSessionsExcept(ctx context.Context, userId, sessionId uuid.UUID) ([]uuid.UUID, error)
+	GetUserSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error)
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+	DeleteExpiredCodes(ctx context.Context, now time.Time) (int64, error)
 	GetUserSessionByRefreshToken(ctx context.Context, refreshToken string) (core.Session, error)
 }
 
@@ -30,7 +48,7 @@ func NewAuthPostgres(db *postgres.Postgres) *AuthRepo {
 }
 
 func (r *AuthRepo) CreateUser(ctx context.Context, signUp core.AuthCredentials) (uuid.UUID, error) {
-	tx, err := r.db.Pool.Begin(ctx)
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
@@ -43,68 +61,375 @@ func (r *AuthRepo) CreateUser(ctx context.Context, signUp core.AuthCredentials)
 		if errR := tx.Rollback(ctx); errR != nil {
 			return uuid.UUID{}, errR
 		}
-		return uuid.UUID{}, err
+		return uuid.UUID{}, pgError(err)
 	}
 
 	return id, tx.Commit(ctx)
 }
 
-func (r *AuthRepo) GetUserIdByLogPas(ctx context.Context, signIn core.AuthCredentials) (uuid.UUID, error) {
-	var userId uuid.UUID
-	query := fmt.Sprintf("SELECT id FROM %s WHERE email=$1 AND password_hash=$2", userTable)
-	err := r.db.Scany.Get(ctx, r.db.Pool, &userId, query, signIn.Email, signIn.Password)
-
-	return userId, err
-}
-
 func (r *AuthRepo) GetUserById(ctx context.Context, userId uuid.UUID) (core.User, error) {
 	var user core.User
 	query := fmt.Sprintf("SELECT * FROM %s WHERE id=$1", userTable)
-	err := r.db.Scany.Get(ctx, r.db.Pool, &user, query, userId)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &user, query, userId)
 
-	return user, err
+	return user, pgError(err)
 }
 
 func (r *AuthRepo) GetUserByEmail(ctx context.Context, email string) (core.User, error) {
 	var user core.User
 	query := fmt.Sprintf("SELECT * FROM %s WHERE email=$1", userTable)
-	err := r.db.Scany.Get(ctx, r.db.Pool, &user, query, email)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &user, query, email)
 
-	return user, err
+	return user, pgError(err)
+}
+
+func (r *AuthRepo) UpdatePasswordHash(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	query := fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE id=$2", userTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, passwordHash, userId)
+
+	return err
 }
 
-func (r *AuthRepo) SetPassResetCode(ctx context.Context, code core.CodeCredentials) error {
+func (r *AuthRepo) SetCode(ctx context.Context, code core.CodeCredentials) error {
 	query := fmt.Sprintf("INSERT INTO %s (user_email, code, code_type, expires_at) values ($1, $2, $3, $4)", codesTable)
-	_, err := r.db.Pool.Exec(ctx, query, code.Email, code.Code, code.CodeType, code.ExpiresAt)
+	_, err := conn(ctx, r.db).Exec(ctx, query, code.Email, code.Code, code.CodeType, code.ExpiresAt)
 
 	return err
 }
 
-func (r *AuthRepo) DeletePassResetCode(ctx context.Context, code core.CodeCredentials) error {
+func (r *AuthRepo) GetCode(ctx context.Context, code core.CodeCredentials) (core.CodeCredentials, error) {
+	var resetCode core.CodeCredentials
+	query := fmt.Sprintf("SELECT user_email AS email, code, code_type, expires_at, new_email FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3", codesTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &resetCode, query, code.Code, code.Email, code.CodeType)
+
+	return resetCode, pgError(err)
+}
+
+func (r *AuthRepo) DeleteCode(ctx context.Context, code core.CodeCredentials) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2", codesTable)
-	_, err := r.db.Pool.Exec(ctx, query, code.Code, code.Email)
+	_, err := conn(ctx, r.db).Exec(ctx, query, code.Code, code.Email)
 
 	return err
 }
 
-func (r *AuthRepo) SetSession(ctx context.Context, session core.Session) error {
-	query := fmt.Sprintf("INSERT INTO  %s (user_id,refresh_token) values ($1, $2)", userSessionTable)
-	_, err := r.db.Pool.Exec(ctx, query, session.UserId, session.RefreshToken)
+func (r *AuthRepo) DeleteCodesByType(ctx context.Context, email, codeType string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, email, codeType)
 
 	return err
 }
+
+// Consume email verification code and mark user email as verified
+// in one transaction. Return ErrNotFound if code already consumed.
+func (r *AuthRepo) VerifyEmail(ctx context.Context, code core.CodeCredentials) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3", codesTable)
+	tag, err := tx.Exec(ctx, query, code.Code, code.Email, code.CodeType)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET email_verified=TRUE WHERE email=$1", userTable)
+	if _, err := tx.Exec(ctx, query, code.Email); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Consume password reset code, set new password hash and delete all user sessions
+// in one transaction. Return ids of deleted sessions (token families).
+// Return ErrNotFound if code already consumed.
+func (r *AuthRepo) ResetPassword(ctx context.Context, code core.CodeCredentials, passwordHash string) ([]uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3", codesTable)
+	tag, err := tx.Exec(ctx, query, code.Code, code.Email, code.CodeType)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	var userId uuid.UUID
+	query = fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE email=$2 RETURNING id", userTable)
+	if err := tx.QueryRow(ctx, query, passwordHash, code.Email).Scan(&userId); err != nil {
+		return nil, err
+	}
+
+	var sessionIds []uuid.UUID
+	query = fmt.Sprintf("WITH d AS (DELETE FROM %s WHERE user_id=$1 RETURNING family_id) SELECT DISTINCT family_id FROM d", userSessionTable)
+	if err := r.db.Scany.Select(ctx, tx, &sessionIds, query, userId); err != nil {
+		return nil, err
+	}
+
+	return sessionIds, tx.Commit(ctx)
+}
+
+// Delete code of given type. Return ErrNotFound if there is no such code,
+// so one code can't be used twice by concurrent requests.
+func (r *AuthRepo) ConsumeCode(ctx context.Context, code core.CodeCredentials) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3", codesTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, code.Code, code.Email, code.CodeType)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Set new not yet enabled TOTP secret of user.
+// Return ErrNotFound if user has TOTP enabled already.
+func (r *AuthRepo) SetTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_secret=$1 WHERE id=$2 AND totp_enabled=FALSE", userTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, secret, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Enable TOTP of user and replace user recovery codes in one transaction.
+// Recovery codes have no expiry. Return ErrNotFound if TOTP is already
+// enabled or secret isn't set.
+func (r *AuthRepo) EnableTOTP(ctx context.Context, userId uuid.UUID, recoveryCodes []core.CodeCredentials) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	query := fmt.Sprintf("UPDATE %s SET totp_enabled=TRUE WHERE id=$1 AND totp_enabled=FALSE AND totp_secret<>'' RETURNING email", userTable)
+	if err := tx.QueryRow(ctx, query, userId).Scan(&email); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	if _, err := tx.Exec(ctx, query, email, core.CodeTypeMFARecovery); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (user_email, code, code_type, expires_at) values ($1, $2, $3, NULL)", codesTable)
+	for _, code := range recoveryCodes {
+		if _, err := tx.Exec(ctx, query, email, code.Code, core.CodeTypeMFARecovery); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Disable TOTP of user, clear secret and delete recovery codes in one transaction.
+func (r *AuthRepo) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	query := fmt.Sprintf("UPDATE %s SET totp_enabled=FALSE, totp_secret='' WHERE id=$1 RETURNING email", userTable)
+	if err := tx.QueryRow(ctx, query, userId).Scan(&email); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	if _, err := tx.Exec(ctx, query, email, core.CodeTypeMFARecovery); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Set time step of last accepted TOTP code of user.
+// Return ErrNotFound unless step is after stored one, so code is accepted once.
+func (r *AuthRepo) SetTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_last_step=$1 WHERE id=$2 AND totp_last_step<$1", userTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, step, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Mark mfa pending token as used until it expires.
+// Return ErrNotFound if token is already used.
+func (r *AuthRepo) UseMFAToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := fmt.Sprintf("INSERT INTO %s (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", usedMFATokensTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *AuthRepo) DeleteExpiredMFATokens(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", usedMFATokensTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
+
+// Write new session and evict oldest user sessions (token families) above maxSessions
+// in one transaction. User row is locked, so concurrent logins of one user can't exceed cap.
+// maxSessions <= 0 disables eviction. Return ids of evicted sessions.
+func (r *AuthRepo) SetSession(ctx context.Context, session core.Session, maxSessions int) ([]uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if maxSessions > 0 {
+		query := fmt.Sprintf("SELECT id FROM %s WHERE id=$1 FOR UPDATE", userTable)
+		if _, err := tx.Exec(ctx, query, session.UserId); err != nil {
+			return nil, err
+		}
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, refresh_token, family_id, parent_token, expires_at, user_agent, ip)
+		values ($1, $2, $3, $4, $5, $6, $7)`, userSessionTable)
+	_, err = tx.Exec(ctx, query, session.UserId, session.RefreshToken, session.FamilyId, session.ParentToken,
+		session.ExpiresAt, session.UserAgent, session.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionIds []uuid.UUID
+	if maxSessions > 0 {
+		query = fmt.Sprintf(`WITH d AS (DELETE FROM %[1]s WHERE user_id=$1 AND family_id IN (
+			SELECT family_id FROM %[1]s WHERE user_id=$1 AND rotated=FALSE
+			ORDER BY created_at DESC, last_used_at DESC OFFSET $2) RETURNING family_id)
+			SELECT DISTINCT family_id FROM d`, userSessionTable)
+		if err := r.db.Scany.Select(ctx, tx, &sessionIds, query, session.UserId, maxSessions); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessionIds, tx.Commit(ctx)
+}
+
+// Mark old session as rotated and write new one in one transaction.
+// Return ErrNotFound if old session already rotated.
+func (r *AuthRepo) RotateSession(ctx context.Context, old core.Session, new core.Session) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("UPDATE %s SET rotated=TRUE WHERE refresh_token=$1 AND rotated=FALSE", userSessionTable)
+	tag, err := tx.Exec(ctx, query, old.RefreshToken)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (user_id, refresh_token, family_id, parent_token, expires_at, created_at, user_agent, ip)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)`, userSessionTable)
+	_, err = tx.Exec(ctx, query, new.UserId, new.RefreshToken, new.FamilyId, new.ParentToken,
+		new.ExpiresAt, new.CreatedAt, new.UserAgent, new.IP)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete session with all tokens of its family.
 func (r *AuthRepo) DeleteSession(ctx context.Context, session core.Session) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE refresh_token = $1 AND user_id = $2", userSessionTable)
-	_, err := r.db.Pool.Exec(ctx, query, session.RefreshToken, session.UserId)
+	query := fmt.Sprintf("DELETE FROM %s WHERE family_id = $1 AND user_id = $2", userSessionTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, session.FamilyId, session.UserId)
 
 	return err
 }
 
+// Delete session with all tokens of its family.
+// Return ErrNotFound if user has no session with such id.
+func (r *AuthRepo) DeleteSessionById(ctx context.Context, userId, sessionId uuid.UUID) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE family_id = $1 AND user_id = $2", userSessionTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, sessionId, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete all user sessions except given one. Return ids of deleted sessions.
+func (r *AuthRepo) DeleteSessionsExcept(ctx context.Context, userId, sessionId uuid.UUID) ([]uuid.UUID, error) {
+	var sessionIds []uuid.UUID
+
+	query := fmt.Sprintf(`WITH d AS (DELETE FROM %s WHERE user_id = $1 AND family_id <> $2 RETURNING family_id)
+		SELECT DISTINCT family_id FROM d`, userSessionTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &sessionIds, query, userId, sessionId)
+
+	return sessionIds, err
+}
+
+// Return last token of every user token family, recently used first.
+func (r *AuthRepo) GetUserSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error) {
+	var sessions []core.Session
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id=$1 AND rotated=FALSE ORDER BY last_used_at DESC", sessionColumns, userSessionTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &sessions, query, userId)
+
+	return sessions, err
+}
+
+func (r *AuthRepo) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", userSessionTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
+
+func (r *AuthRepo) DeleteExpiredCodes(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", codesTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
+
 func (r *AuthRepo) GetUserSessionByRefreshToken(ctx context.Context, refreshToken string) (core.Session, error) {
 	var session core.Session
 
-	query := fmt.Sprintf("SELECT user_id, refresh_token FROM %s WHERE refresh_token=$1", userSessionTable)
-	err := r.db.Scany.Get(ctx, r.db.Pool, &session, query, refreshToken)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE refresh_token=$1", sessionColumns, userSessionTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &session, query, refreshToken)
 
-	return session, err
+	return session, pgError(err)
 }
@@ -0,0 +1,35 @@
+package psql
+
+import (
+	"errors"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	// ErrNotFound is returned when query matches no rows. It wraps pgx.ErrNoRows,
+	// so errors.Is(err, pgx.ErrNoRows) is true for it.
+	ErrNotFound = &core.Error{Kind: core.ErrNotFound, Code: "not_found", Message: "not found", Err: pgx.ErrNoRows}
+	// ErrDuplicate is returned when write violates unique constraint.
+	ErrDuplicate = core.NewError(core.ErrConflict, "duplicate", "duplicate key value")
+)
+
+// Translate pgx errors into domain ones, other errors are returned as is.
+func pgError(err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return ErrDuplicate
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
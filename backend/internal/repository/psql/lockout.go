@@ -0,0 +1,96 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+)
+
+type Lockout interface {
+	AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (core.Lockout, error)
+	RemoveFailure(ctx context.Context, key string) (core.Lockout, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	GetLockout(ctx context.Context, key string) (core.Lockout, error)
+	GetLockouts(ctx context.Context, now time.Time) ([]core.Lockout, error)
+	DeleteLockout(ctx context.Context, key string) error
+	DeleteExpiredLockouts(ctx context.Context, now time.Time) (int64, error)
+}
+
+type LockoutRepo struct {
+	db *postgres.Postgres
+}
+
+func NewLockoutPostgres(db *postgres.Postgres) *LockoutRepo {
+	return &LockoutRepo{db: db}
+}
+
+// Count failure of key. Failures older than window are forgotten, counter starts again.
+// Return updated lockout.
+func (r *LockoutRepo) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (core.Lockout, error) {
+	var lockout core.Lockout
+
+	query := fmt.Sprintf(`INSERT INTO %s (key, failures, last_failure_at, expires_at) VALUES ($1, 1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN %[1]s.last_failure_at < $4 THEN 1 ELSE %[1]s.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at,
+			expires_at = GREATEST(%[1]s.locked_until, EXCLUDED.expires_at)
+		RETURNING key, failures, last_failure_at, locked_until, expires_at`, lockoutsTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &lockout, query, key, now, now.Add(window), now.Add(-window))
+
+	return lockout, err
+}
+
+// Take back one failure of key. Return updated lockout or ErrNotFound if key has none.
+func (r *LockoutRepo) RemoveFailure(ctx context.Context, key string) (core.Lockout, error) {
+	var lockout core.Lockout
+
+	query := fmt.Sprintf(`UPDATE %s SET failures=GREATEST(failures-1, 0) WHERE key=$1
+		RETURNING key, failures, last_failure_at, locked_until, expires_at`, lockoutsTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &lockout, query, key)
+
+	return lockout, err
+}
+
+func (r *LockoutRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET locked_until=$2, expires_at=GREATEST(expires_at, $2) WHERE key=$1", lockoutsTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, key, until)
+
+	return err
+}
+
+func (r *LockoutRepo) GetLockout(ctx context.Context, key string) (core.Lockout, error) {
+	var lockout core.Lockout
+
+	query := fmt.Sprintf("SELECT key, failures, last_failure_at, locked_until, expires_at FROM %s WHERE key=$1", lockoutsTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &lockout, query, key)
+
+	return lockout, err
+}
+
+// Return keys locked at now, longest lock first.
+func (r *LockoutRepo) GetLockouts(ctx context.Context, now time.Time) ([]core.Lockout, error) {
+	var lockouts []core.Lockout
+
+	query := fmt.Sprintf(`SELECT key, failures, last_failure_at, locked_until, expires_at FROM %s
+		WHERE locked_until > $1 ORDER BY locked_until DESC`, lockoutsTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &lockouts, query, now)
+
+	return lockouts, err
+}
+
+func (r *LockoutRepo) DeleteLockout(ctx context.Context, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE key=$1", lockoutsTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, key)
+
+	return err
+}
+
+func (r *LockoutRepo) DeleteExpiredLockouts(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", lockoutsTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
@@ -12,6 +12,7 @@ package mock_psql
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	core "github.com/Cheasezz/anSpace/backend/internal/core"
 	uuid "github.com/google/uuid"
@@ -41,6 +42,20 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// ConsumeCode mocks base method.
+func (m *MockAuth) ConsumeCode(ctx context.Context, code core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeCode indicates an expected call of ConsumeCode.
+func (mr *MockAuthMockRecorder) ConsumeCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeCode", reflect.TypeOf((*MockAuth)(nil).ConsumeCode), ctx, code)
+}
+
 // CreateUser mocks base method.
 func (m *MockAuth) CreateUser(ctx context.Context, signUp core.AuthCredentials) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
@@ -56,18 +71,77 @@ func (mr *MockAuthMockRecorder) CreateUser(ctx, signUp any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuth)(nil).CreateUser), ctx, signUp)
 }
 
-// DeletePassResetCode mocks base method.
-func (m *MockAuth) DeletePassResetCode(ctx context.Context, code core.CodeCredentials) error {
+// DeleteCode mocks base method.
+func (m *MockAuth) DeleteCode(ctx context.Context, code core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCode indicates an expected call of DeleteCode.
+func (mr *MockAuthMockRecorder) DeleteCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCode", reflect.TypeOf((*MockAuth)(nil).DeleteCode), ctx, code)
+}
+
+// DeleteCodesByType mocks base method.
+func (m *MockAuth) DeleteCodesByType(ctx context.Context, email, codeType string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePassResetCode", ctx, code)
+	ret := m.ctrl.Call(m, "DeleteCodesByType", ctx, email, codeType)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeletePassResetCode indicates an expected call of DeletePassResetCode.
-func (mr *MockAuthMockRecorder) DeletePassResetCode(ctx, code any) *gomock.Call {
+// DeleteCodesByType indicates an expected call of DeleteCodesByType.
+func (mr *MockAuthMockRecorder) DeleteCodesByType(ctx, email, codeType any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePassResetCode", reflect.TypeOf((*MockAuth)(nil).DeletePassResetCode), ctx, code)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCodesByType", reflect.TypeOf((*MockAuth)(nil).DeleteCodesByType), ctx, email, codeType)
+}
+
+// DeleteExpiredCodes mocks base method.
+func (m *MockAuth) DeleteExpiredCodes(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredCodes", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredCodes indicates an expected call of DeleteExpiredCodes.
+func (mr *MockAuthMockRecorder) DeleteExpiredCodes(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredCodes", reflect.TypeOf((*MockAuth)(nil).DeleteExpiredCodes), ctx, now)
+}
+
+// DeleteExpiredMFATokens mocks base method.
+func (m *MockAuth) DeleteExpiredMFATokens(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredMFATokens", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredMFATokens indicates an expected call of DeleteExpiredMFATokens.
+func (mr *MockAuthMockRecorder) DeleteExpiredMFATokens(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredMFATokens", reflect.TypeOf((*MockAuth)(nil).DeleteExpiredMFATokens), ctx, now)
+}
+
+// DeleteExpiredSessions mocks base method.
+func (m *MockAuth) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredSessions", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredSessions indicates an expected call of DeleteExpiredSessions.
+func (mr *MockAuthMockRecorder) DeleteExpiredSessions(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredSessions", reflect.TypeOf((*MockAuth)(nil).DeleteExpiredSessions), ctx, now)
 }
 
 // DeleteSession mocks base method.
@@ -84,6 +158,78 @@ func (mr *MockAuthMockRecorder) DeleteSession(ctx, session any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockAuth)(nil).DeleteSession), ctx, session)
 }
 
+// DeleteSessionById mocks base method.
+func (m *MockAuth) DeleteSessionById(ctx context.Context, userId, sessionId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSessionById", ctx, userId, sessionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSessionById indicates an expected call of DeleteSessionById.
+func (mr *MockAuthMockRecorder) DeleteSessionById(ctx, userId, sessionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSessionById", reflect.TypeOf((*MockAuth)(nil).DeleteSessionById), ctx, userId, sessionId)
+}
+
+// DeleteSessionsExcept mocks base method.
+func (m *MockAuth) DeleteSessionsExcept(ctx context.Context, userId, sessionId uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSessionsExcept", ctx, userId, sessionId)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSessionsExcept indicates an expected call of DeleteSessionsExcept.
+func (mr *MockAuthMockRecorder) DeleteSessionsExcept(ctx, userId, sessionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSessionsExcept", reflect.TypeOf((*MockAuth)(nil).DeleteSessionsExcept), ctx, userId, sessionId)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockAuth) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockAuthMockRecorder) DisableTOTP(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockAuth)(nil).DisableTOTP), ctx, userId)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockAuth) EnableTOTP(ctx context.Context, userId uuid.UUID, recoveryCodes []core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userId, recoveryCodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockAuthMockRecorder) EnableTOTP(ctx, userId, recoveryCodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockAuth)(nil).EnableTOTP), ctx, userId, recoveryCodes)
+}
+
+// GetCode mocks base method.
+func (m *MockAuth) GetCode(ctx context.Context, code core.CodeCredentials) (core.CodeCredentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCode", ctx, code)
+	ret0, _ := ret[0].(core.CodeCredentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCode indicates an expected call of GetCode.
+func (mr *MockAuthMockRecorder) GetCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCode", reflect.TypeOf((*MockAuth)(nil).GetCode), ctx, code)
+}
+
 // GetUserByEmail mocks base method.
 func (m *MockAuth) GetUserByEmail(ctx context.Context, email string) (core.User, error) {
 	m.ctrl.T.Helper()
@@ -114,60 +260,160 @@ func (mr *MockAuthMockRecorder) GetUserById(ctx, userId any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockAuth)(nil).GetUserById), ctx, userId)
 }
 
-// GetUserIdByLogPas mocks base method.
-func (m *MockAuth) GetUserIdByLogPas(ctx context.Context, signIn core.AuthCredentials) (uuid.UUID, error) {
+// GetUserSessionByRefreshToken mocks base method.
+func (m *MockAuth) GetUserSessionByRefreshToken(ctx context.Context, refreshToken string) (core.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserIdByLogPas", ctx, signIn)
-	ret0, _ := ret[0].(uuid.UUID)
+	ret := m.ctrl.Call(m, "GetUserSessionByRefreshToken", ctx, refreshToken)
+	ret0, _ := ret[0].(core.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserIdByLogPas indicates an expected call of GetUserIdByLogPas.
-func (mr *MockAuthMockRecorder) GetUserIdByLogPas(ctx, signIn any) *gomock.Call {
+// GetUserSessionByRefreshToken indicates an expected call of GetUserSessionByRefreshToken.
+func (mr *MockAuthMockRecorder) GetUserSessionByRefreshToken(ctx, refreshToken any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIdByLogPas", reflect.TypeOf((*MockAuth)(nil).GetUserIdByLogPas), ctx, signIn)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSessionByRefreshToken", reflect.TypeOf((*MockAuth)(nil).GetUserSessionByRefreshToken), ctx, refreshToken)
 }
 
-// GetUserSessionByRefreshToken mocks base method.
-func (m *MockAuth) GetUserSessionByRefreshToken(ctx context.Context, refreshToken string) (core.Session, error) {
+// GetUserSessions mocks base method.
+func (m *MockAuth) GetUserSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserSessionByRefreshToken", ctx, refreshToken)
-	ret0, _ := ret[0].(core.Session)
+	ret := m.ctrl.Call(m, "GetUserSessions", ctx, userId)
+	ret0, _ := ret[0].([]core.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserSessionByRefreshToken indicates an expected call of GetUserSessionByRefreshToken.
-func (mr *MockAuthMockRecorder) GetUserSessionByRefreshToken(ctx, refreshToken any) *gomock.Call {
+// GetUserSessions indicates an expected call of GetUserSessions.
+func (mr *MockAuthMockRecorder) GetUserSessions(ctx, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSessionByRefreshToken", reflect.TypeOf((*MockAuth)(nil).GetUserSessionByRefreshToken), ctx, refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSessions", reflect.TypeOf((*MockAuth)(nil).GetUserSessions), ctx, userId)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(ctx context.Context, code core.CodeCredentials, passwordHash string) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, code, passwordHash)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(ctx, code, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), ctx, code, passwordHash)
 }
 
-// SetPassResetCode mocks base method.
-func (m *MockAuth) SetPassResetCode(ctx context.Context, code core.CodeCredentials) error {
+// RotateSession mocks base method.
+func (m *MockAuth) RotateSession(ctx context.Context, old, new core.Session) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetPassResetCode", ctx, code)
+	ret := m.ctrl.Call(m, "RotateSession", ctx, old, new)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetPassResetCode indicates an expected call of SetPassResetCode.
-func (mr *MockAuthMockRecorder) SetPassResetCode(ctx, code any) *gomock.Call {
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockAuthMockRecorder) RotateSession(ctx, old, new any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassResetCode", reflect.TypeOf((*MockAuth)(nil).SetPassResetCode), ctx, code)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockAuth)(nil).RotateSession), ctx, old, new)
 }
 
-// SetSession mocks base method.
-func (m *MockAuth) SetSession(ctx context.Context, session core.Session) error {
+// SetCode mocks base method.
+func (m *MockAuth) SetCode(ctx context.Context, code core.CodeCredentials) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetSession", ctx, session)
+	ret := m.ctrl.Call(m, "SetCode", ctx, code)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// SetCode indicates an expected call of SetCode.
+func (mr *MockAuthMockRecorder) SetCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCode", reflect.TypeOf((*MockAuth)(nil).SetCode), ctx, code)
+}
+
+// SetSession mocks base method.
+func (m *MockAuth) SetSession(ctx context.Context, session core.Session, maxSessions int) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSession", ctx, session, maxSessions)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // SetSession indicates an expected call of SetSession.
-func (mr *MockAuthMockRecorder) SetSession(ctx, session any) *gomock.Call {
+func (mr *MockAuthMockRecorder) SetSession(ctx, session, maxSessions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSession", reflect.TypeOf((*MockAuth)(nil).SetSession), ctx, session, maxSessions)
+}
+
+// SetTOTPSecret mocks base method.
+func (m *MockAuth) SetTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPSecret", ctx, userId, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPSecret indicates an expected call of SetTOTPSecret.
+func (mr *MockAuthMockRecorder) SetTOTPSecret(ctx, userId, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPSecret", reflect.TypeOf((*MockAuth)(nil).SetTOTPSecret), ctx, userId, secret)
+}
+
+// SetTOTPStep mocks base method.
+func (m *MockAuth) SetTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPStep", ctx, userId, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPStep indicates an expected call of SetTOTPStep.
+func (mr *MockAuthMockRecorder) SetTOTPStep(ctx, userId, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPStep", reflect.TypeOf((*MockAuth)(nil).SetTOTPStep), ctx, userId, step)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockAuth) UpdatePasswordHash(ctx context.Context, userId uuid.UUID, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, userId, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockAuthMockRecorder) UpdatePasswordHash(ctx, userId, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockAuth)(nil).UpdatePasswordHash), ctx, userId, passwordHash)
+}
+
+// UseMFAToken mocks base method.
+func (m *MockAuth) UseMFAToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMFAToken", ctx, jti, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseMFAToken indicates an expected call of UseMFAToken.
+func (mr *MockAuthMockRecorder) UseMFAToken(ctx, jti, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMFAToken", reflect.TypeOf((*MockAuth)(nil).UseMFAToken), ctx, jti, expiresAt)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuth) VerifyEmail(ctx context.Context, code core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthMockRecorder) VerifyEmail(ctx, code any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSession", reflect.TypeOf((*MockAuth)(nil).SetSession), ctx, session)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuth)(nil).VerifyEmail), ctx, code)
 }
@@ -0,0 +1,145 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/psql/lockout.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/psql/lockout.go -destination=internal/repository/psql/mocks/mock_lockout_repo.go
+//
+
+// Package mock_psql is a generated GoMock package.
+package mock_psql
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLockout is a mock of Lockout interface.
+type MockLockout struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockoutMockRecorder
+}
+
+// MockLockoutMockRecorder is the mock recorder for MockLockout.
+type MockLockoutMockRecorder struct {
+	mock *MockLockout
+}
+
+// NewMockLockout creates a new mock instance.
+func NewMockLockout(ctrl *gomock.Controller) *MockLockout {
+	mock := &MockLockout{ctrl: ctrl}
+	mock.recorder = &MockLockoutMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLockout) EXPECT() *MockLockoutMockRecorder {
+	return m.recorder
+}
+
+// AddFailure mocks base method.
+func (m *MockLockout) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (core.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFailure", ctx, key, now, window)
+	ret0, _ := ret[0].(core.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddFailure indicates an expected call of AddFailure.
+func (mr *MockLockoutMockRecorder) AddFailure(ctx, key, now, window any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFailure", reflect.TypeOf((*MockLockout)(nil).AddFailure), ctx, key, now, window)
+}
+
+// DeleteExpiredLockouts mocks base method.
+func (m *MockLockout) DeleteExpiredLockouts(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredLockouts", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredLockouts indicates an expected call of DeleteExpiredLockouts.
+func (mr *MockLockoutMockRecorder) DeleteExpiredLockouts(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredLockouts", reflect.TypeOf((*MockLockout)(nil).DeleteExpiredLockouts), ctx, now)
+}
+
+// DeleteLockout mocks base method.
+func (m *MockLockout) DeleteLockout(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLockout", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLockout indicates an expected call of DeleteLockout.
+func (mr *MockLockoutMockRecorder) DeleteLockout(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLockout", reflect.TypeOf((*MockLockout)(nil).DeleteLockout), ctx, key)
+}
+
+// GetLockout mocks base method.
+func (m *MockLockout) GetLockout(ctx context.Context, key string) (core.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLockout", ctx, key)
+	ret0, _ := ret[0].(core.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLockout indicates an expected call of GetLockout.
+func (mr *MockLockoutMockRecorder) GetLockout(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLockout", reflect.TypeOf((*MockLockout)(nil).GetLockout), ctx, key)
+}
+
+// GetLockouts mocks base method.
+func (m *MockLockout) GetLockouts(ctx context.Context, now time.Time) ([]core.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLockouts", ctx, now)
+	ret0, _ := ret[0].([]core.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLockouts indicates an expected call of GetLockouts.
+func (mr *MockLockoutMockRecorder) GetLockouts(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLockouts", reflect.TypeOf((*MockLockout)(nil).GetLockouts), ctx, now)
+}
+
+// Lock mocks base method.
+func (m *MockLockout) Lock(ctx context.Context, key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockLockoutMockRecorder) Lock(ctx, key, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockLockout)(nil).Lock), ctx, key, until)
+}
+
+// RemoveFailure mocks base method.
+func (m *MockLockout) RemoveFailure(ctx context.Context, key string) (core.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFailure", ctx, key)
+	ret0, _ := ret[0].(core.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveFailure indicates an expected call of RemoveFailure.
+func (mr *MockLockoutMockRecorder) RemoveFailure(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFailure", reflect.TypeOf((*MockLockout)(nil).RemoveFailure), ctx, key)
+}
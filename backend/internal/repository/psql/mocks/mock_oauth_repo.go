@@ -0,0 +1,189 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/psql/oauth.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/psql/oauth.go -destination=internal/repository/psql/mocks/mock_oauth_repo.go
+//
+
+// Package mock_psql is a generated GoMock package.
+package mock_psql
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth is a mock of OAuth interface.
+type MockOAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthMockRecorder
+}
+
+// MockOAuthMockRecorder is the mock recorder for MockOAuth.
+type MockOAuthMockRecorder struct {
+	mock *MockOAuth
+}
+
+// NewMockOAuth creates a new mock instance.
+func NewMockOAuth(ctrl *gomock.Controller) *MockOAuth {
+	mock := &MockOAuth{ctrl: ctrl}
+	mock.recorder = &MockOAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth) EXPECT() *MockOAuthMockRecorder {
+	return m.recorder
+}
+
+// ConsumeOAuthState mocks base method.
+func (m *MockOAuth) ConsumeOAuthState(ctx context.Context, state string, now time.Time) (core.OAuthState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOAuthState", ctx, state, now)
+	ret0, _ := ret[0].(core.OAuthState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOAuthState indicates an expected call of ConsumeOAuthState.
+func (mr *MockOAuthMockRecorder) ConsumeOAuthState(ctx, state, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOAuthState", reflect.TypeOf((*MockOAuth)(nil).ConsumeOAuthState), ctx, state, now)
+}
+
+// CreateUserWithAccount mocks base method.
+func (m *MockOAuth) CreateUserWithAccount(ctx context.Context, email string, account core.LinkedAccount) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserWithAccount", ctx, email, account)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserWithAccount indicates an expected call of CreateUserWithAccount.
+func (mr *MockOAuthMockRecorder) CreateUserWithAccount(ctx, email, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithAccount", reflect.TypeOf((*MockOAuth)(nil).CreateUserWithAccount), ctx, email, account)
+}
+
+// DeleteExpiredOAuthStates mocks base method.
+func (m *MockOAuth) DeleteExpiredOAuthStates(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredOAuthStates", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredOAuthStates indicates an expected call of DeleteExpiredOAuthStates.
+func (mr *MockOAuthMockRecorder) DeleteExpiredOAuthStates(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredOAuthStates", reflect.TypeOf((*MockOAuth)(nil).DeleteExpiredOAuthStates), ctx, now)
+}
+
+// DeleteLinkedAccount mocks base method.
+func (m *MockOAuth) DeleteLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLinkedAccount", ctx, userId, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLinkedAccount indicates an expected call of DeleteLinkedAccount.
+func (mr *MockOAuthMockRecorder) DeleteLinkedAccount(ctx, userId, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLinkedAccount", reflect.TypeOf((*MockOAuth)(nil).DeleteLinkedAccount), ctx, userId, provider)
+}
+
+// GetLinkedAccount mocks base method.
+func (m *MockOAuth) GetLinkedAccount(ctx context.Context, provider, providerUserId string) (core.LinkedAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkedAccount", ctx, provider, providerUserId)
+	ret0, _ := ret[0].(core.LinkedAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkedAccount indicates an expected call of GetLinkedAccount.
+func (mr *MockOAuthMockRecorder) GetLinkedAccount(ctx, provider, providerUserId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkedAccount", reflect.TypeOf((*MockOAuth)(nil).GetLinkedAccount), ctx, provider, providerUserId)
+}
+
+// GetUserLinkedAccount mocks base method.
+func (m *MockOAuth) GetUserLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) (core.LinkedAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserLinkedAccount", ctx, userId, provider)
+	ret0, _ := ret[0].(core.LinkedAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserLinkedAccount indicates an expected call of GetUserLinkedAccount.
+func (mr *MockOAuthMockRecorder) GetUserLinkedAccount(ctx, userId, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserLinkedAccount", reflect.TypeOf((*MockOAuth)(nil).GetUserLinkedAccount), ctx, userId, provider)
+}
+
+// GetUserLinkedAccounts mocks base method.
+func (m *MockOAuth) GetUserLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserLinkedAccounts", ctx, userId)
+	ret0, _ := ret[0].([]core.LinkedAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserLinkedAccounts indicates an expected call of GetUserLinkedAccounts.
+func (mr *MockOAuthMockRecorder) GetUserLinkedAccounts(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserLinkedAccounts", reflect.TypeOf((*MockOAuth)(nil).GetUserLinkedAccounts), ctx, userId)
+}
+
+// LinkAccount mocks base method.
+func (m *MockOAuth) LinkAccount(ctx context.Context, account core.LinkedAccount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkAccount", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkAccount indicates an expected call of LinkAccount.
+func (mr *MockOAuthMockRecorder) LinkAccount(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkAccount", reflect.TypeOf((*MockOAuth)(nil).LinkAccount), ctx, account)
+}
+
+// SetOAuthState mocks base method.
+func (m *MockOAuth) SetOAuthState(ctx context.Context, state core.OAuthState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOAuthState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOAuthState indicates an expected call of SetOAuthState.
+func (mr *MockOAuthMockRecorder) SetOAuthState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOAuthState", reflect.TypeOf((*MockOAuth)(nil).SetOAuthState), ctx, state)
+}
+
+// UpdateLinkedAccount mocks base method.
+func (m *MockOAuth) UpdateLinkedAccount(ctx context.Context, account core.LinkedAccount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLinkedAccount", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLinkedAccount indicates an expected call of UpdateLinkedAccount.
+func (mr *MockOAuthMockRecorder) UpdateLinkedAccount(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLinkedAccount", reflect.TypeOf((*MockOAuth)(nil).UpdateLinkedAccount), ctx, account)
+}
@@ -0,0 +1,87 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/psql/revocation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/psql/revocation.go -destination=internal/repository/psql/mocks/mock_revocation_repo.go
+//
+
+// Package mock_psql is a generated GoMock package.
+package mock_psql
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRevocation is a mock of Revocation interface.
+type MockRevocation struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevocationMockRecorder
+}
+
+// MockRevocationMockRecorder is the mock recorder for MockRevocation.
+type MockRevocationMockRecorder struct {
+	mock *MockRevocation
+}
+
+// NewMockRevocation creates a new mock instance.
+func NewMockRevocation(ctrl *gomock.Controller) *MockRevocation {
+	mock := &MockRevocation{ctrl: ctrl}
+	mock.recorder = &MockRevocationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevocation) EXPECT() *MockRevocationMockRecorder {
+	return m.recorder
+}
+
+// DeleteExpiredRevocations mocks base method.
+func (m *MockRevocation) DeleteExpiredRevocations(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredRevocations", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredRevocations indicates an expected call of DeleteExpiredRevocations.
+func (mr *MockRevocationMockRecorder) DeleteExpiredRevocations(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredRevocations", reflect.TypeOf((*MockRevocation)(nil).DeleteExpiredRevocations), ctx, now)
+}
+
+// GetRevokedSessions mocks base method.
+func (m *MockRevocation) GetRevokedSessions(ctx context.Context, now time.Time) ([]core.RevokedSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevokedSessions", ctx, now)
+	ret0, _ := ret[0].([]core.RevokedSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevokedSessions indicates an expected call of GetRevokedSessions.
+func (mr *MockRevocationMockRecorder) GetRevokedSessions(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokedSessions", reflect.TypeOf((*MockRevocation)(nil).GetRevokedSessions), ctx, now)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockRevocation) RevokeSessions(ctx context.Context, sessionIds []uuid.UUID, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, sessionIds, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockRevocationMockRecorder) RevokeSessions(ctx, sessionIds, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockRevocation)(nil).RevokeSessions), ctx, sessionIds, expiresAt)
+}
@@ -0,0 +1,161 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/psql/users.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/psql/users.go -destination=internal/repository/psql/mocks/mock_users_repo.go
+//
+
+// Package mock_psql is a generated GoMock package.
+package mock_psql
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUsers is a mock of Users interface.
+type MockUsers struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersMockRecorder
+}
+
+// MockUsersMockRecorder is the mock recorder for MockUsers.
+type MockUsersMockRecorder struct {
+	mock *MockUsers
+}
+
+// NewMockUsers creates a new mock instance.
+func NewMockUsers(ctrl *gomock.Controller) *MockUsers {
+	mock := &MockUsers{ctrl: ctrl}
+	mock.recorder = &MockUsersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
+	return m.recorder
+}
+
+// CancelDeletion mocks base method.
+func (m *MockUsers) CancelDeletion(ctx context.Context, code core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelDeletion", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelDeletion indicates an expected call of CancelDeletion.
+func (mr *MockUsersMockRecorder) CancelDeletion(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelDeletion", reflect.TypeOf((*MockUsers)(nil).CancelDeletion), ctx, code)
+}
+
+// ChangeEmail mocks base method.
+func (m *MockUsers) ChangeEmail(ctx context.Context, code core.CodeCredentials) (core.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeEmail", ctx, code)
+	ret0, _ := ret[0].(core.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeEmail indicates an expected call of ChangeEmail.
+func (mr *MockUsersMockRecorder) ChangeEmail(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeEmail", reflect.TypeOf((*MockUsers)(nil).ChangeEmail), ctx, code)
+}
+
+// ChangePassword mocks base method.
+func (m *MockUsers) ChangePassword(ctx context.Context, userId, sessionId uuid.UUID, passwordHash string) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userId, sessionId, passwordHash)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUsersMockRecorder) ChangePassword(ctx, userId, sessionId, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUsers)(nil).ChangePassword), ctx, userId, sessionId, passwordHash)
+}
+
+// DeleteScheduledUsers mocks base method.
+func (m *MockUsers) DeleteScheduledUsers(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteScheduledUsers", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteScheduledUsers indicates an expected call of DeleteScheduledUsers.
+func (mr *MockUsersMockRecorder) DeleteScheduledUsers(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteScheduledUsers", reflect.TypeOf((*MockUsers)(nil).DeleteScheduledUsers), ctx, now)
+}
+
+// GetUserCodes mocks base method.
+func (m *MockUsers) GetUserCodes(ctx context.Context, email string) ([]core.CodeCredentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserCodes", ctx, email)
+	ret0, _ := ret[0].([]core.CodeCredentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserCodes indicates an expected call of GetUserCodes.
+func (mr *MockUsersMockRecorder) GetUserCodes(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserCodes", reflect.TypeOf((*MockUsers)(nil).GetUserCodes), ctx, email)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockUsers) ScheduleDeletion(ctx context.Context, userId uuid.UUID, code core.CodeCredentials) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", ctx, userId, code)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockUsersMockRecorder) ScheduleDeletion(ctx, userId, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockUsers)(nil).ScheduleDeletion), ctx, userId, code)
+}
+
+// SetEmailChangeCode mocks base method.
+func (m *MockUsers) SetEmailChangeCode(ctx context.Context, code core.CodeCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEmailChangeCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEmailChangeCode indicates an expected call of SetEmailChangeCode.
+func (mr *MockUsersMockRecorder) SetEmailChangeCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEmailChangeCode", reflect.TypeOf((*MockUsers)(nil).SetEmailChangeCode), ctx, code)
+}
+
+// UpdateUsername mocks base method.
+func (m *MockUsers) UpdateUsername(ctx context.Context, userId uuid.UUID, username string, now, changedBefore time.Time) (core.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsername", ctx, userId, username, now, changedBefore)
+	ret0, _ := ret[0].(core.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUsername indicates an expected call of UpdateUsername.
+func (mr *MockUsersMockRecorder) UpdateUsername(ctx, userId, username, now, changedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockUsers)(nil).UpdateUsername), ctx, userId, username, now, changedBefore)
+}
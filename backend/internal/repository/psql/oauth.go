@@ -0,0 +1,154 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type OAuth interface {
+	SetOAuthState(ctx context.Context, state core.OAuthState) error
+	ConsumeOAuthState(ctx context.Context, state string, now time.Time) (core.OAuthState, error)
+	GetLinkedAccount(ctx context.Context, provider, providerUserId string) (core.LinkedAccount, error)
+	GetUserLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) (core.LinkedAccount, error)
+	GetUserLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error)
+	CreateUserWithAccount(ctx context.Context, email string, account core.LinkedAccount) (uuid.UUID, error)
+	LinkAccount(ctx context.Context, account core.LinkedAccount) error
+	UpdateLinkedAccount(ctx context.Context, account core.LinkedAccount) error
+	DeleteLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) error
+	DeleteExpiredOAuthStates(ctx context.Context, now time.Time) (int64, error)
+}
+
+type OAuthRepo struct {
+	db *postgres.Postgres
+}
+
+func NewOAuthPostgres(db *postgres.Postgres) *OAuthRepo {
+	return &OAuthRepo{db: db}
+}
+
+// Write state. State with uuid.Nil user id is stored with NULL user_id.
+func (r *OAuthRepo) SetOAuthState(ctx context.Context, state core.OAuthState) error {
+	query := fmt.Sprintf(`INSERT INTO %s (state, provider, code_verifier, user_id, expires_at)
+		values ($1, $2, $3, NULLIF($4, uuid_nil()), $5)`, oauthStatesTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, state.State, state.Provider, state.CodeVerifier, state.UserId, state.ExpiresAt)
+
+	return err
+}
+
+// Delete state and return it. Return ErrNotFound if there is no such state
+// or it's expired, so one state can't be used twice.
+func (r *OAuthRepo) ConsumeOAuthState(ctx context.Context, state string, now time.Time) (core.OAuthState, error) {
+	var st core.OAuthState
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE state=$1 AND expires_at > $2
+		RETURNING state, provider, code_verifier, COALESCE(user_id, uuid_nil()) AS user_id, expires_at`, oauthStatesTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &st, query, state, now)
+
+	return st, pgError(err)
+}
+
+func (r *OAuthRepo) GetLinkedAccount(ctx context.Context, provider, providerUserId string) (core.LinkedAccount, error) {
+	var account core.LinkedAccount
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE provider=$1 AND provider_user_id=$2", linkedAccountsTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &account, query, provider, providerUserId)
+
+	return account, pgError(err)
+}
+
+func (r *OAuthRepo) GetUserLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) (core.LinkedAccount, error) {
+	var account core.LinkedAccount
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE user_id=$1 AND provider=$2", linkedAccountsTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &account, query, userId, provider)
+
+	return account, pgError(err)
+}
+
+// Return accounts linked to user, oldest link first.
+func (r *OAuthRepo) GetUserLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error) {
+	var accounts []core.LinkedAccount
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE user_id=$1 ORDER BY created_at", linkedAccountsTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &accounts, query, userId)
+
+	return accounts, err
+}
+
+// Create user without password and link account to it in one transaction.
+func (r *OAuthRepo) CreateUserWithAccount(ctx context.Context, email string, account core.LinkedAccount) (uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	query := fmt.Sprintf("INSERT INTO %s (email, password_hash) values ($1, '') RETURNING id", userTable)
+	if err := tx.QueryRow(ctx, query, email).Scan(&id); err != nil {
+		return uuid.UUID{}, pgError(err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (user_id, provider, provider_user_id, username, access_token, refresh_token, token_expires_at)
+		values ($1, $2, $3, $4, $5, $6, $7)`, linkedAccountsTable)
+	_, err = tx.Exec(ctx, query, id, account.Provider, account.ProviderUserId, account.Username,
+		account.AccessToken, account.RefreshToken, account.TokenExpiresAt)
+	if err != nil {
+		return uuid.UUID{}, pgError(err)
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// Link account to existing user.
+func (r *OAuthRepo) LinkAccount(ctx context.Context, account core.LinkedAccount) error {
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, provider, provider_user_id, username, access_token, refresh_token, token_expires_at)
+		values ($1, $2, $3, $4, $5, $6, $7)`, linkedAccountsTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, account.UserId, account.Provider, account.ProviderUserId, account.Username,
+		account.AccessToken, account.RefreshToken, account.TokenExpiresAt)
+
+	return pgError(err)
+}
+
+// Update provider username and tokens of linked account.
+// Return ErrNotFound if account isn't linked.
+func (r *OAuthRepo) UpdateLinkedAccount(ctx context.Context, account core.LinkedAccount) error {
+	query := fmt.Sprintf(`UPDATE %s SET username=$3, access_token=$4, refresh_token=$5, token_expires_at=$6,
+		updated_at=(NOW() AT TIME ZONE 'utc') WHERE provider=$1 AND provider_user_id=$2`, linkedAccountsTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, account.Provider, account.ProviderUserId, account.Username,
+		account.AccessToken, account.RefreshToken, account.TokenExpiresAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Unlink provider from user. Return ErrNotFound if provider isn't linked.
+func (r *OAuthRepo) DeleteLinkedAccount(ctx context.Context, userId uuid.UUID, provider string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id=$1 AND provider=$2", linkedAccountsTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, userId, provider)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *OAuthRepo) DeleteExpiredOAuthStates(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", oauthStatesTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
@@ -5,17 +5,36 @@ import (
 )
 
 const (
-	userTable        = "users"
-	userSessionTable = "users_sessions"
-	codesTable       = "codes"
+	userTable          = "users"
+	userSessionTable   = "users_sessions"
+	codesTable         = "codes"
+	usedMFATokensTable = "used_mfa_tokens"
+
+	revokedSessionsTable = "revoked_sessions"
+	lockoutsTable        = "login_lockouts"
+
+	linkedAccountsTable = "linked_accounts"
+	oauthStatesTable    = "oauth_states"
+
+	sessionColumns = "user_id, refresh_token, family_id, parent_token, rotated, expires_at, created_at, last_used_at, user_agent, ip"
 )
 
 type Repository struct {
 	Auth
+	Revocation
+	Lockout
+	OAuth
+	Users
+	Admin
 }
 
 func NewPsqlRepository(db *postgres.Postgres) *Repository {
 	return &Repository{
-		Auth: NewAuthPostgres(db),
+		Auth:       NewAuthPostgres(db),
+		Revocation: NewRevocationPostgres(db),
+		Lockout:    NewLockoutPostgres(db),
+		OAuth:      NewOAuthPostgres(db),
+		Users:      NewUsersPostgres(db),
+		Admin:      NewAdminPostgres(db),
 	}
 }
@@ -0,0 +1,50 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type Revocation interface {
+	RevokeSessions(ctx context.Context, sessionIds []uuid.UUID, expiresAt time.Time) error
+	GetRevokedSessions(ctx context.Context, now time.Time) ([]core.RevokedSession, error)
+	DeleteExpiredRevocations(ctx context.Context, now time.Time) (int64, error)
+}
+
+type RevocationRepo struct {
+	db *postgres.Postgres
+}
+
+func NewRevocationPostgres(db *postgres.Postgres) *RevocationRepo {
+	return &RevocationRepo{db: db}
+}
+
+// Write revoked session ids. Already revoked session gets later expiry of two.
+func (r *RevocationRepo) RevokeSessions(ctx context.Context, sessionIds []uuid.UUID, expiresAt time.Time) error {
+	query := fmt.Sprintf(`INSERT INTO %s (session_id, expires_at) SELECT unnest($1::uuid[]), $2
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = GREATEST(%[1]s.expires_at, EXCLUDED.expires_at)`, revokedSessionsTable)
+	_, err := conn(ctx, r.db).Exec(ctx, query, sessionIds, expiresAt)
+
+	return err
+}
+
+func (r *RevocationRepo) GetRevokedSessions(ctx context.Context, now time.Time) ([]core.RevokedSession, error) {
+	var revoked []core.RevokedSession
+
+	query := fmt.Sprintf("SELECT session_id, expires_at FROM %s WHERE expires_at > $1", revokedSessionsTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &revoked, query, now)
+
+	return revoked, err
+}
+
+func (r *RevocationRepo) DeleteExpiredRevocations(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= $1", revokedSessionsTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
@@ -0,0 +1,40 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is implemented by both pool and transaction, so repo methods
+// run the same queries inside or outside of unit of work.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// Return context carrying tx. Repo methods called with it run inside tx.
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Return tx carried by context, if any.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Return tx of unit of work in progress or pool. Repo method that needs own
+// transaction begins it on result, inside unit of work it becomes a savepoint.
+func conn(ctx context.Context, db *postgres.Postgres) querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db.Pool
+}
@@ -0,0 +1,210 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type Users interface {
+	UpdateUsername(ctx context.Context, userId uuid.UUID, username string, now, changedBefore time.Time) (core.User, error)
+	ChangePassword(ctx context.Context, userId, sessionId uuid.UUID, passwordHash string) ([]uuid.UUID, error)
+	SetEmailChangeCode(ctx context.Context, code core.CodeCredentials) error
+	ChangeEmail(ctx context.Context, code core.CodeCredentials) (core.User, error)
+	ScheduleDeletion(ctx context.Context, userId uuid.UUID, code core.CodeCredentials) ([]uuid.UUID, error)
+	CancelDeletion(ctx context.Context, code core.CodeCredentials) error
+	DeleteScheduledUsers(ctx context.Context, now time.Time) (int64, error)
+	GetUserCodes(ctx context.Context, email string) ([]core.CodeCredentials, error)
+}
+
+type UsersRepo struct {
+	db *postgres.Postgres
+}
+
+func NewUsersPostgres(db *postgres.Postgres) *UsersRepo {
+	return &UsersRepo{db: db}
+}
+
+// Set username of user if it was last changed before changedBefore.
+// Return ErrNotFound if it was changed later and ErrDuplicate
+// if other user has same username ignoring case.
+func (r *UsersRepo) UpdateUsername(ctx context.Context, userId uuid.UUID, username string, now, changedBefore time.Time) (core.User, error) {
+	var user core.User
+
+	query := fmt.Sprintf(`UPDATE %s SET username=$2, username_changed_at=$3
+		WHERE id=$1 AND username_changed_at <= $4 RETURNING *`, userTable)
+	err := r.db.Scany.Get(ctx, conn(ctx, r.db), &user, query, userId, username, now, changedBefore)
+
+	return user, pgError(err)
+}
+
+// Set new password hash, delete password reset codes and all user sessions
+// except sessionId (token family) in one transaction. Return ids of deleted sessions.
+func (r *UsersRepo) ChangePassword(ctx context.Context, userId, sessionId uuid.UUID, passwordHash string) ([]uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	query := fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE id=$2 RETURNING email", userTable)
+	if err := tx.QueryRow(ctx, query, passwordHash, userId).Scan(&email); err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	if _, err := tx.Exec(ctx, query, email, core.CodeTypePassReset); err != nil {
+		return nil, err
+	}
+
+	var sessionIds []uuid.UUID
+	query = fmt.Sprintf(`WITH d AS (DELETE FROM %s WHERE user_id = $1 AND family_id <> $2 RETURNING family_id)
+		SELECT DISTINCT family_id FROM d`, userSessionTable)
+	if err := r.db.Scany.Select(ctx, tx, &sessionIds, query, userId, sessionId); err != nil {
+		return nil, err
+	}
+
+	return sessionIds, tx.Commit(ctx)
+}
+
+// Replace email change codes of user with new one in one transaction,
+// so only last requested new email can be confirmed.
+func (r *UsersRepo) SetEmailChangeCode(ctx context.Context, code core.CodeCredentials) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	if _, err := tx.Exec(ctx, query, code.Email, core.CodeTypeEmailChange); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (user_email, code, code_type, expires_at, new_email) values ($1, $2, $3, $4, $5)", codesTable)
+	if _, err := tx.Exec(ctx, query, code.Email, code.Code, core.CodeTypeEmailChange, code.ExpiresAt, code.NewEmail); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Consume email change code and set its new email as verified user email in one transaction.
+// Codes sent to old email are deleted, remaining codes follow user by ON UPDATE CASCADE.
+// Return ErrNotFound if code already consumed and ErrDuplicate if new email is taken.
+func (r *UsersRepo) ChangeEmail(ctx context.Context, code core.CodeCredentials) (core.User, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return core.User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var newEmail string
+	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3 RETURNING new_email", codesTable)
+	if err := tx.QueryRow(ctx, query, code.Code, code.Email, core.CodeTypeEmailChange).Scan(&newEmail); err != nil {
+		return core.User{}, pgError(err)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type IN ($2, $3)", codesTable)
+	if _, err := tx.Exec(ctx, query, code.Email, core.CodeTypePassReset, core.CodeTypeEmailVerify); err != nil {
+		return core.User{}, err
+	}
+
+	var user core.User
+	query = fmt.Sprintf("UPDATE %s SET email=$1, email_verified=TRUE WHERE email=$2 RETURNING *", userTable)
+	if err := r.db.Scany.Get(ctx, tx, &user, query, newEmail, code.Email); err != nil {
+		return core.User{}, pgError(err)
+	}
+
+	return user, tx.Commit(ctx)
+}
+
+// Schedule deletion of user at code expiry, replace deletion cancel codes of user with new one
+// and delete all user sessions in one transaction. Return ids of deleted sessions.
+// Return ErrNotFound if deletion is already scheduled.
+func (r *UsersRepo) ScheduleDeletion(ctx context.Context, userId uuid.UUID, code core.CodeCredentials) ([]uuid.UUID, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("UPDATE %s SET delete_at=$1 WHERE id=$2 AND delete_at='epoch'", userTable)
+	tag, err := tx.Exec(ctx, query, code.ExpiresAt, userId)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_email=$1 AND code_type=$2", codesTable)
+	if _, err := tx.Exec(ctx, query, code.Email, core.CodeTypeDeletionCancel); err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (user_email, code, code_type, expires_at) values ($1, $2, $3, $4)", codesTable)
+	if _, err := tx.Exec(ctx, query, code.Email, code.Code, core.CodeTypeDeletionCancel, code.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	var sessionIds []uuid.UUID
+	query = fmt.Sprintf("WITH d AS (DELETE FROM %s WHERE user_id=$1 RETURNING family_id) SELECT DISTINCT family_id FROM d", userSessionTable)
+	if err := r.db.Scany.Select(ctx, tx, &sessionIds, query, userId); err != nil {
+		return nil, err
+	}
+
+	return sessionIds, tx.Commit(ctx)
+}
+
+// Consume deletion cancel code and unschedule deletion of user in one transaction.
+// Return ErrNotFound if code already consumed.
+func (r *UsersRepo) CancelDeletion(ctx context.Context, code core.CodeCredentials) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE code=$1 AND user_email=$2 AND code_type=$3", codesTable)
+	tag, err := tx.Exec(ctx, query, code.Code, code.Email, core.CodeTypeDeletionCancel)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET delete_at='epoch' WHERE email=$1", userTable)
+	if _, err := tx.Exec(ctx, query, code.Email); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete users whose deletion grace period is over. Sessions, codes, linked accounts
+// and other user data are deleted by ON DELETE CASCADE.
+func (r *UsersRepo) DeleteScheduledUsers(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE delete_at > 'epoch' AND delete_at <= $1", userTable)
+	tag, err := conn(ctx, r.db).Exec(ctx, query, now)
+
+	return tag.RowsAffected(), err
+}
+
+// Return every code of email ordered by type and expiry.
+// Code without expiry has epoch one.
+func (r *UsersRepo) GetUserCodes(ctx context.Context, email string) ([]core.CodeCredentials, error) {
+	var codes []core.CodeCredentials
+
+	query := fmt.Sprintf(`SELECT user_email AS email, code, code_type, COALESCE(expires_at, 'epoch') AS expires_at, new_email
+		FROM %s WHERE user_email=$1 ORDER BY code_type, expires_at, code`, codesTable)
+	err := r.db.Scany.Select(ctx, conn(ctx, r.db), &codes, query, email)
+
+	return codes, err
+}
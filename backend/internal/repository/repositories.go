@@ -1,16 +1,52 @@
 package repositories
 
 import (
+	"fmt"
+
+	"github.com/Cheasezz/anSpace/backend/internal/repository/memory"
 	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
 	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
 )
 
+const (
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+)
+
 type Repositories struct {
-	Psql *psql.Repository
+	// Postgres or in memory storage, picked by config.
+	Storage *psql.Repository
+	// Postgres or in memory lockout storage, picked by config.
+	Lockout psql.Lockout
+	Tx      TxManager
 }
 
-func NewRepositories(pg *postgres.Postgres) *Repositories {
-	return &Repositories{
-		Psql: psql.NewPsqlRepository(pg),
+// Build repositories of storage driver. pg is used only by postgres driver.
+// With memory driver postgres lockout storage means lockouts of memory storage,
+// so every data is kept in process.
+func NewRepositories(driver string, pg *postgres.Postgres, lockoutStorage string) (*Repositories, error) {
+	repos := &Repositories{}
+
+	switch driver {
+	case StorageMemory:
+		store := memory.NewStore()
+		repos.Storage = memory.NewRepository(store)
+		repos.Tx = memory.NewTxManager(store)
+	case StoragePostgres:
+		repos.Storage = psql.NewPsqlRepository(pg)
+		repos.Tx = NewTxManager(pg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %q", driver)
 	}
+
+	switch lockoutStorage {
+	case StorageMemory:
+		repos.Lockout = memory.NewLockoutRepo()
+	case StoragePostgres:
+		repos.Lockout = repos.Storage.Lockout
+	default:
+		return nil, fmt.Errorf("unknown lockout storage: %q", lockoutStorage)
+	}
+
+	return repos, nil
 }
@@ -0,0 +1,536 @@
+// Package repotest is conformance suite of repository backends. Every backend
+// must pass it, so services behave the same with any storage driver.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Setup returns repositories of backend under test with no data.
+type Setup func(t *testing.T) *repositories.Repositories
+
+// Run every conformance test against repositories returned by setup.
+func Run(t *testing.T, setup Setup) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repos *repositories.Repositories)
+	}{
+		{"Users", testUsers},
+		{"Username", testUsername},
+		{"Codes", testCodes},
+		{"EmailChange", testEmailChange},
+		{"Sessions", testSessions},
+		{"SessionCap", testSessionCap},
+		{"DeleteScheduledUsers", testDeleteScheduledUsers},
+		{"OAuth", testOAuth},
+		{"Revocation", testRevocation},
+		{"MFAReplay", testMFAReplay},
+		{"Admin", testAdmin},
+		{"Tx", testTx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, setup(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, repos *repositories.Repositories, email string) uuid.UUID {
+	id, err := repos.Storage.CreateUser(context.Background(), core.AuthCredentials{Email: email, Password: "hash"})
+	require.NoError(t, err)
+	return id
+}
+
+func setSession(t *testing.T, repos *repositories.Repositories, session core.Session) {
+	_, err := repos.Storage.SetSession(context.Background(), session, 0)
+	require.NoError(t, err)
+}
+
+func newSession(userId uuid.UUID, token string) core.Session {
+	return core.Session{
+		UserId:       userId,
+		RefreshToken: token,
+		FamilyId:     uuid.New(),
+		ExpiresAt:    time.Now().Add(time.Hour),
+		UserAgent:    "agent",
+		IP:           "127.0.0.1",
+	}
+}
+
+func testUsers(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	id := createUser(t, repos, "User@gmail.com")
+
+	_, err := repos.Storage.CreateUser(ctx, core.AuthCredentials{Email: "user@GMAIL.com", Password: "hash"})
+	r.ErrorIs(err, psql.ErrDuplicate)
+
+	user, err := repos.Storage.GetUserByEmail(ctx, "USER@gmail.com")
+	r.NoError(err)
+	r.Equal(id, user.Id)
+	r.Equal("User@gmail.com", user.Email)
+	r.NotEmpty(user.Username)
+	r.Equal(core.RoleUser, user.Role)
+	r.False(user.EmailVerified)
+	r.False(user.DeletionScheduled())
+	r.False(user.Banned())
+
+	_, err = repos.Storage.GetUserById(ctx, uuid.New())
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetUserByEmail(ctx, "nobody@gmail.com")
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	r.NoError(repos.Storage.UpdatePasswordHash(ctx, id, "new hash"))
+	user, err = repos.Storage.GetUserById(ctx, id)
+	r.NoError(err)
+	r.Equal("new hash", user.PasswordHash)
+}
+
+func testUsername(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	id := createUser(t, repos, "user@gmail.com")
+	otherId := createUser(t, repos, "other@gmail.com")
+
+	user, err := repos.Storage.UpdateUsername(ctx, id, "Naruto", now, now)
+	r.NoError(err)
+	r.Equal("Naruto", user.Username)
+
+	_, err = repos.Storage.UpdateUsername(ctx, otherId, "naruto", now, now)
+	r.ErrorIs(err, psql.ErrDuplicate)
+
+	// Changed after changedBefore
+	_, err = repos.Storage.UpdateUsername(ctx, id, "Sasuke", now, now.Add(-time.Hour))
+	r.ErrorIs(err, psql.ErrNotFound)
+}
+
+func testCodes(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	id := createUser(t, repos, "user@gmail.com")
+	code := core.CodeCredentials{
+		Email:     "user@gmail.com",
+		Code:      "verify",
+		CodeType:  core.CodeTypeEmailVerify,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	r.Error(repos.Storage.SetCode(ctx, core.CodeCredentials{
+		Email: "nobody@gmail.com", Code: "orphan", CodeType: core.CodeTypeEmailVerify, ExpiresAt: code.ExpiresAt,
+	}))
+	r.NoError(repos.Storage.SetCode(ctx, code))
+
+	got, err := repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "USER@gmail.com", Code: "verify", CodeType: core.CodeTypeEmailVerify})
+	r.NoError(err)
+	r.Equal(code.Code, got.Code)
+	r.WithinDuration(code.ExpiresAt, got.ExpiresAt, time.Millisecond)
+
+	_, err = repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "user@gmail.com", Code: "verify", CodeType: core.CodeTypePassReset})
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	r.NoError(repos.Storage.VerifyEmail(ctx, code))
+	r.ErrorIs(repos.Storage.VerifyEmail(ctx, code), psql.ErrNotFound)
+	user, err := repos.Storage.GetUserById(ctx, id)
+	r.NoError(err)
+	r.True(user.EmailVerified)
+
+	// Reset deletes every session of user
+	setSession(t, repos, newSession(id, "token"))
+	reset := core.CodeCredentials{Email: "user@gmail.com", Code: "reset", CodeType: core.CodeTypePassReset, ExpiresAt: code.ExpiresAt}
+	r.NoError(repos.Storage.SetCode(ctx, reset))
+	sessionIds, err := repos.Storage.ResetPassword(ctx, reset, "new hash")
+	r.NoError(err)
+	r.Len(sessionIds, 1)
+	_, err = repos.Storage.ResetPassword(ctx, reset, "new hash")
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	// Recovery codes have no expiry and aren't swept
+	r.NoError(repos.Storage.SetTOTPSecret(ctx, id, "secret"))
+	r.NoError(repos.Storage.EnableTOTP(ctx, id, []core.CodeCredentials{{Code: "recovery"}}))
+	r.ErrorIs(repos.Storage.SetTOTPSecret(ctx, id, "other"), psql.ErrNotFound)
+	r.NoError(repos.Storage.SetCode(ctx, core.CodeCredentials{
+		Email: "user@gmail.com", Code: "expired", CodeType: core.CodeTypePassReset, ExpiresAt: time.Now().Add(-time.Hour),
+	}))
+	deleted, err := repos.Storage.DeleteExpiredCodes(ctx, time.Now())
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+
+	recovery := core.CodeCredentials{Email: "user@gmail.com", Code: "recovery", CodeType: core.CodeTypeMFARecovery}
+	r.NoError(repos.Storage.ConsumeCode(ctx, recovery))
+	r.ErrorIs(repos.Storage.ConsumeCode(ctx, recovery), psql.ErrNotFound)
+}
+
+func testEmailChange(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	id := createUser(t, repos, "user@gmail.com")
+	createUser(t, repos, "taken@gmail.com")
+
+	taken := core.CodeCredentials{Email: "user@gmail.com", Code: "taken", ExpiresAt: expiresAt, NewEmail: "Taken@gmail.com"}
+	r.NoError(repos.Storage.SetEmailChangeCode(ctx, taken))
+	_, err := repos.Storage.ChangeEmail(ctx, taken)
+	r.ErrorIs(err, psql.ErrDuplicate)
+
+	// Only last requested code is valid
+	change := core.CodeCredentials{Email: "user@gmail.com", Code: "change", ExpiresAt: expiresAt, NewEmail: "new@gmail.com"}
+	r.NoError(repos.Storage.SetEmailChangeCode(ctx, change))
+	_, err = repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "user@gmail.com", Code: "taken", CodeType: core.CodeTypeEmailChange})
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	// Codes follow user to new email, except ones sent to old email
+	r.NoError(repos.Storage.SetCode(ctx, core.CodeCredentials{
+		Email: "user@gmail.com", Code: "reset", CodeType: core.CodeTypePassReset, ExpiresAt: expiresAt,
+	}))
+	r.NoError(repos.Storage.SetTOTPSecret(ctx, id, "secret"))
+	r.NoError(repos.Storage.EnableTOTP(ctx, id, []core.CodeCredentials{{Code: "recovery"}}))
+
+	// Every code of user ordered by type, code without expiry has epoch one
+	codes, err := repos.Storage.GetUserCodes(ctx, "USER@gmail.com")
+	r.NoError(err)
+	r.Len(codes, 3)
+	r.Equal(core.CodeTypeEmailChange, codes[0].CodeType)
+	r.Equal("new@gmail.com", codes[0].NewEmail)
+	r.WithinDuration(expiresAt, codes[0].ExpiresAt, time.Millisecond)
+	r.Equal(core.CodeTypeMFARecovery, codes[1].CodeType)
+	r.True(codes[1].ExpiresAt.Equal(time.Unix(0, 0)))
+	r.Equal(core.CodeTypePassReset, codes[2].CodeType)
+
+	user, err := repos.Storage.ChangeEmail(ctx, change)
+	r.NoError(err)
+	r.Equal(id, user.Id)
+	r.Equal("new@gmail.com", user.Email)
+	r.True(user.EmailVerified)
+
+	_, err = repos.Storage.ChangeEmail(ctx, change)
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "new@gmail.com", Code: "reset", CodeType: core.CodeTypePassReset})
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "new@gmail.com", Code: "recovery", CodeType: core.CodeTypeMFARecovery})
+	r.NoError(err)
+}
+
+func testSessions(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	id := createUser(t, repos, "user@gmail.com")
+	_, err := repos.Storage.SetSession(ctx, newSession(uuid.New(), "orphan"), 0)
+	r.Error(err)
+
+	first := newSession(id, "first")
+	second := newSession(id, "second")
+	setSession(t, repos, first)
+	setSession(t, repos, second)
+
+	// Sessions are ordered by last use
+	time.Sleep(2 * time.Millisecond)
+	rotated := newSession(id, "rotated")
+	rotated.FamilyId = first.FamilyId
+	rotated.ParentToken = first.RefreshToken
+	rotated.CreatedAt = time.Now().Add(-time.Minute)
+	r.NoError(repos.Storage.RotateSession(ctx, first, rotated))
+	r.ErrorIs(repos.Storage.RotateSession(ctx, first, newSession(id, "again")), psql.ErrNotFound)
+
+	got, err := repos.Storage.GetUserSessionByRefreshToken(ctx, "first")
+	r.NoError(err)
+	r.True(got.Rotated)
+	_, err = repos.Storage.GetUserSessionByRefreshToken(ctx, "unknown")
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	// One session per family, recently used first
+	sessions, err := repos.Storage.GetUserSessions(ctx, id)
+	r.NoError(err)
+	r.Len(sessions, 2)
+	r.Equal("rotated", sessions[0].RefreshToken)
+	r.Equal("second", sessions[1].RefreshToken)
+	r.WithinDuration(rotated.CreatedAt, sessions[0].CreatedAt, time.Millisecond)
+
+	sessionIds, err := repos.Storage.DeleteSessionsExcept(ctx, id, second.FamilyId)
+	r.NoError(err)
+	r.Equal([]uuid.UUID{first.FamilyId}, sessionIds)
+
+	r.ErrorIs(repos.Storage.DeleteSessionById(ctx, uuid.New(), second.FamilyId), psql.ErrNotFound)
+	r.NoError(repos.Storage.DeleteSessionById(ctx, id, second.FamilyId))
+
+	expired := newSession(id, "expired")
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	setSession(t, repos, expired)
+	deleted, err := repos.Storage.DeleteExpiredSessions(ctx, time.Now())
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+}
+
+func testSessionCap(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	id := createUser(t, repos, "user@gmail.com")
+	var evicted [][]uuid.UUID
+	first := newSession(id, "first")
+	for _, session := range []core.Session{first, newSession(id, "second"), newSession(id, "third")} {
+		sessionIds, err := repos.Storage.SetSession(ctx, session, 2)
+		r.NoError(err)
+		evicted = append(evicted, sessionIds)
+		// Sessions are ordered by creation time
+		time.Sleep(2 * time.Millisecond)
+	}
+	r.Empty(evicted[0])
+	r.Empty(evicted[1])
+	r.Equal([]uuid.UUID{first.FamilyId}, evicted[2])
+
+	sessions, err := repos.Storage.GetUserSessions(ctx, id)
+	r.NoError(err)
+	r.Len(sessions, 2)
+	_, err = repos.Storage.GetUserSessionByRefreshToken(ctx, "first")
+	r.ErrorIs(err, psql.ErrNotFound)
+}
+
+func testDeleteScheduledUsers(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	id := createUser(t, repos, "user@gmail.com")
+	keptId := createUser(t, repos, "kept@gmail.com")
+	setSession(t, repos, newSession(id, "token"))
+	r.NoError(repos.Storage.LinkAccount(ctx, core.LinkedAccount{UserId: id, Provider: "shikimori", ProviderUserId: "1"}))
+
+	cancel := core.CodeCredentials{Email: "user@gmail.com", Code: "cancel", ExpiresAt: now.Add(time.Hour)}
+	sessionIds, err := repos.Storage.ScheduleDeletion(ctx, id, cancel)
+	r.NoError(err)
+	r.Len(sessionIds, 1)
+	_, err = repos.Storage.ScheduleDeletion(ctx, id, cancel)
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	r.NoError(repos.Storage.CancelDeletion(ctx, cancel))
+	r.ErrorIs(repos.Storage.CancelDeletion(ctx, cancel), psql.ErrNotFound)
+
+	setSession(t, repos, newSession(id, "token"))
+	_, err = repos.Storage.ScheduleDeletion(ctx, id, core.CodeCredentials{Email: "user@gmail.com", Code: "again", ExpiresAt: now})
+	r.NoError(err)
+	setSession(t, repos, newSession(id, "new token"))
+
+	deleted, err := repos.Storage.DeleteScheduledUsers(ctx, now.Add(time.Second))
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+
+	// User data is deleted with user
+	_, err = repos.Storage.GetUserById(ctx, id)
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetUserSessionByRefreshToken(ctx, "new token")
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetLinkedAccount(ctx, "shikimori", "1")
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetCode(ctx, core.CodeCredentials{Email: "user@gmail.com", Code: "again", CodeType: core.CodeTypeDeletionCancel})
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.GetUserById(ctx, keptId)
+	r.NoError(err)
+
+	// Email is free again
+	createUser(t, repos, "user@gmail.com")
+}
+
+func testOAuth(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	state := core.OAuthState{State: "state", Provider: "shikimori", CodeVerifier: "verifier", ExpiresAt: now.Add(time.Minute)}
+	r.NoError(repos.Storage.SetOAuthState(ctx, state))
+	r.NoError(repos.Storage.SetOAuthState(ctx, core.OAuthState{
+		State: "expired", Provider: "shikimori", CodeVerifier: "verifier", ExpiresAt: now.Add(-time.Minute),
+	}))
+
+	got, err := repos.Storage.ConsumeOAuthState(ctx, "state", now)
+	r.NoError(err)
+	r.Equal(uuid.Nil, got.UserId)
+	r.Equal("verifier", got.CodeVerifier)
+	_, err = repos.Storage.ConsumeOAuthState(ctx, "state", now)
+	r.ErrorIs(err, psql.ErrNotFound)
+	_, err = repos.Storage.ConsumeOAuthState(ctx, "expired", now)
+	r.ErrorIs(err, psql.ErrNotFound)
+	deleted, err := repos.Storage.DeleteExpiredOAuthStates(ctx, now)
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+
+	account := core.LinkedAccount{Provider: "shikimori", ProviderUserId: "1", Username: "naruto"}
+	id, err := repos.Storage.CreateUserWithAccount(ctx, "user@gmail.com", account)
+	r.NoError(err)
+
+	// Provider account belongs to one user only
+	_, err = repos.Storage.CreateUserWithAccount(ctx, "other@gmail.com", account)
+	r.ErrorIs(err, psql.ErrDuplicate)
+	_, err = repos.Storage.GetUserByEmail(ctx, "other@gmail.com")
+	r.ErrorIs(err, psql.ErrNotFound)
+	r.ErrorIs(repos.Storage.LinkAccount(ctx, core.LinkedAccount{UserId: id, Provider: "shikimori", ProviderUserId: "2"}), psql.ErrDuplicate)
+	r.NoError(repos.Storage.LinkAccount(ctx, core.LinkedAccount{UserId: id, Provider: "kinopoisk", ProviderUserId: "1"}))
+
+	account.Username = "sasuke"
+	r.NoError(repos.Storage.UpdateLinkedAccount(ctx, account))
+	got2, err := repos.Storage.GetUserLinkedAccount(ctx, id, "shikimori")
+	r.NoError(err)
+	r.Equal("sasuke", got2.Username)
+	r.ErrorIs(repos.Storage.UpdateLinkedAccount(ctx, core.LinkedAccount{Provider: "litres", ProviderUserId: "1"}), psql.ErrNotFound)
+
+	accounts, err := repos.Storage.GetUserLinkedAccounts(ctx, id)
+	r.NoError(err)
+	r.Len(accounts, 2)
+
+	r.NoError(repos.Storage.DeleteLinkedAccount(ctx, id, "kinopoisk"))
+	r.ErrorIs(repos.Storage.DeleteLinkedAccount(ctx, id, "kinopoisk"), psql.ErrNotFound)
+}
+
+func testRevocation(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	id := uuid.New()
+	r.NoError(repos.Storage.RevokeSessions(ctx, []uuid.UUID{id}, now.Add(time.Hour)))
+	// Earlier expiry doesn't shorten revocation
+	r.NoError(repos.Storage.RevokeSessions(ctx, []uuid.UUID{id, uuid.New()}, now.Add(time.Minute)))
+
+	revoked, err := repos.Storage.GetRevokedSessions(ctx, now)
+	r.NoError(err)
+	r.Len(revoked, 2)
+
+	deleted, err := repos.Storage.DeleteExpiredRevocations(ctx, now.Add(2*time.Minute))
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+
+	revoked, err = repos.Storage.GetRevokedSessions(ctx, now.Add(2*time.Minute))
+	r.NoError(err)
+	r.Len(revoked, 1)
+	r.Equal(id, revoked[0].SessionId)
+}
+
+func testMFAReplay(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	id := createUser(t, repos, "mfa@example.com")
+
+	r.NoError(repos.Storage.SetTOTPStep(ctx, id, 10))
+	// Step at or before last accepted one is rejected
+	r.ErrorIs(repos.Storage.SetTOTPStep(ctx, id, 10), psql.ErrNotFound)
+	r.ErrorIs(repos.Storage.SetTOTPStep(ctx, id, 9), psql.ErrNotFound)
+	r.NoError(repos.Storage.SetTOTPStep(ctx, id, 11))
+
+	user, err := repos.Storage.GetUserById(ctx, id)
+	r.NoError(err)
+	r.EqualValues(11, user.TOTPLastStep)
+
+	r.NoError(repos.Storage.UseMFAToken(ctx, "jti1", now.Add(time.Minute)))
+	r.ErrorIs(repos.Storage.UseMFAToken(ctx, "jti1", now.Add(time.Minute)), psql.ErrNotFound)
+	r.NoError(repos.Storage.UseMFAToken(ctx, "jti2", now.Add(time.Hour)))
+
+	deleted, err := repos.Storage.DeleteExpiredMFATokens(ctx, now.Add(2*time.Minute))
+	r.NoError(err)
+	r.EqualValues(1, deleted)
+	r.ErrorIs(repos.Storage.UseMFAToken(ctx, "jti2", now.Add(time.Hour)), psql.ErrNotFound)
+}
+
+func testAdmin(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	id := createUser(t, repos, "b_user@gmail.com")
+	createUser(t, repos, "A_user@gmail.com")
+	createUser(t, repos, "cuser@gmail.com")
+
+	users, err := repos.Storage.SearchUsers(ctx, core.UserSearch{Query: "_USER", Limit: 10})
+	r.NoError(err)
+	r.Len(users, 2)
+	r.Equal("A_user@gmail.com", users[0].Email)
+	r.Equal("b_user@gmail.com", users[1].Email)
+
+	users, err = repos.Storage.SearchUsers(ctx, core.UserSearch{Limit: 1, Offset: 2})
+	r.NoError(err)
+	r.Len(users, 1)
+	r.Equal("cuser@gmail.com", users[0].Email)
+
+	setSession(t, repos, newSession(id, "token"))
+	sessionIds, err := repos.Storage.BanUser(ctx, id, "spam", now)
+	r.NoError(err)
+	r.Len(sessionIds, 1)
+	_, err = repos.Storage.BanUser(ctx, id, "flood", now.Add(time.Hour))
+	r.NoError(err)
+	_, err = repos.Storage.BanUser(ctx, uuid.New(), "spam", now)
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	// Second ban keeps ban time
+	user, err := repos.Storage.GetUserById(ctx, id)
+	r.NoError(err)
+	r.True(user.Banned())
+	r.Equal("flood", user.BanReason)
+	r.WithinDuration(now, user.BannedAt, time.Millisecond)
+
+	r.NoError(repos.Storage.UnbanUser(ctx, id))
+	r.ErrorIs(repos.Storage.UnbanUser(ctx, uuid.New()), psql.ErrNotFound)
+
+	user, err = repos.Storage.SetRole(ctx, id, core.RoleModerator)
+	r.NoError(err)
+	r.False(user.Banned())
+	r.Equal(core.RoleModerator, user.Role)
+	_, err = repos.Storage.SetRole(ctx, id, "king")
+	r.Error(err)
+	_, err = repos.Storage.SetRole(ctx, uuid.New(), core.RoleAdmin)
+	r.ErrorIs(err, psql.ErrNotFound)
+}
+
+func testTx(t *testing.T, repos *repositories.Repositories) {
+	r := require.New(t)
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	createUser(t, repos, "user@gmail.com")
+
+	err := repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := repos.Storage.CreateUser(ctx, core.AuthCredentials{Email: "rollback@gmail.com", Password: "hash"})
+		r.NoError(err)
+		return errAbort
+	})
+	r.ErrorIs(err, errAbort)
+	_, err = repos.Storage.GetUserByEmail(ctx, "rollback@gmail.com")
+	r.ErrorIs(err, psql.ErrNotFound)
+
+	err = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := repos.Storage.CreateUser(ctx, core.AuthCredentials{Email: "commit@gmail.com", Password: "hash"})
+		return err
+	})
+	r.NoError(err)
+	_, err = repos.Storage.GetUserByEmail(ctx, "commit@gmail.com")
+	r.NoError(err)
+
+	// Write made outside of unit of work while it runs survives its rollback
+	written := make(chan error)
+	err = repos.Tx.WithinTx(ctx, func(txCtx context.Context) error {
+		go func() {
+			_, err := repos.Storage.CreateUser(ctx, core.AuthCredentials{Email: "outside@gmail.com", Password: "hash"})
+			written <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		return errAbort
+	})
+	r.ErrorIs(err, errAbort)
+	r.NoError(<-written)
+	_, err = repos.Storage.GetUserByEmail(ctx, "outside@gmail.com")
+	r.NoError(err)
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/Cheasezz/anSpace/backend/pkg/postgres"
+)
+
+// TxManager runs unit of work: psql repo calls made with context passed to fn
+// share one transaction. It is committed if fn returns nil and rolled back
+// if fn returns error or panics.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type PgTxManager struct {
+	db *postgres.Postgres
+}
+
+func NewTxManager(db *postgres.Postgres) *PgTxManager {
+	return &PgTxManager{db: db}
+}
+
+// Run fn inside transaction. Nested call joins transaction of outer one,
+// so only outermost unit of work commits.
+func (m *PgTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := psql.TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback(ctx)
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(psql.ContextWithTx(ctx, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
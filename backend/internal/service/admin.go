@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type Admin interface {
+	SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error)
+	BanUser(ctx context.Context, actor core.Actor, userId uuid.UUID, reason string) error
+	UnbanUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error
+	LogoutUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error
+	SetRole(ctx context.Context, actor core.Actor, userId uuid.UUID, role string) (core.User, error)
+	GetLockouts(ctx context.Context) ([]core.Lockout, error)
+	ClearLockout(ctx context.Context, key string) error
+}
+
+const defaultSearchLimit = 20
+
+var (
+	ErrUserNotFound     = core.NewError(core.ErrNotFound, "user_not_found", "user not found")
+	ErrInsufficientRole = core.NewError(core.ErrForbidden, "insufficient_role", "role is too low for this action")
+	ErrSelfModeration   = core.NewError(core.ErrForbidden, "self_moderation", "moderation actions can't target yourself")
+)
+
+type AdminService struct {
+	repo       psql.Admin
+	authRepo   psql.Auth
+	revocation Revocation
+	limiter    LoginLimiter
+}
+
+func newAdminService(r psql.Admin, ar psql.Auth, rv Revocation, ll LoginLimiter) *AdminService {
+	return &AdminService{
+		repo:       r,
+		authRepo:   ar,
+		revocation: rv,
+		limiter:    ll,
+	}
+}
+
+// Return page of users whose email or username contains query.
+func (s *AdminService) SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error) {
+	if search.Limit <= 0 {
+		search.Limit = defaultSearchLimit
+	}
+	return s.repo.SearchUsers(ctx, search)
+}
+
+// Ban user and delete all user sessions, their access tokens are revoked.
+// Banned user can't sign in or refresh tokens until unbanned.
+func (s *AdminService) BanUser(ctx context.Context, actor core.Actor, userId uuid.UUID, reason string) error {
+	if _, err := s.moderatedUser(ctx, actor, userId); err != nil {
+		return err
+	}
+
+	sessionIds, err := s.repo.BanUser(ctx, userId, reason, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return s.revocation.Revoke(ctx, sessionIds...)
+}
+
+// Lift ban of user.
+func (s *AdminService) UnbanUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error {
+	if _, err := s.moderatedUser(ctx, actor, userId); err != nil {
+		return err
+	}
+
+	if err := s.repo.UnbanUser(ctx, userId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete all user sessions and revoke their access tokens. User may sign in again.
+func (s *AdminService) LogoutUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error {
+	if _, err := s.moderatedUser(ctx, actor, userId); err != nil {
+		return err
+	}
+
+	sessionIds, err := s.repo.DeleteUserSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return s.revocation.Revoke(ctx, sessionIds...)
+}
+
+// Set role of user. Actor can't grant role above own one.
+// New role is put into access token claims on next token refresh.
+func (s *AdminService) SetRole(ctx context.Context, actor core.Actor, userId uuid.UUID, role string) (core.User, error) {
+	if core.RoleRank(role) > core.RoleRank(actor.Role) {
+		return core.User{}, ErrInsufficientRole
+	}
+	if _, err := s.moderatedUser(ctx, actor, userId); err != nil {
+		return core.User{}, err
+	}
+
+	user, err := s.repo.SetRole(ctx, userId, role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.User{}, ErrUserNotFound
+		}
+		return core.User{}, err
+	}
+	return user, nil
+}
+
+// Return currently locked emails and ips.
+func (s *AdminService) GetLockouts(ctx context.Context) ([]core.Lockout, error) {
+	return s.limiter.GetLockouts(ctx)
+}
+
+// Unlock email or ip and forget its failed attempts.
+func (s *AdminService) ClearLockout(ctx context.Context, key string) error {
+	return s.limiter.ClearLockout(ctx, key)
+}
+
+// Return user targeted by moderation action. Actor can moderate only
+// users with role below own one and never themselves.
+func (s *AdminService) moderatedUser(ctx context.Context, actor core.Actor, userId uuid.UUID) (core.User, error) {
+	if actor.Id == userId {
+		return core.User{}, ErrSelfModeration
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.User{}, ErrUserNotFound
+		}
+		return core.User{}, err
+	}
+	if core.RoleRank(user.Role) >= core.RoleRank(actor.Role) {
+		return core.User{}, ErrInsufficientRole
+	}
+	return user, nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func initAdminDeps(t *testing.T) (deps, *mock_psql.MockAdmin, *AdminService) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	authRepo := mock_psql.NewMockAuth(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	repo := mock_psql.NewMockAdmin(ctrl)
+
+	srv := newAdminService(repo, authRepo, rv, ll)
+	return initDeps(nil, authRepo, nil, nil, rv, ll), repo, srv
+}
+
+func TestAdmin_SearchUsers(t *testing.T) {
+	_, repo, adminSrv := initAdminDeps(t)
+	users := []core.User{{Id: uuid.New(), Email: "user@example.com"}}
+
+	repo.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{Query: "user", Limit: defaultSearchLimit}).Return(users, nil)
+	res, err := adminSrv.SearchUsers(context.Background(), core.UserSearch{Query: "user"})
+	require.NoError(t, err)
+	require.Equal(t, users, res)
+
+	repo.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{Query: "user", Limit: 5, Offset: 10}).Return(nil, errRepo)
+	_, err = adminSrv.SearchUsers(context.Background(), core.UserSearch{Query: "user", Limit: 5, Offset: 10})
+	require.ErrorIs(t, err, errRepo)
+}
+
+func TestAdmin_BanUser(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID)
+
+	d, repo, adminSrv := initAdminDeps(t)
+	moderator := core.Actor{Id: uuid.New(), Role: core.RoleModerator}
+	testUUID := uuid.New()
+	sessions := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tests := []struct {
+		name         string
+		actor        core.Actor
+		userId       uuid.UUID
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:   "OK",
+			actor:  moderator,
+			userId: testUUID,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil)
+				r.EXPECT().BanUser(gomock.Any(), userId, "spam", gomock.Any()).Return(sessions, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), sessions[0], sessions[1]).Return(nil)
+			},
+		},
+		{
+			name:         "self",
+			actor:        moderator,
+			userId:       moderator.Id,
+			expErr:       ErrSelfModeration,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {},
+		},
+		{
+			name:   "target of same role",
+			actor:  moderator,
+			userId: testUUID,
+			expErr: ErrInsufficientRole,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleModerator}, nil)
+			},
+		},
+		{
+			name:   "admin bans moderator",
+			actor:  core.Actor{Id: uuid.New(), Role: core.RoleAdmin},
+			userId: testUUID,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleModerator}, nil)
+				r.EXPECT().BanUser(gomock.Any(), userId, "spam", gomock.Any()).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:   "user not found",
+			actor:  moderator,
+			userId: testUUID,
+			expErr: ErrUserNotFound,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo ban error",
+			actor:  moderator,
+			userId: testUUID,
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil)
+				r.EXPECT().BanUser(gomock.Any(), userId, "spam", gomock.Any()).Return(nil, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, tt.userId)
+
+			err := adminSrv.BanUser(context.Background(), tt.actor, tt.userId, "spam")
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAdmin_UnbanUser(t *testing.T) {
+	d, repo, adminSrv := initAdminDeps(t)
+	moderator := core.Actor{Id: uuid.New(), Role: core.RoleModerator}
+	userId := uuid.New()
+
+	d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil).Times(2)
+	repo.EXPECT().UnbanUser(gomock.Any(), userId).Return(nil)
+	require.NoError(t, adminSrv.UnbanUser(context.Background(), moderator, userId))
+
+	// Deleted concurrently.
+	repo.EXPECT().UnbanUser(gomock.Any(), userId).Return(pgx.ErrNoRows)
+	require.ErrorIs(t, adminSrv.UnbanUser(context.Background(), moderator, userId), ErrUserNotFound)
+}
+
+func TestAdmin_LogoutUser(t *testing.T) {
+	d, repo, adminSrv := initAdminDeps(t)
+	moderator := core.Actor{Id: uuid.New(), Role: core.RoleModerator}
+	userId := uuid.New()
+	sessions := []uuid.UUID{uuid.New()}
+
+	d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil)
+	repo.EXPECT().DeleteUserSessions(gomock.Any(), userId).Return(sessions, nil)
+	d.rv.EXPECT().Revoke(gomock.Any(), sessions[0]).Return(nil)
+	require.NoError(t, adminSrv.LogoutUser(context.Background(), moderator, userId))
+
+	require.ErrorIs(t, adminSrv.LogoutUser(context.Background(), moderator, moderator.Id), ErrSelfModeration)
+}
+
+func TestAdmin_SetRole(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID, role string)
+
+	d, repo, adminSrv := initAdminDeps(t)
+	admin := core.Actor{Id: uuid.New(), Role: core.RoleAdmin}
+	testUUID := uuid.New()
+
+	tests := []struct {
+		name         string
+		actor        core.Actor
+		role         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:  "OK",
+			actor: admin,
+			role:  core.RoleModerator,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID, role string) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil)
+				r.EXPECT().SetRole(gomock.Any(), userId, role).Return(core.User{Id: userId, Role: role}, nil)
+			},
+		},
+		{
+			name:         "role above own",
+			actor:        core.Actor{Id: uuid.New(), Role: core.RoleModerator},
+			role:         core.RoleAdmin,
+			expErr:       ErrInsufficientRole,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID, role string) {},
+		},
+		{
+			name:   "demote other admin",
+			actor:  admin,
+			role:   core.RoleUser,
+			expErr: ErrInsufficientRole,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID, role string) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleAdmin}, nil)
+			},
+		},
+		{
+			name:   "user deleted concurrently",
+			actor:  admin,
+			role:   core.RoleModerator,
+			expErr: ErrUserNotFound,
+			mockBehavior: func(d deps, r *mock_psql.MockAdmin, userId uuid.UUID, role string) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Role: core.RoleUser}, nil)
+				r.EXPECT().SetRole(gomock.Any(), userId, role).Return(core.User{}, pgx.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID, tt.role)
+
+			user, err := adminSrv.SetRole(context.Background(), tt.actor, testUUID, tt.role)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+				require.Empty(t, user)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.role, user.Role)
+			}
+		})
+	}
+}
@@ -2,84 +2,220 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/Cheasezz/anSpace/backend/internal/core"
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
 	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	"github.com/Cheasezz/anSpace/backend/pkg/email"
 	"github.com/Cheasezz/anSpace/backend/pkg/hasher"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type Auth interface {
-	SignUp(ctx context.Context, signUp core.AuthCredentials) (auth.Tokens, error)
-	SignIn(ctx context.Context, signIn core.AuthCredentials) (auth.Tokens, error)
+	SignUp(ctx context.Context, signUp core.AuthCredentials, device core.Device) (auth.Tokens, error)
+	SignIn(ctx context.Context, signIn core.AuthCredentials, device core.Device) (auth.Tokens, error)
 	LogOut(ctx context.Context, refreshToken string) (auth.Tokens, error)
-	RefreshAccessToken(ctx context.Context, refreshToken string) (auth.Tokens, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string, device core.Device) (auth.Tokens, error)
+	GetSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error)
+	RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userId uuid.UUID, refreshToken string) error
 	GetUser(ctx context.Context, userId uuid.UUID) (core.User, error)
 	GenPassResetCode(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, input core.PassResetCredentials) error
+	VerifyEmail(ctx context.Context, input core.EmailVerifyCredentials) error
+	ResendEmailVerifyCode(ctx context.Context, userId uuid.UUID) error
+	EnrollTOTP(ctx context.Context, userId uuid.UUID) (core.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userId uuid.UUID, code string) ([]string, error)
+	VerifyMFA(ctx context.Context, input core.MFACredentials, device core.Device) (auth.Tokens, error)
+	DisableTOTP(ctx context.Context, userId uuid.UUID, code string, device core.Device) error
 }
 
+const (
+	passResetCodeTTL   = 30 * time.Minute
+	emailVerifyCodeTTL = 24 * time.Hour
+)
+
+var (
+	ErrInvalidCredentials   = core.NewError(core.ErrUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrInvalidRefreshToken  = core.NewError(core.ErrUnauthorized, "invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenReused   = core.NewError(core.ErrUnauthorized, "refresh_token_reused", "refresh token reused, session revoked")
+	ErrRefreshTokenExpired  = core.NewError(core.ErrUnauthorized, "refresh_token_expired", "refresh token is expired")
+	ErrSessionNotFound      = core.NewError(core.ErrNotFound, "session_not_found", "session not found")
+	ErrUserBanned           = core.NewError(core.ErrForbidden, "user_banned", "user is banned")
+	ErrInvalidPassResetCode = core.NewError(core.ErrValidation, "invalid_pass_reset_code", "invalid password reset code")
+	ErrPassResetCodeExpired = core.NewError(core.ErrValidation, "pass_reset_code_expired", "password reset code is expired")
+
+	ErrInvalidEmailVerifyCode = core.NewError(core.ErrValidation, "invalid_email_verify_code", "invalid email verification code")
+	ErrEmailVerifyCodeExpired = core.NewError(core.ErrValidation, "email_verify_code_expired", "email verification code is expired")
+	ErrEmailAlreadyVerified   = core.NewError(core.ErrConflict, "email_already_verified", "email already verified")
+)
+
 type AuthService struct {
 	repo         psql.Auth
+	tx           repositories.TxManager
 	hasher       hasher.PasswordHasher
 	tokenManager auth.TokenManager
 	emailSender  email.Sender
+	revocation   Revocation
+	limiter      LoginLimiter
+	maxSessions  int
 }
 
-func newAuthService(r psql.Auth, h hasher.PasswordHasher, tm auth.TokenManager, es email.Sender) *AuthService {
+func newAuthService(r psql.Auth, tx repositories.TxManager, h hasher.PasswordHasher, tm auth.TokenManager, es email.Sender, rv Revocation, ll LoginLimiter, maxSessions int) *AuthService {
 	return &AuthService{
 		repo:         r,
+		tx:           tx,
 		hasher:       h,
 		tokenManager: tm,
 		emailSender:  es,
+		revocation:   rv,
+		limiter:      ll,
+		maxSessions:  maxSessions,
 	}
 }
 
 // Hash password and write new user into db.
-// With method repo.CreateUser.
-// Return userId and error.
-func (s *AuthService) SignUp(ctx context.Context, signUp core.AuthCredentials) (auth.Tokens, error) {
+// With method repo.CreateUser. Return ErrEmailTaken if email is taken.
+// Send email verification code on user email.
+// User, session and code are written in one transaction, failed
+// sign up leaves nothing behind.
+// Return auth.Tokens and error.
+func (s *AuthService) SignUp(ctx context.Context, signUp core.AuthCredentials, device core.Device) (auth.Tokens, error) {
 	pass, err := s.hasher.Hash(signUp.Password)
 	if err != nil {
 		return auth.Tokens{}, err
 	}
 	signUp.Password = pass
-	userId, err := s.repo.CreateUser(ctx, signUp)
+
+	var tokens auth.Tokens
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		userId, err := s.repo.CreateUser(ctx, signUp)
+		if err != nil {
+			if errors.Is(err, psql.ErrDuplicate) {
+				return ErrEmailTaken
+			}
+			return err
+		}
+
+		tokens, err = s.createSession(ctx, core.User{Id: userId, Email: signUp.Email, Role: core.RoleUser}, device)
+		if err != nil {
+			return err
+		}
+
+		// Email goes last, nothing can be rolled back after it is sent.
+		return s.sendEmailVerifyCode(ctx, signUp.Email)
+	})
 	if err != nil {
 		return auth.Tokens{}, err
 	}
 
-	return s.createSession(ctx, userId)
+	return tokens, nil
 }
 
-// Hash password and search ind db userId with method repo.GetUser.
+// Search user by email and verify password against stored hash.
+// Failed attempts are counted per email and ip, locked ones get *LockoutError.
+// Legacy or outdated hash is upgraded to current format on success.
+// User with TOTP enabled gets *MFARequiredError with mfa pending token instead of tokens.
 // Pass userId into createSession method.
 // Return auth.Tokens and error.
-func (s *AuthService) SignIn(ctx context.Context, signIn core.AuthCredentials) (auth.Tokens, error) {
-	pass, err := s.hasher.Hash(signIn.Password)
+func (s *AuthService) SignIn(ctx context.Context, signIn core.AuthCredentials, device core.Device) (auth.Tokens, error) {
+	if err := s.limiter.Check(ctx, limitScopeSignIn, signIn.Email, device.IP); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, signIn.Email)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.Tokens{}, s.signInFailed(ctx, signIn.Email, device.IP)
+		}
 		return auth.Tokens{}, err
 	}
-	signIn.Password = pass
-	userId, err := s.repo.GetUserIdByLogPas(ctx, signIn)
+	// User created by oauth sign in has no password.
+	if user.PasswordHash == "" {
+		return auth.Tokens{}, s.signInFailed(ctx, signIn.Email, device.IP)
+	}
+
+	ok, err := s.hasher.Verify(signIn.Password, user.PasswordHash)
 	if err != nil {
 		return auth.Tokens{}, err
 	}
+	if !ok {
+		return auth.Tokens{}, s.signInFailed(ctx, signIn.Email, device.IP)
+	}
 
-	return s.createSession(ctx, userId)
+	if err := s.limiter.Succeed(ctx, limitScopeSignIn, signIn.Email); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(ctx, user.Id, signIn.Password)
+	}
+
+	return s.signInUser(ctx, user, device)
+}
+
+// Create session of authenticated user. For user with TOTP enabled
+// return *MFARequiredError with mfa pending token instead.
+// Banned user can't sign in, user with scheduled deletion can't until it is cancelled.
+func (s *AuthService) signInUser(ctx context.Context, user core.User, device core.Device) (auth.Tokens, error) {
+	if user.Banned() {
+		return auth.Tokens{}, ErrUserBanned
+	}
+	if user.DeletionScheduled() {
+		return auth.Tokens{}, ErrAccountPendingDeletion
+	}
+	if user.TOTPEnabled {
+		mfaToken, err := s.tokenManager.NewMFAToken(user.Id.String())
+		if err != nil {
+			return auth.Tokens{}, err
+		}
+		return auth.Tokens{}, &MFARequiredError{Token: mfaToken}
+	}
+
+	return s.createSession(ctx, user, device)
 }
 
-// Uppdate core.Session in repo with emty tokens value.
+// Count failed sign in attempt and return ErrInvalidCredentials.
+func (s *AuthService) signInFailed(ctx context.Context, email, ip string) error {
+	if err := s.limiter.Fail(ctx, limitScopeSignIn, email, ip); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// Upgrade stored password hash to current hasher format.
+// Best effort: on failure old hash stays and upgrade is retried on next sign in.
+func (s *AuthService) rehashPassword(ctx context.Context, userId uuid.UUID, password string) {
+	pass, err := s.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+	_ = s.repo.UpdatePasswordHash(ctx, userId, pass)
+}
+
+// Delete core.Session with all tokens of its family from repo
+// and revoke access tokens issued for it.
+// Expired refresh token is rejected.
 // Return empty auth.Tokens struct
 func (s *AuthService) LogOut(ctx context.Context, refreshToken string) (auth.Tokens, error) {
 
 	tkns := auth.Tokens{Access: auth.ATknInfo{Token: ""}, Refresh: auth.RTknInfo{Token: ""}}
 	session, err := s.repo.GetUserSessionByRefreshToken(ctx, refreshToken)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tkns, ErrInvalidRefreshToken
+		}
+		return tkns, err
+	}
+
+	if err := s.checkSessionExpiry(ctx, session); err != nil {
 		return tkns, err
 	}
 
@@ -87,61 +223,192 @@ func (s *AuthService) LogOut(ctx context.Context, refreshToken string) (auth.Tok
 		return auth.Tokens{}, err
 	}
 
+	if err := s.revocation.Revoke(ctx, session.FamilyId); err != nil {
+		return auth.Tokens{}, err
+	}
+
 	return tkns, nil
 }
 
-// Generate new jwt and refresh tokens, create new core.Session struct.
-// And write this session in repo by repo.SetSession method.
+// Return ErrRefreshTokenExpired if session refresh token is expired.
+// Expired session is deleted.
+func (s *AuthService) checkSessionExpiry(ctx context.Context, session core.Session) error {
+	if _, err := s.tokenManager.ValidateRefreshToken(session.ExpiresAt); err != nil {
+		if err := s.repo.DeleteSession(ctx, session); err != nil {
+			return err
+		}
+		return ErrRefreshTokenExpired
+	}
+	return nil
+}
+
+// Generate new jwt and refresh tokens, create new core.Session struct
+// that starts new token family.
+// And write this session in repo by repo.SetSession method,
+// oldest user sessions above s.maxSessions are evicted there
+// and access tokens of evicted sessions are revoked.
 // Return auth.Tokens and error.
-func (s *AuthService) createSession(ctx context.Context, userId uuid.UUID) (auth.Tokens, error) {
+func (s *AuthService) createSession(ctx context.Context, user core.User, device core.Device) (auth.Tokens, error) {
+	tokens, session, err := s.newTokens(user, uuid.New(), device)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	evicted, err := s.repo.SetSession(ctx, session, s.maxSessions)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+	if err := s.revocation.Revoke(ctx, evicted...); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// Generate new jwt and refresh tokens and core.Session of token family for them.
+func (s *AuthService) newTokens(user core.User, familyId uuid.UUID, device core.Device) (auth.Tokens, core.Session, error) {
 	var (
 		tokens auth.Tokens
 		err    error
 	)
 
-	tokens.Access.Token, err = s.tokenManager.NewJWT(userId.String())
+	tokens.Access.Token, err = s.tokenManager.NewJWT(accessClaims(user, familyId))
 	if err != nil {
-		return auth.Tokens{}, err
+		return auth.Tokens{}, core.Session{}, err
 	}
 
-	RTInfo, err := s.tokenManager.NewRefreshToken()
+	RTInfo, err := s.tokenManager.NewRefreshToken(familyId)
 	if err != nil {
-		return auth.Tokens{}, err
+		return auth.Tokens{}, core.Session{}, err
 	}
 	tokens.Refresh = RTInfo
 
 	session := core.Session{
-		UserId:       userId,
+		UserId:       user.Id,
 		RefreshToken: tokens.Refresh.Token,
+		FamilyId:     familyId,
+		ExpiresAt:    tokens.Refresh.ExpiresAt,
+		UserAgent:    device.UserAgent,
+		IP:           device.IP,
+	}
+
+	return tokens, session, nil
+}
+
+// Claims of access token issued for session (token family) of user.
+func accessClaims(user core.User, familyId uuid.UUID) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: user.Id.String()},
+		SessionId:      familyId.String(),
+		Roles:          []string{user.Role},
+		EmailVerified:  user.EmailVerified,
 	}
+}
 
-	err = s.repo.SetSession(ctx, session)
+// Rotate refresh token: issue new access and refresh tokens in the same
+// token family and mark presented refresh token as rotated.
+// If already rotated token is presented again, whole token family is revoked.
+// Session of user banned after sign in is deleted too.
+// Return auth.Tokens and error.
+func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshToken string, device core.Device) (auth.Tokens, error) {
+	session, err := s.repo.GetUserSessionByRefreshToken(ctx, refreshToken)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.Tokens{}, ErrInvalidRefreshToken
+		}
 		return auth.Tokens{}, err
 	}
 
-	return tokens, nil
-}
+	if session.Rotated {
+		return auth.Tokens{}, s.revokeSessionFamily(ctx, session)
+	}
 
-// Return auth.Tokens struct with uppdate access token only.
-func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshToken string) (auth.Tokens, error) {
-	var (
-		tokens auth.Tokens
-	)
+	if err := s.checkSessionExpiry(ctx, session); err != nil {
+		return auth.Tokens{}, err
+	}
 
-	session, err := s.repo.GetUserSessionByRefreshToken(ctx, refreshToken)
+	user, err := s.repo.GetUserById(ctx, session.UserId)
 	if err != nil {
-		return tokens, err
+		return auth.Tokens{}, err
+	}
+	if user.Banned() {
+		if err := s.repo.DeleteSession(ctx, session); err != nil {
+			return auth.Tokens{}, err
+		}
+		if err := s.revocation.Revoke(ctx, session.FamilyId); err != nil {
+			return auth.Tokens{}, err
+		}
+		return auth.Tokens{}, ErrUserBanned
 	}
 
-	tokens.Access.Token, err = s.tokenManager.NewJWT(session.UserId.String())
+	tokens, newSession, err := s.newTokens(user, session.FamilyId, device)
 	if err != nil {
 		return auth.Tokens{}, err
 	}
+	newSession.ParentToken = session.RefreshToken
+	newSession.CreatedAt = session.CreatedAt
+
+	if err := s.repo.RotateSession(ctx, session, newSession); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Token was rotated by concurrent request with the same token.
+			return auth.Tokens{}, s.revokeSessionFamily(ctx, session)
+		}
+		return auth.Tokens{}, err
+	}
 
 	return tokens, nil
 }
 
+// Reuse of rotated refresh token is a theft signal,
+// so all tokens of its family are deleted.
+func (s *AuthService) revokeSessionFamily(ctx context.Context, session core.Session) error {
+	if err := s.repo.DeleteSession(ctx, session); err != nil {
+		return err
+	}
+	if err := s.revocation.Revoke(ctx, session.FamilyId); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Return active sessions of user, one per token family.
+func (s *AuthService) GetSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error) {
+	return s.repo.GetUserSessions(ctx, userId)
+}
+
+// Delete session with given id (token family) of user and revoke its access tokens.
+func (s *AuthService) RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) error {
+	err := s.repo.DeleteSessionById(ctx, userId, sessionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return s.revocation.Revoke(ctx, sessionId)
+}
+
+// Delete all user sessions except the one refresh token belongs to
+// and revoke their access tokens.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userId uuid.UUID, refreshToken string) error {
+	session, err := s.repo.GetUserSessionByRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if session.UserId != userId || session.Rotated {
+		return ErrInvalidRefreshToken
+	}
+
+	sessionIds, err := s.repo.DeleteSessionsExcept(ctx, userId, session.FamilyId)
+	if err != nil {
+		return err
+	}
+	return s.revocation.Revoke(ctx, sessionIds...)
+}
+
 // Return user by userid
 func (s *AuthService) GetUser(c context.Context, userId uuid.UUID) (core.User, error) {
 	user, err := s.repo.GetUserById(c, userId)
@@ -151,31 +418,155 @@ func (s *AuthService) GetUser(c context.Context, userId uuid.UUID) (core.User, e
 	return user, nil
 }
 
-// Genereate password reset code. Set them in db and send on email in param
+// Genereate password reset code. Set them in db and send on email in param.
+// Unknown email gets no code and no error, so response doesn't tell
+// whether email has account. Every request is counted against quota of email,
+// email above it gets *LockoutError. Requests per ip are limited by rate limiter
+// of transport, ip lockout is kept for failed attempts.
 func (s *AuthService) GenPassResetCode(c context.Context, email string) error {
+	if err := s.limiter.Check(c, limitScopePassReset, email, ""); err != nil {
+		return err
+	}
+	if err := s.limiter.Fail(c, limitScopePassReset, email, ""); err != nil {
+		return err
+	}
+
 	user, err := s.repo.GetUserByEmail(c, email)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
 		return err
 	}
 
-	passResetCode := make([]byte, 32)
-	src := rand.NewSource(time.Now().UTC().Unix() + rand.Int63())
-	r := rand.New(src)
-	r.Read(passResetCode)
+	return s.tx.WithinTx(c, func(c context.Context) error {
+		return s.sendCode(c, user.Email, core.CodeTypePassReset, passResetCodeTTL, "This is your password reset code:%s")
+	})
+}
 
-	code := core.CodeCredentials{
+// Generate random code with given type, set it in db and send on email.
+// Message must contain %s verb for code. Used inside unit of work,
+// so code isn't stored if email can't be sent.
+func (s *AuthService) sendCode(c context.Context, email, codeType string, ttl time.Duration, message string) error {
+	code, err := newCode(email, codeType, ttl)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetCode(c, code); err != nil {
+		return err
+	}
+	if err := s.emailSender.Send(email, fmt.Sprintf(message, code.Code)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Generate random code of email with given type, expiring after ttl.
+func newCode(email, codeType string, ttl time.Duration) (core.CodeCredentials, error) {
+	rawCode := make([]byte, 32)
+	if _, err := rand.Read(rawCode); err != nil {
+		return core.CodeCredentials{}, err
+	}
+
+	return core.CodeCredentials{
 		Email:     email,
-		Code:      fmt.Sprintf("%x", passResetCode),
-		CodeType:  "passReset",
-		ExpiresAt: time.Now().Add(time.Minute * 30),
+		Code:      fmt.Sprintf("%x", rawCode),
+		CodeType:  codeType,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}, nil
+}
+
+// Generate email verification code, set it in db and send on user email.
+// Previous verification codes of user are deleted.
+func (s *AuthService) ResendEmailVerifyCode(c context.Context, userId uuid.UUID) error {
+	user, err := s.repo.GetUserById(c, userId)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
 	}
 
-	if err := s.repo.SetPassResetCode(c, code); err != nil {
+	// Old codes stay valid if new one can't be stored or sent.
+	return s.tx.WithinTx(c, func(c context.Context) error {
+		if err := s.repo.DeleteCodesByType(c, user.Email, core.CodeTypeEmailVerify); err != nil {
+			return err
+		}
+		return s.sendEmailVerifyCode(c, user.Email)
+	})
+}
+
+func (s *AuthService) sendEmailVerifyCode(c context.Context, email string) error {
+	return s.sendCode(c, email, core.CodeTypeEmailVerify, emailVerifyCodeTTL, "This is your email verification code:%s")
+}
+
+// Check email verification code and its expiration time.
+// Mark user email as verified and delete code.
+func (s *AuthService) VerifyEmail(c context.Context, input core.EmailVerifyCredentials) error {
+	code, err := s.repo.GetCode(c, core.CodeCredentials{
+		Email:    input.Email,
+		Code:     input.Code,
+		CodeType: core.CodeTypeEmailVerify,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidEmailVerifyCode
+		}
 		return err
 	}
-	message := fmt.Sprintf("This is your password reset code:%x", passResetCode)
-	if err := s.emailSender.Send(user.Email, message); err != nil {
+
+	if time.Now().UTC().After(code.ExpiresAt) {
+		if err := s.repo.DeleteCode(c, code); err != nil {
+			return err
+		}
+		return ErrEmailVerifyCodeExpired
+	}
+
+	if err := s.repo.VerifyEmail(c, code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidEmailVerifyCode
+		}
 		return err
 	}
+
 	return nil
 }
+
+// Check password reset code from email and its expiration time.
+// Hash new password and write it in db, code and all user sessions are deleted,
+// access tokens of deleted sessions are revoked.
+func (s *AuthService) ResetPassword(c context.Context, input core.PassResetCredentials) error {
+	code, err := s.repo.GetCode(c, core.CodeCredentials{
+		Email:    input.Email,
+		Code:     input.Code,
+		CodeType: core.CodeTypePassReset,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidPassResetCode
+		}
+		return err
+	}
+
+	if time.Now().UTC().After(code.ExpiresAt) {
+		if err := s.repo.DeleteCode(c, code); err != nil {
+			return err
+		}
+		return ErrPassResetCodeExpired
+	}
+
+	pass, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return err
+	}
+
+	sessionIds, err := s.repo.ResetPassword(c, code, pass)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidPassResetCode
+		}
+		return err
+	}
+	return s.revocation.Revoke(c, sessionIds...)
+}
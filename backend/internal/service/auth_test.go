@@ -3,16 +3,21 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/Cheasezz/anSpace/backend/internal/core"
+	mock_repositories "github.com/Cheasezz/anSpace/backend/internal/repository/mocks"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
 	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	mock_auth "github.com/Cheasezz/anSpace/backend/pkg/auth/mocks"
 	mock_email "github.com/Cheasezz/anSpace/backend/pkg/email/mocks"
 	mock_hash "github.com/Cheasezz/anSpace/backend/pkg/hasher/mocks"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -25,6 +30,10 @@ var (
 	errRepoGetUserSessionByRefreshToken = fmt.Errorf("repo get by refresh token error")
 	errRepoGetUserById                  = fmt.Errorf("repo GetUserById error")
 	errRefreshTokenIsExpired            = fmt.Errorf("tm refresh token is expired")
+	errEmailSender                      = fmt.Errorf("email sender error")
+
+	testMaxSessions = 5
+	testDevice      = core.Device{UserAgent: "Mozilla/5.0", IP: "127.0.0.1"}
 )
 
 func initTokens() auth.Tokens {
@@ -41,7 +50,69 @@ func initTokens() auth.Tokens {
 	}
 }
 func initSession() core.Session {
-	return core.Session{UserId: uuid.New(), RefreshToken: "token"}
+	return core.Session{UserId: uuid.New(), RefreshToken: "token", FamilyId: uuid.New(), ExpiresAt: time.Now().Add(time.Hour).UTC()}
+}
+
+// sessionMatcher match session that starts new token family.
+type sessionMatcher struct {
+	s core.Session
+}
+
+func newSessionMatcher(s core.Session) gomock.Matcher {
+	return sessionMatcher{s: s}
+}
+
+func (m sessionMatcher) Matches(x interface{}) bool {
+	s, ok := x.(core.Session)
+	if !ok {
+		return false
+	}
+	return s.UserId == m.s.UserId &&
+		s.RefreshToken == m.s.RefreshToken &&
+		s.ExpiresAt.Equal(m.s.ExpiresAt) &&
+		s.FamilyId != uuid.Nil &&
+		s.ParentToken == "" &&
+		s.UserAgent == testDevice.UserAgent &&
+		s.IP == testDevice.IP &&
+		!s.Rotated
+}
+
+func (m sessionMatcher) String() string {
+	return fmt.Sprintf("is new session family of user %s with refresh token %q", m.s.UserId, m.s.RefreshToken)
+}
+
+// claimsMatcher match access token claims of new token family.
+type claimsMatcher struct {
+	userId        uuid.UUID
+	emailVerified bool
+}
+
+func newClaimsMatcher(userId uuid.UUID, emailVerified bool) gomock.Matcher {
+	return claimsMatcher{userId: userId, emailVerified: emailVerified}
+}
+
+func (m claimsMatcher) Matches(x interface{}) bool {
+	c, ok := x.(auth.Claims)
+	if !ok {
+		return false
+	}
+	sid, err := uuid.Parse(c.SessionId)
+	return c.Subject == m.userId.String() &&
+		c.EmailVerified == m.emailVerified &&
+		err == nil && sid != uuid.Nil
+}
+
+func (m claimsMatcher) String() string {
+	return fmt.Sprintf("is access claims of user %s with new session id", m.userId)
+}
+
+// newTxMock returns unit of work that just runs fn, rollback is covered by integration tests.
+func newTxMock(ctrl *gomock.Controller) *mock_repositories.MockTxManager {
+	tx := mock_repositories.NewMockTxManager(ctrl)
+	tx.EXPECT().WithinTx(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).AnyTimes()
+	return tx
 }
 
 type deps struct {
@@ -49,10 +120,12 @@ type deps struct {
 	r  *mock_psql.MockAuth
 	tm *mock_auth.MockTokenManager
 	es *mock_email.MockSender
+	rv *mock_service.MockRevocation
+	ll *mock_service.MockLoginLimiter
 }
 
-func initDeps(h *mock_hash.MockPasswordHasher, r *mock_psql.MockAuth, tm *mock_auth.MockTokenManager, es *mock_email.MockSender) deps {
-	return deps{h, r, tm, es}
+func initDeps(h *mock_hash.MockPasswordHasher, r *mock_psql.MockAuth, tm *mock_auth.MockTokenManager, es *mock_email.MockSender, rv *mock_service.MockRevocation, ll *mock_service.MockLoginLimiter) deps {
+	return deps{h, r, tm, es, rv, ll}
 }
 
 func TestAuth_SignUp(t *testing.T) {
@@ -66,9 +139,11 @@ func TestAuth_SignUp(t *testing.T) {
 	hash := mock_hash.NewMockPasswordHasher(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	es := mock_email.NewMockSender(ctrl)
-	d := initDeps(hash, repo, tm, es)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
 
-	authSrv := newAuthService(repo, hash, tm, es)
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
 	testUUID := uuid.New()
 	tests := []struct {
 		name         string
@@ -80,13 +155,18 @@ func TestAuth_SignUp(t *testing.T) {
 		{
 			name:      "OK",
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
-			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token},
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
 			mockBehavior: func(d deps, input core.AuthCredentials, session core.Session) {
 				d.h.EXPECT().Hash(input.Password).Return(input.Password, nil)
 				d.r.EXPECT().CreateUser(gomock.Any(), input).Return(testUUID, nil)
-				d.tm.EXPECT().NewJWT(testUUID.String()).Return(tokens.Access.Token, nil)
-				d.tm.EXPECT().NewRefreshToken().Return(tokens.Refresh, nil)
-				d.r.EXPECT().SetSession(gomock.Any(), session).Return(nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).Return(nil)
+				d.es.EXPECT().Send(input.Email, gomock.Any()).Return(nil)
+				d.tm.EXPECT().NewJWT(gomock.All(newClaimsMatcher(testUUID, false), gomock.Cond(func(x any) bool {
+					return slices.Equal(x.(auth.Claims).Roles, []string{core.RoleUser})
+				}))).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(session), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
 			},
 		},
 		{
@@ -106,13 +186,49 @@ func TestAuth_SignUp(t *testing.T) {
 				d.r.EXPECT().CreateUser(gomock.Any(), input).Return(uuid.UUID{}, errRepo)
 			},
 		},
+		{
+			name:      "email taken",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrEmailTaken,
+			mockBehavior: func(d deps, input core.AuthCredentials, session core.Session) {
+				d.h.EXPECT().Hash(input.Password).Return(input.Password, nil)
+				d.r.EXPECT().CreateUser(gomock.Any(), input).Return(uuid.UUID{}, psql.ErrDuplicate)
+			},
+		},
+		{
+			name:      "Email sender error",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    errEmailSender,
+			mockBehavior: func(d deps, input core.AuthCredentials, session core.Session) {
+				d.h.EXPECT().Hash(input.Password).Return(input.Password, nil)
+				d.r.EXPECT().CreateUser(gomock.Any(), input).Return(testUUID, nil)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), gomock.Any(), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).Return(nil)
+				d.es.EXPECT().Send(input.Email, gomock.Any()).Return(errEmailSender)
+			},
+		},
+		{
+			name:      "Repo set session error",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    errRepo,
+			mockBehavior: func(d deps, input core.AuthCredentials, session core.Session) {
+				d.h.EXPECT().Hash(input.Password).Return(input.Password, nil)
+				d.r.EXPECT().CreateUser(gomock.Any(), input).Return(testUUID, nil)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), gomock.Any(), testMaxSessions).Return(nil, errRepo)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockBehavior(d, tt.inputUser, tt.session)
 
-			tkns, err := authSrv.SignUp(context.Background(), tt.inputUser)
+			tkns, err := authSrv.SignUp(context.Background(), tt.inputUser, testDevice)
 			if err != nil {
 				require.Empty(t, tkns)
 				require.Error(t, err)
@@ -137,10 +253,13 @@ func TestAuth_SignIn(t *testing.T) {
 	repo := mock_psql.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	es := mock_email.NewMockSender(ctrl)
-	d := initDeps(hash, repo, tm, es)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
 
-	authSrv := newAuthService(repo, hash, tm, es)
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
 	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "Cheasezz@gmail.com", PasswordHash: "hash"}
 	tests := []struct {
 		name         string
 		inputUser    core.AuthCredentials
@@ -151,31 +270,110 @@ func TestAuth_SignIn(t *testing.T) {
 		{
 			name:      "OK",
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
-			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token},
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return(i.Password, nil)
-				d.r.EXPECT().GetUserIdByLogPas(gomock.Any(), i).Return(testUUID, nil)
-				d.tm.EXPECT().NewJWT(testUUID.String()).Return(tokens.Access.Token, nil)
-				d.tm.EXPECT().NewRefreshToken().Return(tokens.Refresh, nil)
-				d.r.EXPECT().SetSession(gomock.Any(), s).Return(nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(s), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
 			},
 		},
 		{
-			name:      "hasher error",
+			name:      "OK with evicted sessions revoked",
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
-			expErr:    errHasher,
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				evicted := uuid.New()
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(s), testMaxSessions).Return([]uuid.UUID{evicted}, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), evicted).Return(nil)
+			},
+		},
+		{
+			name:      "OK with legacy hash upgrade",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(true)
+				d.h.EXPECT().Hash(i.Password).Return("newHash", nil)
+				d.r.EXPECT().UpdatePasswordHash(gomock.Any(), testUUID, "newHash").Return(nil)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(s), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:      "OK with failed hash upgrade",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(true)
+				d.h.EXPECT().Hash(i.Password).Return("newHash", nil)
+				d.r.EXPECT().UpdatePasswordHash(gomock.Any(), testUUID, "newHash").Return(errRepo)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(s), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:      "user not found",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrInvalidCredentials,
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return("", errHasher)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(core.User{}, pgx.ErrNoRows)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
 			},
 		},
 		{
-			name:      "repo get user by id error",
+			name:      "repo get user by email error",
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			expErr:    errRepo,
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return(i.Password, nil)
-				d.r.EXPECT().GetUserIdByLogPas(gomock.Any(), i).Return(uuid.UUID{}, errRepo)
-
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(core.User{}, errRepo)
+			},
+		},
+		{
+			name:      "wrong password",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrInvalidCredentials,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(false, nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:      "hasher verify error",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    errHasher,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(false, errHasher)
 			},
 		},
 		{
@@ -183,9 +381,12 @@ func TestAuth_SignIn(t *testing.T) {
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			expErr:    errNewJwt,
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return(i.Password, nil)
-				d.r.EXPECT().GetUserIdByLogPas(gomock.Any(), i).Return(testUUID, nil)
-				d.tm.EXPECT().NewJWT(testUUID.String()).Return("", errNewJwt)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return("", errNewJwt)
 			},
 		},
 		{
@@ -193,23 +394,103 @@ func TestAuth_SignIn(t *testing.T) {
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			expErr:    errNewRefreshToken,
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return(i.Password, nil)
-				d.r.EXPECT().GetUserIdByLogPas(gomock.Any(), i).Return(testUUID, nil)
-				d.tm.EXPECT().NewJWT(testUUID.String()).Return(tokens.Access.Token, nil)
-				d.tm.EXPECT().NewRefreshToken().Return(auth.RTknInfo{}, errNewRefreshToken)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(auth.RTknInfo{}, errNewRefreshToken)
 			},
 		},
 		{
 			name:      "repo set session error",
 			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
-			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token},
+			session:   core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt},
+			expErr:    errRepo,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(s), testMaxSessions).Return(nil, errRepo)
+			},
+		},
+		{
+			name:      "user without password",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrInvalidCredentials,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				oauthUser := user
+				oauthUser.PasswordHash = ""
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(oauthUser, nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:      "mfa required",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrMFARequired,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				mfaUser := user
+				mfaUser.TOTPEnabled = true
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(mfaUser, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+				d.tm.EXPECT().NewMFAToken(testUUID.String()).Return("mfaToken", nil)
+			},
+		},
+		{
+			name:      "deletion scheduled",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrAccountPendingDeletion,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				deletingUser := user
+				deletingUser.DeleteAt = time.Now().Add(time.Hour)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(deletingUser, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+			},
+		},
+		{
+			name:      "banned",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrUserBanned,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				bannedUser := user
+				bannedUser.BannedAt = time.Now().Add(-time.Hour)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(bannedUser, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeSignIn, i.Email).Return(nil)
+				d.h.EXPECT().NeedsRehash(user.PasswordHash).Return(false)
+			},
+		},
+		{
+			name:      "locked out",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			expErr:    ErrTooManyAttempts,
+			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(&LockoutError{RetryAfter: time.Minute})
+			},
+		},
+		{
+			name:      "limiter fail error",
+			inputUser: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			expErr:    errRepo,
 			mockBehavior: func(d deps, i core.AuthCredentials, s core.Session) {
-				d.h.EXPECT().Hash(i.Password).Return(i.Password, nil)
-				d.r.EXPECT().GetUserIdByLogPas(gomock.Any(), i).Return(testUUID, nil)
-				d.tm.EXPECT().NewJWT(testUUID.String()).Return(tokens.Access.Token, nil)
-				d.tm.EXPECT().NewRefreshToken().Return(tokens.Refresh, nil)
-				d.r.EXPECT().SetSession(gomock.Any(), s).Return(errRepo)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), i.Email).Return(user, nil)
+				d.h.EXPECT().Verify(i.Password, user.PasswordHash).Return(false, nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeSignIn, i.Email, testDevice.IP).Return(errRepo)
 			},
 		},
 	}
@@ -218,7 +499,7 @@ func TestAuth_SignIn(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockBehavior(d, tt.inputUser, tt.session)
 
-			tkns, err := authSrv.SignIn(context.Background(), tt.inputUser)
+			tkns, err := authSrv.SignIn(context.Background(), tt.inputUser, testDevice)
 			if err != nil {
 				require.Empty(t, tkns)
 				require.Error(t, err)
@@ -241,9 +522,11 @@ func TestAuth_LogOut(t *testing.T) {
 	repo := mock_psql.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	es := mock_email.NewMockSender(ctrl)
-	d := initDeps(hash, repo, tm, es)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
 
-	authSrv := newAuthService(repo, hash, tm, es)
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
 
 	tests := []struct {
 		name         string
@@ -260,7 +543,30 @@ func TestAuth_LogOut(t *testing.T) {
 			expTokens: auth.Tokens{Refresh: auth.RTknInfo{ExpiresAt: time.Now()}},
 			mockBehavior: func(d deps, rt string, s core.Session) {
 				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
 				d.r.EXPECT().DeleteSession(gomock.Any(), s)
+				d.rv.EXPECT().Revoke(gomock.Any(), s.FamilyId).Return(nil)
+			},
+		},
+		{
+			name:      "expired refresh token",
+			rToken:    "token",
+			session:   initSession(),
+			expTokens: auth.Tokens{Refresh: auth.RTknInfo{ExpiresAt: time.Now()}},
+			expErr:    ErrRefreshTokenExpired,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(0, auth.ErrRefreshTokenExpired)
+				d.r.EXPECT().DeleteSession(gomock.Any(), s).Return(nil)
+			},
+		},
+		{
+			name:      "unknown refresh token",
+			rToken:    "token",
+			expTokens: auth.Tokens{Refresh: auth.RTknInfo{ExpiresAt: time.Now()}},
+			expErr:    ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(core.Session{}, pgx.ErrNoRows)
 			},
 		},
 		{
@@ -293,7 +599,8 @@ func TestAuth_LogOut(t *testing.T) {
 }
 
 func TestAuthService_RefreshAccessToken(t *testing.T) {
-	type mockBehavior func(d deps, tkns auth.Tokens, s core.Session, day int)
+	type mockBehavior func(d deps, rt string, s core.Session)
+	tokens := initTokens()
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -302,60 +609,330 @@ func TestAuthService_RefreshAccessToken(t *testing.T) {
 	repo := mock_psql.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	es := mock_email.NewMockSender(ctrl)
-	d := initDeps(hash, repo, tm, es)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+
+	rotatedSession := initSession()
+	rotatedSession.Rotated = true
+	rotatedSession.RefreshToken = "oldToken"
 
-	authSrv := newAuthService(repo, hash, tm, es)
+	refreshUser := func(s core.Session) core.User {
+		return core.User{Id: s.UserId, EmailVerified: true}
+	}
+
+	newSession := func(s core.Session) core.Session {
+		return core.Session{
+			UserId:       s.UserId,
+			RefreshToken: tokens.Refresh.Token,
+			FamilyId:     s.FamilyId,
+			ParentToken:  s.RefreshToken,
+			ExpiresAt:    tokens.Refresh.ExpiresAt,
+			CreatedAt:    s.CreatedAt,
+			UserAgent:    testDevice.UserAgent,
+			IP:           testDevice.IP,
+		}
+	}
 
 	tests := []struct {
-		name           string
-		tokens         auth.Tokens
-		session        core.Session
-		dayUntilExpire int
-		expErr         error
-		mockBehavior   mockBehavior
+		name         string
+		rToken       string
+		session      core.Session
+		expErr       error
+		mockBehavior mockBehavior
 	}{
 		{
-			name:           "ok (upd access token only)",
-			tokens:         auth.Tokens{Access: auth.ATknInfo{Token: "token"}},
-			session:        initSession(),
-			dayUntilExpire: 20,
-			mockBehavior: func(d deps, tkns auth.Tokens, s core.Session, day int) {
-				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), tkns.Refresh.Token).Return(s, nil)
-				d.tm.EXPECT().NewJWT(s.UserId.String()).Return("newToken", nil)
+			name:    "ok (rotate refresh token)",
+			rToken:  "oldToken",
+			session: initSession(),
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(refreshUser(s), nil)
+				d.tm.EXPECT().NewJWT(accessClaims(refreshUser(s), s.FamilyId)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().RotateSession(gomock.Any(), s, newSession(s)).Return(nil)
+			},
+		},
+		{
+			name:   "unknown refresh token",
+			rToken: "oldToken",
+			expErr: ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(core.Session{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo get by refresh token error",
+			rToken: "oldToken",
+			expErr: errRepoGetUserSessionByRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(core.Session{}, errRepoGetUserSessionByRefreshToken)
+			},
+		},
+		{
+			name:    "reuse of rotated token revokes family",
+			rToken:  "oldToken",
+			session: rotatedSession,
+			expErr:  ErrRefreshTokenReused,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.r.EXPECT().DeleteSession(gomock.Any(), s).Return(nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), s.FamilyId).Return(nil)
+			},
+		},
+		{
+			name:    "concurrent rotation revokes family",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  ErrRefreshTokenReused,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(refreshUser(s), nil)
+				d.tm.EXPECT().NewJWT(accessClaims(refreshUser(s), s.FamilyId)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().RotateSession(gomock.Any(), s, newSession(s)).Return(pgx.ErrNoRows)
+				d.r.EXPECT().DeleteSession(gomock.Any(), s).Return(nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), s.FamilyId).Return(nil)
+			},
+		},
+		{
+			name:    "expired refresh token",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  ErrRefreshTokenExpired,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(0, auth.ErrRefreshTokenExpired)
+				d.r.EXPECT().DeleteSession(gomock.Any(), s).Return(nil)
+			},
+		},
+		{
+			name:    "repo get user error",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  errRepoGetUserById,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(core.User{}, errRepoGetUserById)
+			},
+		},
+		{
+			name:    "banned user loses session",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  ErrUserBanned,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				bannedUser := refreshUser(s)
+				bannedUser.BannedAt = time.Now().Add(-time.Minute)
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(bannedUser, nil)
+				d.r.EXPECT().DeleteSession(gomock.Any(), s).Return(nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), s.FamilyId).Return(nil)
 			},
 		},
 		{
-			name:    "repo get by refresh token error",
-			tokens:  initTokens(),
-			session: core.Session{},
-			expErr:  errRepoGetUserSessionByRefreshToken,
-			mockBehavior: func(d deps, tkns auth.Tokens, s core.Session, day int) {
-				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), tkns.Refresh.Token).Return(s, errRepoGetUserSessionByRefreshToken)
+			name:    "tm new jwt error",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  errNewJwt,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(refreshUser(s), nil)
+				d.tm.EXPECT().NewJWT(accessClaims(refreshUser(s), s.FamilyId)).Return("", errNewJwt)
 			},
 		},
 		{
-			name:           "tm new jwt error",
-			tokens:         initTokens(),
-			session:        initSession(),
-			dayUntilExpire: 20,
-			expErr:         errNewJwt,
-			mockBehavior: func(d deps, tkns auth.Tokens, s core.Session, day int) {
-				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), tkns.Refresh.Token).Return(s, nil)
-				d.tm.EXPECT().NewJWT(s.UserId.String()).Return("", errNewJwt)
+			name:    "repo rotate session error",
+			rToken:  "oldToken",
+			session: initSession(),
+			expErr:  errRepo,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.tm.EXPECT().ValidateRefreshToken(s.ExpiresAt).Return(3600, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), s.UserId).Return(refreshUser(s), nil)
+				d.tm.EXPECT().NewJWT(accessClaims(refreshUser(s), s.FamilyId)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().RotateSession(gomock.Any(), s, newSession(s)).Return(errRepo)
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.mockBehavior(d, tt.tokens, tt.session, tt.dayUntilExpire)
-			tkns, err := authSrv.RefreshAccessToken(context.Background(), tt.tokens.Refresh.Token)
+			tt.mockBehavior(d, tt.rToken, tt.session)
+			tkns, err := authSrv.RefreshAccessToken(context.Background(), tt.rToken, testDevice)
 			if err != nil {
 				require.Empty(t, tkns)
 				require.Error(t, err)
 				require.EqualError(t, tt.expErr, err.Error())
+			} else {
+				require.NoError(t, tt.expErr)
+				require.Equal(t, tokens, tkns)
+			}
+		})
+	}
+}
+
+func Test_accessClaims(t *testing.T) {
+	familyId := uuid.New()
+	user := core.User{Id: uuid.New(), EmailVerified: true, Role: core.RoleModerator}
+
+	claims := accessClaims(user, familyId)
+
+	require.Equal(t, user.Id.String(), claims.Subject)
+	require.Equal(t, familyId.String(), claims.SessionId)
+	require.Equal(t, []string{core.RoleModerator}, claims.Roles)
+	require.True(t, claims.EmailVerified)
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	type mockBehavior func(d deps, userId, sessionId uuid.UUID)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "ok",
+			mockBehavior: func(d deps, userId, sessionId uuid.UUID) {
+				d.r.EXPECT().DeleteSessionById(gomock.Any(), userId, sessionId).Return(nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), sessionId).Return(nil)
+			},
+		},
+		{
+			name:   "session not found",
+			expErr: ErrSessionNotFound,
+			mockBehavior: func(d deps, userId, sessionId uuid.UUID) {
+				d.r.EXPECT().DeleteSessionById(gomock.Any(), userId, sessionId).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, userId, sessionId uuid.UUID) {
+				d.r.EXPECT().DeleteSessionById(gomock.Any(), userId, sessionId).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, sessionId := uuid.New(), uuid.New()
+			tt.mockBehavior(d, userId, sessionId)
+			err := authSrv.RevokeSession(context.Background(), userId, sessionId)
+			if tt.expErr != nil {
+				require.Error(t, err)
+				require.EqualError(t, tt.expErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_RevokeOtherSessions(t *testing.T) {
+	type mockBehavior func(d deps, rt string, s core.Session)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+
+	rotatedSession := initSession()
+	rotatedSession.Rotated = true
+	otherSessions := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tests := []struct {
+		name         string
+		session      core.Session
+		userId       uuid.UUID
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:    "ok",
+			session: initSession(),
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.r.EXPECT().DeleteSessionsExcept(gomock.Any(), s.UserId, s.FamilyId).Return(otherSessions, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), otherSessions[0], otherSessions[1]).Return(nil)
+			},
+		},
+		{
+			name:    "unknown refresh token",
+			session: initSession(),
+			expErr:  ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(core.Session{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:    "refresh token of another user",
+			session: initSession(),
+			userId:  uuid.New(),
+			expErr:  ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+			},
+		},
+		{
+			name:    "rotated refresh token",
+			session: rotatedSession,
+			expErr:  ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+			},
+		},
+		{
+			name:    "repo error",
+			session: initSession(),
+			expErr:  errRepo,
+			mockBehavior: func(d deps, rt string, s core.Session) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), rt).Return(s, nil)
+				d.r.EXPECT().DeleteSessionsExcept(gomock.Any(), s.UserId, s.FamilyId).Return(nil, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId := tt.userId
+			if userId == uuid.Nil {
+				userId = tt.session.UserId
+			}
+			tt.mockBehavior(d, tt.session.RefreshToken, tt.session)
+			err := authSrv.RevokeOtherSessions(context.Background(), userId, tt.session.RefreshToken)
+			if tt.expErr != nil {
+				require.Error(t, err)
+				require.EqualError(t, tt.expErr, err.Error())
 			} else {
 				require.NoError(t, err)
-				require.NotEqual(t, tt.tokens, tkns)
 			}
 		})
 	}
@@ -371,9 +948,11 @@ func TestAuthService_GetUser(t *testing.T) {
 	repo := mock_psql.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	es := mock_email.NewMockSender(ctrl)
-	d := initDeps(hash, repo, tm, es)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
 
-	authSrv := newAuthService(repo, hash, tm, es)
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
 	testUUID, _ := uuid.NewRandom()
 
 	tests := []struct {
@@ -412,3 +991,336 @@ func TestAuthService_GetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	type mockBehavior func(d deps, input core.PassResetCredentials, code core.CodeCredentials)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+
+	input := core.PassResetCredentials{Email: "Cheasezz@gmail.com", Code: "code", Password: "qwerty123456"}
+	codeQuery := core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypePassReset}
+	sessionIds := []uuid.UUID{uuid.New()}
+
+	tests := []struct {
+		name         string
+		code         core.CodeCredentials
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "ok",
+			code: core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypePassReset, ExpiresAt: time.Now().UTC().Add(time.Minute)},
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.h.EXPECT().Hash(input.Password).Return("hash", nil)
+				d.r.EXPECT().ResetPassword(gomock.Any(), code, "hash").Return(sessionIds, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), sessionIds[0]).Return(nil)
+			},
+		},
+		{
+			name:   "code not found",
+			expErr: ErrInvalidPassResetCode,
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo get code error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{}, errRepo)
+			},
+		},
+		{
+			name:   "code expired",
+			code:   core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypePassReset, ExpiresAt: time.Now().UTC().Add(-time.Minute)},
+			expErr: ErrPassResetCodeExpired,
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.r.EXPECT().DeleteCode(gomock.Any(), code).Return(nil)
+			},
+		},
+		{
+			name:   "hasher error",
+			code:   core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypePassReset, ExpiresAt: time.Now().UTC().Add(time.Minute)},
+			expErr: errHasher,
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.h.EXPECT().Hash(input.Password).Return("", errHasher)
+			},
+		},
+		{
+			name:   "code already used",
+			code:   core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypePassReset, ExpiresAt: time.Now().UTC().Add(time.Minute)},
+			expErr: ErrInvalidPassResetCode,
+			mockBehavior: func(d deps, input core.PassResetCredentials, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.h.EXPECT().Hash(input.Password).Return("hash", nil)
+				d.r.EXPECT().ResetPassword(gomock.Any(), code, "hash").Return(nil, pgx.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, input, tt.code)
+			err := authSrv.ResetPassword(context.Background(), input)
+			if tt.expErr != nil {
+				require.Error(t, err)
+				require.EqualError(t, tt.expErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	type mockBehavior func(d deps, code core.CodeCredentials)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+
+	input := core.EmailVerifyCredentials{Email: "Cheasezz@gmail.com", Code: "code"}
+	codeQuery := core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypeEmailVerify}
+	validCode := codeQuery
+	validCode.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	expiredCode := codeQuery
+	expiredCode.ExpiresAt = time.Now().UTC().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		code         core.CodeCredentials
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "ok",
+			code: validCode,
+			mockBehavior: func(d deps, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.r.EXPECT().VerifyEmail(gomock.Any(), code).Return(nil)
+			},
+		},
+		{
+			name:   "code not found",
+			expErr: ErrInvalidEmailVerifyCode,
+			mockBehavior: func(d deps, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "code expired",
+			code:   expiredCode,
+			expErr: ErrEmailVerifyCodeExpired,
+			mockBehavior: func(d deps, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.r.EXPECT().DeleteCode(gomock.Any(), code).Return(nil)
+			},
+		},
+		{
+			name:   "repo verify email error",
+			code:   validCode,
+			expErr: errRepo,
+			mockBehavior: func(d deps, code core.CodeCredentials) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				d.r.EXPECT().VerifyEmail(gomock.Any(), code).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, tt.code)
+			err := authSrv.VerifyEmail(context.Background(), input)
+			if tt.expErr != nil {
+				require.Error(t, err)
+				require.EqualError(t, tt.expErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_GenPassResetCode(t *testing.T) {
+	type mockBehavior func(d deps, email string)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+	lockoutErr := &LockoutError{RetryAfter: time.Minute}
+
+	tests := []struct {
+		name         string
+		email        string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:  "ok",
+			email: "Cheasezz@gmail.com",
+			mockBehavior: func(d deps, email string) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), email).Return(core.User{Email: email}, nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, code core.CodeCredentials) error {
+					require.Equal(t, email, code.Email)
+					require.Equal(t, core.CodeTypePassReset, code.CodeType)
+					return nil
+				})
+				d.es.EXPECT().Send(email, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:  "unknown email gets nothing silently",
+			email: "unknown@gmail.com",
+			mockBehavior: func(d deps, email string) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), email).Return(core.User{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "locked out",
+			email:  "Cheasezz@gmail.com",
+			expErr: lockoutErr,
+			mockBehavior: func(d deps, email string) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopePassReset, email, "").Return(lockoutErr)
+			},
+		},
+		{
+			name:   "repo error",
+			email:  "Cheasezz@gmail.com",
+			expErr: errRepo,
+			mockBehavior: func(d deps, email string) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), email).Return(core.User{}, errRepo)
+			},
+		},
+		{
+			name:   "email sender error",
+			email:  "Cheasezz@gmail.com",
+			expErr: errEmailSender,
+			mockBehavior: func(d deps, email string) {
+				d.ll.EXPECT().Check(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopePassReset, email, "").Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), email).Return(core.User{Email: email}, nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).Return(nil)
+				d.es.EXPECT().Send(email, gomock.Any()).Return(errEmailSender)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, tt.email)
+			err := authSrv.GenPassResetCode(context.Background(), tt.email)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_ResendEmailVerifyCode(t *testing.T) {
+	type mockBehavior func(d deps, user core.User)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	d := initDeps(hash, repo, tm, es, rv, ll)
+
+	authSrv := newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+	testUUID := uuid.New()
+
+	tests := []struct {
+		name         string
+		user         core.User
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "ok",
+			user: core.User{Id: testUUID, Email: "Cheasezz@gmail.com"},
+			mockBehavior: func(d deps, user core.User) {
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				d.r.EXPECT().DeleteCodesByType(gomock.Any(), user.Email, core.CodeTypeEmailVerify).Return(nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, code core.CodeCredentials) error {
+					require.Equal(t, user.Email, code.Email)
+					require.Equal(t, core.CodeTypeEmailVerify, code.CodeType)
+					require.Len(t, code.Code, 64)
+					return nil
+				})
+				d.es.EXPECT().Send(user.Email, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:   "already verified",
+			user:   core.User{Id: testUUID, Email: "Cheasezz@gmail.com", EmailVerified: true},
+			expErr: ErrEmailAlreadyVerified,
+			mockBehavior: func(d deps, user core.User) {
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+			},
+		},
+		{
+			name:   "email sender error",
+			user:   core.User{Id: testUUID, Email: "Cheasezz@gmail.com"},
+			expErr: errEmailSender,
+			mockBehavior: func(d deps, user core.User) {
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				d.r.EXPECT().DeleteCodesByType(gomock.Any(), user.Email, core.CodeTypeEmailVerify).Return(nil)
+				d.r.EXPECT().SetCode(gomock.Any(), gomock.Any()).Return(nil)
+				d.es.EXPECT().Send(user.Email, gomock.Any()).Return(errEmailSender)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, tt.user)
+			err := authSrv.ResendEmailVerifyCode(context.Background(), testUUID)
+			if tt.expErr != nil {
+				require.Error(t, err)
+				require.EqualError(t, tt.expErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/jackc/pgx/v5"
+)
+
+// Scopes of limited attempts. Lockout in one scope doesn't affect another.
+const (
+	limitScopeSignIn    = "signin"
+	limitScopePassReset = "passreset"
+	// Current password check of signed in user before credentials change.
+	limitScopeReauth = "reauth"
+)
+
+var ErrTooManyAttempts = core.NewError(core.ErrRateLimited, "too_many_attempts", "too many attempts, try again later")
+
+// LockoutError is returned for attempt made while email or ip is locked.
+// errors.Is(err, ErrTooManyAttempts) is true for it.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+func (e *LockoutError) RetryIn() time.Duration {
+	return e.RetryAfter
+}
+
+// LoginLimiter counts failed attempts per email and per client ip
+// and locks them with exponential backoff.
+type LoginLimiter interface {
+	Check(ctx context.Context, scope, email, ip string) error
+	Fail(ctx context.Context, scope, email, ip string) error
+	Succeed(ctx context.Context, scope, email string) error
+	Refund(ctx context.Context, scope, ip string) error
+	GetLockouts(ctx context.Context) ([]core.Lockout, error)
+	ClearLockout(ctx context.Context, key string) error
+}
+
+type LockoutLimiter struct {
+	repo psql.Lockout
+	cfg  config.Lockout
+}
+
+func newLockoutLimiter(r psql.Lockout, cfg config.Lockout) *LockoutLimiter {
+	return &LockoutLimiter{repo: r, cfg: cfg}
+}
+
+// Return *LockoutError if email or ip is locked in scope.
+func (l *LockoutLimiter) Check(ctx context.Context, scope, email, ip string) error {
+	now := time.Now().UTC()
+
+	var retryAfter time.Duration
+	for _, key := range limitKeys(scope, email, ip) {
+		lockout, err := l.repo.GetLockout(ctx, key)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		if wait := lockout.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Count failed attempt of email and ip. Key that reached its max attempts
+// is locked for base delay doubled with every next failure.
+func (l *LockoutLimiter) Fail(ctx context.Context, scope, email, ip string) error {
+	now := time.Now().UTC()
+
+	for _, key := range limitKeys(scope, email, ip) {
+		lockout, err := l.repo.AddFailure(ctx, key, now, l.cfg.Window)
+		if err != nil {
+			return err
+		}
+
+		maxAttempts := l.cfg.MaxAttempts
+		if strings.HasPrefix(key, scope+":ip:") {
+			maxAttempts = l.cfg.MaxAttemptsPerIP
+		}
+		if delay := l.lockDelay(lockout.Failures, maxAttempts); delay > 0 {
+			if err := l.repo.Lock(ctx, key, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Forget failed attempts of email. Ip counter stays, successful login
+// into own account must not unlock ip that guesses passwords of others.
+func (l *LockoutLimiter) Succeed(ctx context.Context, scope, email string) error {
+	return l.repo.DeleteLockout(ctx, emailKey(scope, email))
+}
+
+// Take back failure of ip counted in advance by attempt that succeeded.
+// Lock set by that failure is lifted unless remaining failures keep ip locked.
+func (l *LockoutLimiter) Refund(ctx context.Context, scope, ip string) error {
+	if ip == "" {
+		return nil
+	}
+
+	key := ipKey(scope, ip)
+	lockout, err := l.repo.RemoveFailure(ctx, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	if lockout.LockedUntil.After(now) && l.lockDelay(lockout.Failures, l.cfg.MaxAttemptsPerIP) == 0 {
+		return l.repo.Lock(ctx, key, now)
+	}
+	return nil
+}
+
+// Return currently locked keys.
+func (l *LockoutLimiter) GetLockouts(ctx context.Context) ([]core.Lockout, error) {
+	return l.repo.GetLockouts(ctx, time.Now().UTC())
+}
+
+// Remove lockout and failed attempts of key.
+func (l *LockoutLimiter) ClearLockout(ctx context.Context, key string) error {
+	return l.repo.DeleteLockout(ctx, key)
+}
+
+func (l *LockoutLimiter) lockDelay(failures, maxAttempts int) time.Duration {
+	if maxAttempts <= 0 || failures < maxAttempts {
+		return 0
+	}
+
+	delay := l.cfg.BaseDelay
+	for i := maxAttempts; i < failures && delay < l.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if l.cfg.MaxDelay > 0 && delay > l.cfg.MaxDelay {
+		delay = l.cfg.MaxDelay
+	}
+	return delay
+}
+
+func limitKeys(scope, email, ip string) []string {
+	keys := []string{emailKey(scope, email)}
+	if ip != "" {
+		keys = append(keys, ipKey(scope, ip))
+	}
+	return keys
+}
+
+func ipKey(scope, ip string) string {
+	return scope + ":ip:" + ip
+}
+
+// Emails are case insensitive in db, so is the key.
+func emailKey(scope, email string) string {
+	return scope + ":email:" + strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var testLockoutCfg = config.Lockout{
+	MaxAttempts:      3,
+	MaxAttemptsPerIP: 5,
+	BaseDelay:        time.Minute,
+	MaxDelay:         10 * time.Minute,
+	Window:           time.Hour,
+}
+
+func TestLockoutLimiter_Email(t *testing.T) {
+	ctx := context.Background()
+	limiter := newLockoutLimiter(memory.NewLockoutRepo(), testLockoutCfg)
+
+	for i := 0; i < testLockoutCfg.MaxAttempts-1; i++ {
+		require.NoError(t, limiter.Fail(ctx, limitScopeSignIn, "user@example.com", "10.0.0.1"))
+		require.NoError(t, limiter.Check(ctx, limitScopeSignIn, "user@example.com", "10.0.0.1"))
+	}
+	require.NoError(t, limiter.Fail(ctx, limitScopeSignIn, "user@example.com", "10.0.0.1"))
+
+	// Email is case insensitive and locked from any ip.
+	err := limiter.Check(ctx, limitScopeSignIn, "USER@example.com", "10.0.0.2")
+	require.ErrorIs(t, err, ErrTooManyAttempts)
+	var lockoutErr *LockoutError
+	require.True(t, errors.As(err, &lockoutErr))
+	require.InDelta(t, time.Minute.Seconds(), lockoutErr.RetryAfter.Seconds(), 1)
+
+	// Other scopes and emails aren't affected.
+	require.NoError(t, limiter.Check(ctx, limitScopePassReset, "user@example.com", "10.0.0.1"))
+	require.NoError(t, limiter.Check(ctx, limitScopeSignIn, "other@example.com", "10.0.0.1"))
+
+	lockouts, err := limiter.GetLockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	require.Equal(t, "signin:email:user@example.com", lockouts[0].Key)
+
+	require.NoError(t, limiter.ClearLockout(ctx, lockouts[0].Key))
+	require.NoError(t, limiter.Check(ctx, limitScopeSignIn, "user@example.com", "10.0.0.1"))
+}
+
+func TestLockoutLimiter_IP(t *testing.T) {
+	ctx := context.Background()
+	limiter := newLockoutLimiter(memory.NewLockoutRepo(), testLockoutCfg)
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, email := range emails {
+		require.NoError(t, limiter.Fail(ctx, limitScopeSignIn, email, "10.0.0.1"))
+	}
+
+	require.ErrorIs(t, limiter.Check(ctx, limitScopeSignIn, "f@example.com", "10.0.0.1"), ErrTooManyAttempts)
+	require.NoError(t, limiter.Check(ctx, limitScopeSignIn, "f@example.com", "10.0.0.2"))
+
+	// Success resets email counter only.
+	require.NoError(t, limiter.Succeed(ctx, limitScopeSignIn, "a@example.com"))
+	require.ErrorIs(t, limiter.Check(ctx, limitScopeSignIn, "a@example.com", "10.0.0.1"), ErrTooManyAttempts)
+}
+
+func TestLockoutLimiter_Refund(t *testing.T) {
+	ctx := context.Background()
+	limiter := newLockoutLimiter(memory.NewLockoutRepo(), testLockoutCfg)
+
+	// Nothing to take back
+	require.NoError(t, limiter.Refund(ctx, limitScopeMFA, "10.0.0.1"))
+
+	for i := 0; i < testLockoutCfg.MaxAttemptsPerIP; i++ {
+		require.NoError(t, limiter.Fail(ctx, limitScopeMFA, fmt.Sprintf("u%d@example.com", i), "10.0.0.1"))
+	}
+	require.ErrorIs(t, limiter.Check(ctx, limitScopeMFA, "other@example.com", "10.0.0.1"), ErrTooManyAttempts)
+
+	// Last failure turned out success, lock set by it is lifted
+	require.NoError(t, limiter.Refund(ctx, limitScopeMFA, "10.0.0.1"))
+	require.NoError(t, limiter.Check(ctx, limitScopeMFA, "other@example.com", "10.0.0.1"))
+
+	// Failures above max attempts keep ip locked
+	require.NoError(t, limiter.Fail(ctx, limitScopeMFA, "a@example.com", "10.0.0.1"))
+	require.NoError(t, limiter.Fail(ctx, limitScopeMFA, "b@example.com", "10.0.0.1"))
+	require.NoError(t, limiter.Refund(ctx, limitScopeMFA, "10.0.0.1"))
+	require.ErrorIs(t, limiter.Check(ctx, limitScopeMFA, "other@example.com", "10.0.0.1"), ErrTooManyAttempts)
+}
+
+func TestLockoutLimiter_lockDelay(t *testing.T) {
+	limiter := newLockoutLimiter(memory.NewLockoutRepo(), testLockoutCfg)
+
+	tests := []struct {
+		name     string
+		failures int
+		expDelay time.Duration
+	}{
+		{name: "below max attempts", failures: 2},
+		{name: "max attempts", failures: 3, expDelay: time.Minute},
+		{name: "doubled", failures: 4, expDelay: 2 * time.Minute},
+		{name: "doubled twice", failures: 5, expDelay: 4 * time.Minute},
+		{name: "capped", failures: 10, expDelay: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expDelay, limiter.lockDelay(tt.failures, testLockoutCfg.MaxAttempts))
+		})
+	}
+}
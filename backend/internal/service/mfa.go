@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	limitScopeMFA     = "mfa"
+	recoveryCodesNum  = 10
+	recoveryCodeBytes = 5
+)
+
+var (
+	ErrMFARequired        = core.NewError(core.ErrUnauthorized, "mfa_required", "two-factor authentication required")
+	ErrInvalidMFACode     = core.NewError(core.ErrUnauthorized, "invalid_mfa_code", "invalid two-factor authentication code")
+	ErrTOTPAlreadyEnabled = core.NewError(core.ErrConflict, "totp_already_enabled", "two-factor authentication already enabled")
+	ErrTOTPNotEnrolled    = core.NewError(core.ErrConflict, "totp_not_enrolled", "two-factor authentication isn't enrolled")
+	ErrTOTPNotEnabled     = core.NewError(core.ErrConflict, "totp_not_enabled", "two-factor authentication isn't enabled")
+	ErrMFATokenUsed       = core.NewError(core.ErrUnauthorized, "mfa_token_used", "mfa pending token is already used")
+)
+
+// MFARequiredError is returned by SignIn instead of tokens for user with TOTP enabled.
+// Token is mfa pending token, it is exchanged for tokens by VerifyMFA.
+// errors.Is(err, ErrMFARequired) is true for it.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Unwrap() error {
+	return ErrMFARequired
+}
+
+// Generate new TOTP secret of user. It isn't used for sign in
+// until confirmed with first code by ConfirmTOTP.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userId uuid.UUID) (core.TOTPEnrollment, error) {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	if user.TOTPEnabled {
+		return core.TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := s.tokenManager.NewTOTPSecret()
+	if err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	if err := s.repo.SetTOTPSecret(ctx, userId, secret); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+		}
+		return core.TOTPEnrollment{}, err
+	}
+
+	return core.TOTPEnrollment{
+		Secret: secret,
+		URI:    s.tokenManager.TOTPURI(user.Email, secret),
+	}, nil
+}
+
+// Check first code of enrolled secret and enable TOTP. Code is accepted once,
+// it can't be reused as second factor. Return recovery codes,
+// they are shown only once, only hashes are stored.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+	step, ok := s.tokenManager.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	rawCodes := make([]string, 0, recoveryCodesNum)
+	codes := make([]core.CodeCredentials, 0, recoveryCodesNum)
+	for i := 0; i < recoveryCodesNum; i++ {
+		raw, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		rawCodes = append(rawCodes, raw)
+		codes = append(codes, core.CodeCredentials{
+			Email:    user.Email,
+			Code:     hashRecoveryCode(raw),
+			CodeType: core.CodeTypeMFARecovery,
+		})
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetTOTPStep(ctx, userId, step); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		if err := s.repo.EnableTOTP(ctx, userId, codes); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTOTPAlreadyEnabled
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rawCodes, nil
+}
+
+// Exchange mfa pending token and TOTP or recovery code for tokens.
+// Pending token is exchanged once, failed attempt doesn't use it up.
+// Attempts are counted per email and ip, locked ones get *LockoutError.
+func (s *AuthService) VerifyMFA(ctx context.Context, input core.MFACredentials, device core.Device) (auth.Tokens, error) {
+	claims, err := s.tokenManager.ParseMFA(input.MFAToken)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return auth.Tokens{}, auth.ErrTokenInvalid
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+	if !user.TOTPEnabled {
+		return auth.Tokens{}, ErrTOTPNotEnabled
+	}
+	// Banned or deletion scheduled after mfa token was issued.
+	if user.Banned() {
+		return auth.Tokens{}, ErrUserBanned
+	}
+	if user.DeletionScheduled() {
+		return auth.Tokens{}, ErrAccountPendingDeletion
+	}
+
+	if err := s.countMFAAttempt(ctx, user.Email, device.IP); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	// Token and code are used up only together with session written.
+	var tokens auth.Tokens
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := s.repo.UseMFAToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0).UTC())
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrMFATokenUsed
+			}
+			return err
+		}
+		if err := s.checkSecondFactor(ctx, user, input.Code); err != nil {
+			return err
+		}
+
+		tokens, err = s.createSession(ctx, user, device)
+		return err
+	})
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	if err := s.forgetMFAAttempt(ctx, user.Email, device.IP); err != nil {
+		return auth.Tokens{}, err
+	}
+	return tokens, nil
+}
+
+// Check TOTP or recovery code of user and disable TOTP.
+func (s *AuthService) DisableTOTP(ctx context.Context, userId uuid.UUID, code string, device core.Device) error {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := s.countMFAAttempt(ctx, user.Email, device.IP); err != nil {
+		return err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkSecondFactor(ctx, user, code); err != nil {
+			return err
+		}
+		return s.repo.DisableTOTP(ctx, userId)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.forgetMFAAttempt(ctx, user.Email, device.IP)
+}
+
+// Count second factor attempt as failed before code is checked, so concurrent
+// guesses can't pass lockout check together. Succeeded attempt is forgotten
+// by forgetMFAAttempt.
+func (s *AuthService) countMFAAttempt(ctx context.Context, email, ip string) error {
+	if err := s.limiter.Check(ctx, limitScopeMFA, email, ip); err != nil {
+		return err
+	}
+	return s.limiter.Fail(ctx, limitScopeMFA, email, ip)
+}
+
+// Forget attempts of email and take back ip failure counted in advance,
+// so successful logins don't lock ip shared by many users.
+func (s *AuthService) forgetMFAAttempt(ctx context.Context, email, ip string) error {
+	if err := s.limiter.Succeed(ctx, limitScopeMFA, email); err != nil {
+		return err
+	}
+	return s.limiter.Refund(ctx, limitScopeMFA, ip)
+}
+
+// Accept TOTP code of time step after last accepted one or consume one of
+// recovery codes. Accepted code can't be used again.
+func (s *AuthService) checkSecondFactor(ctx context.Context, user core.User, code string) error {
+	if step, ok := s.tokenManager.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep); ok {
+		err := s.repo.SetTOTPStep(ctx, user.Id, step)
+		// Same code accepted concurrently.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	err := s.repo.ConsumeCode(ctx, core.CodeCredentials{
+		Email:    user.Email,
+		Code:     hashRecoveryCode(code),
+		CodeType: core.CodeTypeMFARecovery,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+// Recovery code looks like "1a2b3-c4d5e".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := fmt.Sprintf("%x", b)
+	return raw[:len(raw)/2] + "-" + raw[len(raw)/2:], nil
+}
+
+// Recovery codes are random, so fast hash is enough to keep them unusable from db dump.
+// Case, dashes and spaces are ignored.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(normalized)))
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/memory"
+	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	mock_auth "github.com/Cheasezz/anSpace/backend/pkg/auth/mocks"
+	mock_email "github.com/Cheasezz/anSpace/backend/pkg/email/mocks"
+	mock_hash "github.com/Cheasezz/anSpace/backend/pkg/hasher/mocks"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func initMFADeps(t *testing.T) (deps, *AuthService) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+
+	return initDeps(hash, repo, tm, es, rv, ll), newAuthService(repo, newTxMock(ctrl), hash, tm, es, rv, ll, testMaxSessions)
+}
+
+func TestAuth_EnrollTOTP(t *testing.T) {
+	type mockBehavior func(d deps, userId uuid.UUID)
+
+	d, authSrv := initMFADeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com"}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().NewTOTPSecret().Return("SECRET", nil)
+				d.r.EXPECT().SetTOTPSecret(gomock.Any(), userId, "SECRET").Return(nil)
+				d.tm.EXPECT().TOTPURI(user.Email, "SECRET").Return("otpauth://totp/anspace:user@example.com?secret=SECRET")
+			},
+		},
+		{
+			name:   "already enabled",
+			expErr: ErrTOTPAlreadyEnabled,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, TOTPEnabled: true}, nil)
+			},
+		},
+		{
+			name:   "enabled concurrently",
+			expErr: ErrTOTPAlreadyEnabled,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().NewTOTPSecret().Return("SECRET", nil)
+				d.r.EXPECT().SetTOTPSecret(gomock.Any(), userId, "SECRET").Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo get user error",
+			expErr: errRepoGetUserById,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{}, errRepoGetUserById)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, testUUID)
+
+			enrollment, err := authSrv.EnrollTOTP(context.Background(), testUUID)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, enrollment)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "SECRET", enrollment.Secret)
+				require.Equal(t, "otpauth://totp/anspace:user@example.com?secret=SECRET", enrollment.URI)
+			}
+		})
+	}
+}
+
+func TestAuth_ConfirmTOTP(t *testing.T) {
+	type mockBehavior func(d deps, userId uuid.UUID)
+
+	d, authSrv := initMFADeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com", TOTPSecret: "SECRET"}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), userId, int64(57)).Return(nil)
+				d.r.EXPECT().EnableTOTP(gomock.Any(), userId, gomock.Len(recoveryCodesNum)).Return(nil)
+			},
+		},
+		{
+			name:   "not enrolled",
+			expErr: ErrTOTPNotEnrolled,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId}, nil)
+			},
+		},
+		{
+			name:   "already enabled",
+			expErr: ErrTOTPAlreadyEnabled,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, TOTPSecret: "SECRET", TOTPEnabled: true}, nil)
+			},
+		},
+		{
+			name:   "invalid code",
+			expErr: ErrInvalidMFACode,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(0), false)
+			},
+		},
+		{
+			name:   "code accepted concurrently",
+			expErr: ErrInvalidMFACode,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), userId, int64(57)).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo enable error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), userId, int64(57)).Return(nil)
+				d.r.EXPECT().EnableTOTP(gomock.Any(), userId, gomock.Any()).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, testUUID)
+
+			codes, err := authSrv.ConfirmTOTP(context.Background(), testUUID, "123456")
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Nil(t, codes)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, codes, recoveryCodesNum)
+				require.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, codes[0])
+				require.NotEqual(t, codes[0], codes[1])
+			}
+		})
+	}
+}
+
+func TestAuth_VerifyMFA(t *testing.T) {
+	type mockBehavior func(d deps, input core.MFACredentials)
+	tokens := initTokens()
+
+	d, authSrv := initMFADeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com", TOTPSecret: "SECRET", TOTPEnabled: true, TOTPLastStep: 56}
+	session := core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt}
+	mfaClaims := auth.MFAClaims{}
+	mfaClaims.Subject = testUUID.String()
+	mfaClaims.Id = "jti"
+	mfaClaims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	mfaExpiresAt := time.Unix(mfaClaims.ExpiresAt, 0).UTC()
+	recovery := core.CodeCredentials{Email: user.Email, Code: hashRecoveryCode("1a2b3-c4d5e"), CodeType: core.CodeTypeMFARecovery}
+
+	countAttempt := func(d deps) {
+		d.ll.EXPECT().Check(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+		d.ll.EXPECT().Fail(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+	}
+
+	tests := []struct {
+		name         string
+		input        core.MFACredentials
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:  "OK with TOTP code",
+			input: core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", i.Code, int64(56)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), testUUID, int64(57)).Return(nil)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(session), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeMFA, user.Email).Return(nil)
+				d.ll.EXPECT().Refund(gomock.Any(), limitScopeMFA, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:  "OK with recovery code",
+			input: core.MFACredentials{MFAToken: "mfaToken", Code: "1A2B3C4D5E"},
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", i.Code, int64(56)).Return(int64(0), false)
+				d.r.EXPECT().ConsumeCode(gomock.Any(), recovery).Return(nil)
+				d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+				d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+				d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(session), testMaxSessions).Return(nil, nil)
+				d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeMFA, user.Email).Return(nil)
+				d.ll.EXPECT().Refund(gomock.Any(), limitScopeMFA, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:   "invalid code",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrInvalidMFACode,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", i.Code, int64(56)).Return(int64(0), false)
+				d.r.EXPECT().ConsumeCode(gomock.Any(), gomock.Any()).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "code accepted concurrently",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrInvalidMFACode,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", i.Code, int64(56)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), testUUID, int64(57)).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "mfa token already used",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrMFATokenUsed,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "banned after mfa token issued",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrUserBanned,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				bannedUser := user
+				bannedUser.BannedAt = time.Now().Add(-time.Minute)
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(bannedUser, nil)
+			},
+		},
+		{
+			name:   "locked out",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrTooManyAttempts,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(&LockoutError{})
+			},
+		},
+		{
+			name:   "expired mfa token",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: auth.ErrTokenExpired,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(auth.MFAClaims{}, auth.ErrTokenExpired)
+			},
+		},
+		{
+			name:   "totp disabled after sign in",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: ErrTOTPNotEnabled,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(core.User{Id: testUUID}, nil)
+			},
+		},
+		{
+			name:   "repo consume code error",
+			input:  core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expErr: errRepo,
+			mockBehavior: func(d deps, i core.MFACredentials) {
+				d.tm.EXPECT().ParseMFA(i.MFAToken).Return(mfaClaims, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+				countAttempt(d)
+				d.r.EXPECT().UseMFAToken(gomock.Any(), "jti", mfaExpiresAt).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", i.Code, int64(56)).Return(int64(0), false)
+				d.r.EXPECT().ConsumeCode(gomock.Any(), gomock.Any()).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, tt.input)
+
+			tkns, err := authSrv.VerifyMFA(context.Background(), tt.input, testDevice)
+			if tt.expErr != nil {
+				require.True(t, errors.Is(err, tt.expErr), err)
+				require.Empty(t, tkns)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tokens, tkns)
+			}
+		})
+	}
+}
+
+// Successful attempts counted in advance don't lock ip shared by many users.
+func TestAuth_VerifyMFA_sharedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	repo := mock_psql.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	limiter := newLockoutLimiter(memory.NewLockoutRepo(), testLockoutCfg)
+	authSrv := newAuthService(repo, newTxMock(ctrl), mock_hash.NewMockPasswordHasher(ctrl), tm, mock_email.NewMockSender(ctrl), rv, limiter, testMaxSessions)
+	tokens := initTokens()
+
+	for i := 0; i < testLockoutCfg.MaxAttemptsPerIP+2; i++ {
+		user := core.User{Id: uuid.New(), Email: fmt.Sprintf("user%d@example.com", i), TOTPSecret: "SECRET", TOTPEnabled: true}
+		claims := auth.MFAClaims{}
+		claims.Subject = user.Id.String()
+		claims.Id = fmt.Sprintf("jti%d", i)
+
+		tm.EXPECT().ParseMFA("mfaToken").Return(claims, nil)
+		repo.EXPECT().GetUserById(gomock.Any(), user.Id).Return(user, nil)
+		repo.EXPECT().UseMFAToken(gomock.Any(), claims.Id, gomock.Any()).Return(nil)
+		tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(57), true)
+		repo.EXPECT().SetTOTPStep(gomock.Any(), user.Id, int64(57)).Return(nil)
+		tm.EXPECT().NewJWT(gomock.Any()).Return(tokens.Access.Token, nil)
+		tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+		repo.EXPECT().SetSession(gomock.Any(), gomock.Any(), testMaxSessions).Return(nil, nil)
+		rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+
+		_, err := authSrv.VerifyMFA(context.Background(), core.MFACredentials{MFAToken: "mfaToken", Code: "123456"}, testDevice)
+		require.NoError(t, err, "attempt %d", i)
+	}
+
+	require.NoError(t, limiter.Check(context.Background(), limitScopeMFA, "other@example.com", testDevice.IP))
+}
+
+func TestAuth_DisableTOTP(t *testing.T) {
+	type mockBehavior func(d deps, userId uuid.UUID)
+
+	d, authSrv := initMFADeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com", TOTPSecret: "SECRET", TOTPEnabled: true}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(57), true)
+				d.r.EXPECT().SetTOTPStep(gomock.Any(), userId, int64(57)).Return(nil)
+				d.r.EXPECT().DisableTOTP(gomock.Any(), userId).Return(nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeMFA, user.Email).Return(nil)
+				d.ll.EXPECT().Refund(gomock.Any(), limitScopeMFA, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:   "not enabled",
+			expErr: ErrTOTPNotEnabled,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId}, nil)
+			},
+		},
+		{
+			name:   "invalid code",
+			expErr: ErrInvalidMFACode,
+			mockBehavior: func(d deps, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeMFA, user.Email, testDevice.IP).Return(nil)
+				d.tm.EXPECT().ValidateTOTP("SECRET", "123456", int64(0)).Return(int64(0), false)
+				d.r.EXPECT().ConsumeCode(gomock.Any(), gomock.Any()).Return(pgx.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, testUUID)
+
+			err := authSrv.DisableTOTP(context.Background(), testUUID, "123456", testDevice)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_hashRecoveryCode(t *testing.T) {
+	code, err := newRecoveryCode()
+	require.NoError(t, err)
+
+	require.Len(t, hashRecoveryCode(code), 64)
+	require.Equal(t, hashRecoveryCode("1a2b3-c4d5e"), hashRecoveryCode(" 1A2B3C4D5E"))
+	require.NotEqual(t, hashRecoveryCode("1a2b3-c4d5e"), hashRecoveryCode("1a2b3-c4d5f"))
+}
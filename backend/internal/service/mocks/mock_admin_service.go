@@ -0,0 +1,143 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/admin.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/admin.go -destination=internal/service/mocks/mock_admin_service.go
+//
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	context "context"
+	reflect "reflect"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAdmin is a mock of Admin interface.
+type MockAdmin struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdminMockRecorder
+}
+
+// MockAdminMockRecorder is the mock recorder for MockAdmin.
+type MockAdminMockRecorder struct {
+	mock *MockAdmin
+}
+
+// NewMockAdmin creates a new mock instance.
+func NewMockAdmin(ctrl *gomock.Controller) *MockAdmin {
+	mock := &MockAdmin{ctrl: ctrl}
+	mock.recorder = &MockAdminMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdmin) EXPECT() *MockAdminMockRecorder {
+	return m.recorder
+}
+
+// BanUser mocks base method.
+func (m *MockAdmin) BanUser(ctx context.Context, actor core.Actor, userId uuid.UUID, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BanUser", ctx, actor, userId, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BanUser indicates an expected call of BanUser.
+func (mr *MockAdminMockRecorder) BanUser(ctx, actor, userId, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BanUser", reflect.TypeOf((*MockAdmin)(nil).BanUser), ctx, actor, userId, reason)
+}
+
+// ClearLockout mocks base method.
+func (m *MockAdmin) ClearLockout(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearLockout", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearLockout indicates an expected call of ClearLockout.
+func (mr *MockAdminMockRecorder) ClearLockout(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearLockout", reflect.TypeOf((*MockAdmin)(nil).ClearLockout), ctx, key)
+}
+
+// GetLockouts mocks base method.
+func (m *MockAdmin) GetLockouts(ctx context.Context) ([]core.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLockouts", ctx)
+	ret0, _ := ret[0].([]core.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLockouts indicates an expected call of GetLockouts.
+func (mr *MockAdminMockRecorder) GetLockouts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLockouts", reflect.TypeOf((*MockAdmin)(nil).GetLockouts), ctx)
+}
+
+// LogoutUser mocks base method.
+func (m *MockAdmin) LogoutUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutUser", ctx, actor, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutUser indicates an expected call of LogoutUser.
+func (mr *MockAdminMockRecorder) LogoutUser(ctx, actor, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutUser", reflect.TypeOf((*MockAdmin)(nil).LogoutUser), ctx, actor, userId)
+}
+
+// SearchUsers mocks base method.
+func (m *MockAdmin) SearchUsers(ctx context.Context, search core.UserSearch) ([]core.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, search)
+	ret0, _ := ret[0].([]core.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockAdminMockRecorder) SearchUsers(ctx, search any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockAdmin)(nil).SearchUsers), ctx, search)
+}
+
+// SetRole mocks base method.
+func (m *MockAdmin) SetRole(ctx context.Context, actor core.Actor, userId uuid.UUID, role string) (core.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, actor, userId, role)
+	ret0, _ := ret[0].(core.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockAdminMockRecorder) SetRole(ctx, actor, userId, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockAdmin)(nil).SetRole), ctx, actor, userId, role)
+}
+
+// UnbanUser mocks base method.
+func (m *MockAdmin) UnbanUser(ctx context.Context, actor core.Actor, userId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnbanUser", ctx, actor, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnbanUser indicates an expected call of UnbanUser.
+func (mr *MockAdminMockRecorder) UnbanUser(ctx, actor, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnbanUser", reflect.TypeOf((*MockAdmin)(nil).UnbanUser), ctx, actor, userId)
+}
@@ -42,6 +42,50 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// ConfirmTOTP mocks base method.
+func (m *MockAuth) ConfirmTOTP(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, userId, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockAuthMockRecorder) ConfirmTOTP(ctx, userId, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockAuth)(nil).ConfirmTOTP), ctx, userId, code)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockAuth) DisableTOTP(ctx context.Context, userId uuid.UUID, code string, device core.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userId, code, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockAuthMockRecorder) DisableTOTP(ctx, userId, code, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockAuth)(nil).DisableTOTP), ctx, userId, code, device)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockAuth) EnrollTOTP(ctx context.Context, userId uuid.UUID) (core.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, userId)
+	ret0, _ := ret[0].(core.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockAuthMockRecorder) EnrollTOTP(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockAuth)(nil).EnrollTOTP), ctx, userId)
+}
+
 // GenPassResetCode mocks base method.
 func (m *MockAuth) GenPassResetCode(ctx context.Context, email string) error {
 	m.ctrl.T.Helper()
@@ -56,6 +100,21 @@ func (mr *MockAuthMockRecorder) GenPassResetCode(ctx, email any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenPassResetCode", reflect.TypeOf((*MockAuth)(nil).GenPassResetCode), ctx, email)
 }
 
+// GetSessions mocks base method.
+func (m *MockAuth) GetSessions(ctx context.Context, userId uuid.UUID) ([]core.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userId)
+	ret0, _ := ret[0].([]core.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockAuthMockRecorder) GetSessions(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockAuth)(nil).GetSessions), ctx, userId)
+}
+
 // GetUser mocks base method.
 func (m *MockAuth) GetUser(ctx context.Context, userId uuid.UUID) (core.User, error) {
 	m.ctrl.T.Helper()
@@ -87,46 +146,131 @@ func (mr *MockAuthMockRecorder) LogOut(ctx, refreshToken any) *gomock.Call {
 }
 
 // RefreshAccessToken mocks base method.
-func (m *MockAuth) RefreshAccessToken(ctx context.Context, refreshToken string) (auth.Tokens, error) {
+func (m *MockAuth) RefreshAccessToken(ctx context.Context, refreshToken string, device core.Device) (auth.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshAccessToken", ctx, refreshToken)
+	ret := m.ctrl.Call(m, "RefreshAccessToken", ctx, refreshToken, device)
 	ret0, _ := ret[0].(auth.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RefreshAccessToken indicates an expected call of RefreshAccessToken.
-func (mr *MockAuthMockRecorder) RefreshAccessToken(ctx, refreshToken any) *gomock.Call {
+func (mr *MockAuthMockRecorder) RefreshAccessToken(ctx, refreshToken, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshAccessToken", reflect.TypeOf((*MockAuth)(nil).RefreshAccessToken), ctx, refreshToken, device)
+}
+
+// ResendEmailVerifyCode mocks base method.
+func (m *MockAuth) ResendEmailVerifyCode(ctx context.Context, userId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendEmailVerifyCode", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendEmailVerifyCode indicates an expected call of ResendEmailVerifyCode.
+func (mr *MockAuthMockRecorder) ResendEmailVerifyCode(ctx, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshAccessToken", reflect.TypeOf((*MockAuth)(nil).RefreshAccessToken), ctx, refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendEmailVerifyCode", reflect.TypeOf((*MockAuth)(nil).ResendEmailVerifyCode), ctx, userId)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(ctx context.Context, input core.PassResetCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), ctx, input)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockAuth) RevokeOtherSessions(ctx context.Context, userId uuid.UUID, refreshToken string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, userId, refreshToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockAuthMockRecorder) RevokeOtherSessions(ctx, userId, refreshToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockAuth)(nil).RevokeOtherSessions), ctx, userId, refreshToken)
+}
+
+// RevokeSession mocks base method.
+func (m *MockAuth) RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userId, sessionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockAuthMockRecorder) RevokeSession(ctx, userId, sessionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockAuth)(nil).RevokeSession), ctx, userId, sessionId)
 }
 
 // SignIn mocks base method.
-func (m *MockAuth) SignIn(ctx context.Context, signIn core.AuthCredentials) (auth.Tokens, error) {
+func (m *MockAuth) SignIn(ctx context.Context, signIn core.AuthCredentials, device core.Device) (auth.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignIn", ctx, signIn)
+	ret := m.ctrl.Call(m, "SignIn", ctx, signIn, device)
 	ret0, _ := ret[0].(auth.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignIn indicates an expected call of SignIn.
-func (mr *MockAuthMockRecorder) SignIn(ctx, signIn any) *gomock.Call {
+func (mr *MockAuthMockRecorder) SignIn(ctx, signIn, device any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuth)(nil).SignIn), ctx, signIn)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuth)(nil).SignIn), ctx, signIn, device)
 }
 
 // SignUp mocks base method.
-func (m *MockAuth) SignUp(ctx context.Context, signUp core.AuthCredentials) (auth.Tokens, error) {
+func (m *MockAuth) SignUp(ctx context.Context, signUp core.AuthCredentials, device core.Device) (auth.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignUp", ctx, signUp)
+	ret := m.ctrl.Call(m, "SignUp", ctx, signUp, device)
 	ret0, _ := ret[0].(auth.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignUp indicates an expected call of SignUp.
-func (mr *MockAuthMockRecorder) SignUp(ctx, signUp any) *gomock.Call {
+func (mr *MockAuthMockRecorder) SignUp(ctx, signUp, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockAuth)(nil).SignUp), ctx, signUp, device)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuth) VerifyEmail(ctx context.Context, input core.EmailVerifyCredentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthMockRecorder) VerifyEmail(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuth)(nil).VerifyEmail), ctx, input)
+}
+
+// VerifyMFA mocks base method.
+func (m *MockAuth) VerifyMFA(ctx context.Context, input core.MFACredentials, device core.Device) (auth.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFA", ctx, input, device)
+	ret0, _ := ret[0].(auth.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyMFA indicates an expected call of VerifyMFA.
+func (mr *MockAuthMockRecorder) VerifyMFA(ctx, input, device any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockAuth)(nil).SignUp), ctx, signUp)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFA", reflect.TypeOf((*MockAuth)(nil).VerifyMFA), ctx, input, device)
 }
@@ -0,0 +1,149 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/oauth.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/oauth.go -destination=internal/service/mocks/mock_oauth_service.go
+//
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	context "context"
+	reflect "reflect"
+
+	core "github.com/Cheasezz/anSpace/backend/internal/core"
+	auth "github.com/Cheasezz/anSpace/backend/pkg/auth"
+	oauth "github.com/Cheasezz/anSpace/backend/pkg/oauth"
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth is a mock of OAuth interface.
+type MockOAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthMockRecorder
+}
+
+// MockOAuthMockRecorder is the mock recorder for MockOAuth.
+type MockOAuthMockRecorder struct {
+	mock *MockOAuth
+}
+
+// NewMockOAuth creates a new mock instance.
+func NewMockOAuth(ctrl *gomock.Controller) *MockOAuth {
+	mock := &MockOAuth{ctrl: ctrl}
+	mock.recorder = &MockOAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth) EXPECT() *MockOAuthMockRecorder {
+	return m.recorder
+}
+
+// GetLinkedAccounts mocks base method.
+func (m *MockOAuth) GetLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkedAccounts", ctx, userId)
+	ret0, _ := ret[0].([]core.LinkedAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkedAccounts indicates an expected call of GetLinkedAccounts.
+func (mr *MockOAuthMockRecorder) GetLinkedAccounts(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkedAccounts", reflect.TypeOf((*MockOAuth)(nil).GetLinkedAccounts), ctx, userId)
+}
+
+// LinkCallback mocks base method.
+func (m *MockOAuth) LinkCallback(ctx context.Context, userId uuid.UUID, provider string, input core.OAuthCallback) (core.LinkedAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkCallback", ctx, userId, provider, input)
+	ret0, _ := ret[0].(core.LinkedAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkCallback indicates an expected call of LinkCallback.
+func (mr *MockOAuthMockRecorder) LinkCallback(ctx, userId, provider, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkCallback", reflect.TypeOf((*MockOAuth)(nil).LinkCallback), ctx, userId, provider, input)
+}
+
+// OAuthCallback mocks base method.
+func (m *MockOAuth) OAuthCallback(ctx context.Context, provider string, input core.OAuthCallback, device core.Device) (auth.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OAuthCallback", ctx, provider, input, device)
+	ret0, _ := ret[0].(auth.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OAuthCallback indicates an expected call of OAuthCallback.
+func (mr *MockOAuthMockRecorder) OAuthCallback(ctx, provider, input, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OAuthCallback", reflect.TypeOf((*MockOAuth)(nil).OAuthCallback), ctx, provider, input, device)
+}
+
+// ProviderToken mocks base method.
+func (m *MockOAuth) ProviderToken(ctx context.Context, userId uuid.UUID, provider string) (oauth.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProviderToken", ctx, userId, provider)
+	ret0, _ := ret[0].(oauth.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProviderToken indicates an expected call of ProviderToken.
+func (mr *MockOAuthMockRecorder) ProviderToken(ctx, userId, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProviderToken", reflect.TypeOf((*MockOAuth)(nil).ProviderToken), ctx, userId, provider)
+}
+
+// StartLink mocks base method.
+func (m *MockOAuth) StartLink(ctx context.Context, userId uuid.UUID, provider string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartLink", ctx, userId, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartLink indicates an expected call of StartLink.
+func (mr *MockOAuthMockRecorder) StartLink(ctx, userId, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartLink", reflect.TypeOf((*MockOAuth)(nil).StartLink), ctx, userId, provider)
+}
+
+// StartOAuth mocks base method.
+func (m *MockOAuth) StartOAuth(ctx context.Context, provider string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartOAuth", ctx, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// StartOAuth indicates an expected call of StartOAuth.
+func (mr *MockOAuthMockRecorder) StartOAuth(ctx, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartOAuth", reflect.TypeOf((*MockOAuth)(nil).StartOAuth), ctx, provider)
+}
+
+// Unlink mocks base method.
+func (m *MockOAuth) Unlink(ctx context.Context, userId uuid.UUID, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlink", ctx, userId, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlink indicates an expected call of Unlink.
+func (mr *MockOAuthMockRecorder) Unlink(ctx, userId, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlink", reflect.TypeOf((*MockOAuth)(nil).Unlink), ctx, userId, provider)
+}
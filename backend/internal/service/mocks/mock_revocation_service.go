@@ -0,0 +1,88 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/revocation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/revocation.go -destination=internal/service/mocks/mock_revocation_service.go
+//
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	context "context"
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRevocation is a mock of Revocation interface.
+type MockRevocation struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevocationMockRecorder
+}
+
+// MockRevocationMockRecorder is the mock recorder for MockRevocation.
+type MockRevocationMockRecorder struct {
+	mock *MockRevocation
+}
+
+// NewMockRevocation creates a new mock instance.
+func NewMockRevocation(ctrl *gomock.Controller) *MockRevocation {
+	mock := &MockRevocation{ctrl: ctrl}
+	mock.recorder = &MockRevocationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevocation) EXPECT() *MockRevocationMockRecorder {
+	return m.recorder
+}
+
+// IsRevoked mocks base method.
+func (m *MockRevocation) IsRevoked(sessionId uuid.UUID) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", sessionId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockRevocationMockRecorder) IsRevoked(sessionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockRevocation)(nil).IsRevoked), sessionId)
+}
+
+// Revoke mocks base method.
+func (m *MockRevocation) Revoke(ctx context.Context, sessionIds ...uuid.UUID) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range sessionIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Revoke", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockRevocationMockRecorder) Revoke(ctx any, sessionIds ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, sessionIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockRevocation)(nil).Revoke), varargs...)
+}
+
+// Sync mocks base method.
+func (m *MockRevocation) Sync(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sync", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Sync indicates an expected call of Sync.
+func (mr *MockRevocationMockRecorder) Sync(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockRevocation)(nil).Sync), ctx)
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	"github.com/Cheasezz/anSpace/backend/pkg/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type OAuth interface {
+	StartOAuth(ctx context.Context, provider string) (url, state string, err error)
+	OAuthCallback(ctx context.Context, provider string, input core.OAuthCallback, device core.Device) (auth.Tokens, error)
+	GetLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error)
+	StartLink(ctx context.Context, userId uuid.UUID, provider string) (string, error)
+	LinkCallback(ctx context.Context, userId uuid.UUID, provider string, input core.OAuthCallback) (core.LinkedAccount, error)
+	Unlink(ctx context.Context, userId uuid.UUID, provider string) error
+	ProviderToken(ctx context.Context, userId uuid.UUID, provider string) (oauth.Token, error)
+}
+
+// Provider token is refreshed this long before expiry.
+const providerTokenLeeway = time.Minute
+
+var (
+	ErrUnknownOAuthProvider  = core.NewError(core.ErrNotFound, "unknown_oauth_provider", "unknown oauth provider")
+	ErrInvalidOAuthState     = core.NewError(core.ErrValidation, "invalid_oauth_state", "invalid or expired oauth state")
+	ErrInvalidOAuthCode      = core.NewError(core.ErrValidation, "invalid_oauth_code", "invalid oauth authorization code")
+	ErrAccountAlreadyLinked  = core.NewError(core.ErrConflict, "account_already_linked", "provider account is linked to another user")
+	ErrProviderAlreadyLinked = core.NewError(core.ErrConflict, "provider_already_linked", "provider is already linked")
+	ErrProviderNotLinked     = core.NewError(core.ErrNotFound, "provider_not_linked", "provider isn't linked")
+	ErrLastSignInMethod      = core.NewError(core.ErrConflict, "last_sign_in_method", "can't unlink the only sign in method, set password first")
+	ErrProviderTokenExpired  = core.NewError(core.ErrConflict, "provider_token_expired", "provider token is expired, link provider again")
+)
+
+type OAuthService struct {
+	repo      psql.OAuth
+	auth      *AuthService
+	providers map[string]Provider
+	stateTTL  time.Duration
+}
+
+func newOAuthService(r psql.OAuth, as *AuthService, providers map[string]Provider, stateTTL time.Duration) *OAuthService {
+	return &OAuthService{
+		repo:      r,
+		auth:      as,
+		providers: providers,
+		stateTTL:  stateTTL,
+	}
+}
+
+// Start sign in with provider.
+// Return provider url user should be redirected to and state of request.
+// Sign in state isn't bound to user, so caller must bind it to browser.
+func (s *OAuthService) StartOAuth(ctx context.Context, provider string) (url, state string, err error) {
+	return s.start(ctx, provider, uuid.Nil)
+}
+
+// Consume state, exchange code for provider tokens and fetch provider profile.
+// User linked with profile is signed in, provider tokens of link are updated.
+// If profile isn't linked yet, new user without password is created for it.
+// For user with TOTP enabled return *MFARequiredError as SignIn does.
+func (s *OAuthService) OAuthCallback(ctx context.Context, provider string, input core.OAuthCallback, device core.Device) (auth.Tokens, error) {
+	account, err := s.exchange(ctx, provider, input, uuid.Nil)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	userId, err := s.signInAccount(ctx, account)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	user, err := s.auth.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	return s.auth.signInUser(ctx, user, device)
+}
+
+// Update tokens of linked account or create user for new one.
+// Return id of user account is linked to.
+func (s *OAuthService) signInAccount(ctx context.Context, account core.LinkedAccount) (uuid.UUID, error) {
+	linked, err := s.repo.GetLinkedAccount(ctx, account.Provider, account.ProviderUserId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.repo.CreateUserWithAccount(ctx, oauthEmail(account), account)
+		}
+		return uuid.UUID{}, err
+	}
+
+	account.UserId = linked.UserId
+	if err := s.repo.UpdateLinkedAccount(ctx, account); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return linked.UserId, nil
+}
+
+func (s *OAuthService) GetLinkedAccounts(ctx context.Context, userId uuid.UUID) ([]core.LinkedAccount, error) {
+	return s.repo.GetUserLinkedAccounts(ctx, userId)
+}
+
+// Start linking provider to signed in user.
+// Return provider url user should be redirected to.
+func (s *OAuthService) StartLink(ctx context.Context, userId uuid.UUID, provider string) (string, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return "", ErrUnknownOAuthProvider
+	}
+	if err := s.checkNotLinked(ctx, userId, provider); err != nil {
+		return "", err
+	}
+	url, _, err := s.start(ctx, provider, userId)
+	return url, err
+}
+
+// Consume state of link request started by user and link provider profile to user.
+// Profile linked to other user is rejected. Relinking same profile updates its tokens.
+func (s *OAuthService) LinkCallback(ctx context.Context, userId uuid.UUID, provider string, input core.OAuthCallback) (core.LinkedAccount, error) {
+	account, err := s.exchange(ctx, provider, input, userId)
+	if err != nil {
+		return core.LinkedAccount{}, err
+	}
+	account.UserId = userId
+
+	linked, err := s.repo.GetLinkedAccount(ctx, provider, account.ProviderUserId)
+	if err == nil {
+		if linked.UserId != userId {
+			return core.LinkedAccount{}, ErrAccountAlreadyLinked
+		}
+		if err := s.repo.UpdateLinkedAccount(ctx, account); err != nil {
+			return core.LinkedAccount{}, err
+		}
+		account.CreatedAt = linked.CreatedAt
+		return account, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return core.LinkedAccount{}, err
+	}
+
+	if err := s.checkNotLinked(ctx, userId, provider); err != nil {
+		return core.LinkedAccount{}, err
+	}
+	if err := s.repo.LinkAccount(ctx, account); err != nil {
+		return core.LinkedAccount{}, err
+	}
+	account.CreatedAt = time.Now().UTC()
+
+	return account, nil
+}
+
+// Return ErrProviderAlreadyLinked if user has profile of provider linked.
+func (s *OAuthService) checkNotLinked(ctx context.Context, userId uuid.UUID, provider string) error {
+	_, err := s.repo.GetUserLinkedAccount(ctx, userId, provider)
+	if err == nil {
+		return ErrProviderAlreadyLinked
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// Unlink provider from user. User without password must keep
+// at least one linked provider to be able to sign in.
+func (s *OAuthService) Unlink(ctx context.Context, userId uuid.UUID, provider string) error {
+	user, err := s.auth.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordHash == "" {
+		accounts, err := s.repo.GetUserLinkedAccounts(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if !hasOtherProvider(accounts, provider) {
+			return ErrLastSignInMethod
+		}
+	}
+
+	if err := s.repo.DeleteLinkedAccount(ctx, userId, provider); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProviderNotLinked
+		}
+		return err
+	}
+
+	return nil
+}
+
+func hasOtherProvider(accounts []core.LinkedAccount, provider string) bool {
+	for _, a := range accounts {
+		if a.Provider != provider {
+			return true
+		}
+	}
+	return false
+}
+
+// Return provider access token of user for calls to provider api.
+// Token expiring within providerTokenLeeway is refreshed and stored.
+func (s *OAuthService) ProviderToken(ctx context.Context, userId uuid.UUID, provider string) (oauth.Token, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return oauth.Token{}, ErrUnknownOAuthProvider
+	}
+
+	account, err := s.repo.GetUserLinkedAccount(ctx, userId, provider)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return oauth.Token{}, ErrProviderNotLinked
+		}
+		return oauth.Token{}, err
+	}
+
+	token := oauth.Token{
+		AccessToken:  account.AccessToken,
+		RefreshToken: account.RefreshToken,
+		ExpiresAt:    account.TokenExpiresAt,
+	}
+	if token.ExpiresAt.IsZero() || time.Now().UTC().Add(providerTokenLeeway).Before(token.ExpiresAt) {
+		return token, nil
+	}
+	if token.RefreshToken == "" {
+		return oauth.Token{}, ErrProviderTokenExpired
+	}
+
+	token, err = p.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		if errors.Is(err, oauth.ErrTokenRequest) {
+			return oauth.Token{}, ErrProviderTokenExpired
+		}
+		return oauth.Token{}, err
+	}
+
+	account.AccessToken = token.AccessToken
+	account.RefreshToken = token.RefreshToken
+	account.TokenExpiresAt = token.ExpiresAt
+	if err := s.repo.UpdateLinkedAccount(ctx, account); err != nil {
+		return oauth.Token{}, err
+	}
+
+	return token, nil
+}
+
+// Write state with PKCE code verifier of new authorization request.
+// userId is uuid.Nil for sign in and user being linked otherwise.
+// Return provider url and state.
+func (s *OAuthService) start(ctx context.Context, provider string, userId uuid.UUID) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownOAuthProvider
+	}
+
+	state, err := oauth.NewRandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oauth.NewRandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.repo.SetOAuthState(ctx, core.OAuthState{
+		State:        state,
+		Provider:     provider,
+		CodeVerifier: verifier,
+		UserId:       userId,
+		ExpiresAt:    time.Now().UTC().Add(s.stateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return p.AuthCodeURL(state, oauth.CodeChallenge(verifier)), state, nil
+}
+
+// Consume state written by start with same provider and userId,
+// exchange code for provider tokens and fetch provider profile.
+// Return account of profile with provider tokens, not yet stored.
+func (s *OAuthService) exchange(ctx context.Context, provider string, input core.OAuthCallback, userId uuid.UUID) (core.LinkedAccount, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return core.LinkedAccount{}, ErrUnknownOAuthProvider
+	}
+
+	state, err := s.repo.ConsumeOAuthState(ctx, input.State, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.LinkedAccount{}, ErrInvalidOAuthState
+		}
+		return core.LinkedAccount{}, err
+	}
+	if state.Provider != provider || state.UserId != userId {
+		return core.LinkedAccount{}, ErrInvalidOAuthState
+	}
+
+	token, err := p.Exchange(ctx, input.Code, state.CodeVerifier)
+	if err != nil {
+		if errors.Is(err, oauth.ErrTokenRequest) {
+			return core.LinkedAccount{}, ErrInvalidOAuthCode
+		}
+		return core.LinkedAccount{}, err
+	}
+	profile, err := p.Profile(ctx, token)
+	if err != nil {
+		return core.LinkedAccount{}, err
+	}
+
+	return core.LinkedAccount{
+		Provider:       provider,
+		ProviderUserId: profile.Id,
+		Username:       profile.Username,
+		AccessToken:    token.AccessToken,
+		RefreshToken:   token.RefreshToken,
+		TokenExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+// Placeholder email of user created by oauth sign in.
+// Providers don't share user email, .invalid domain never receives mail.
+func oauthEmail(account core.LinkedAccount) string {
+	return fmt.Sprintf("%s+%s@oauth.invalid", account.Provider, account.ProviderUserId)
+}
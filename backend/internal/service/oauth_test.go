@@ -0,0 +1,590 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testOAuthCode     = "code"
+	testOAuthVerifier = "verifier"
+	testOAuthAccess   = "shikiAccess"
+	testOAuthRefresh  = "shikiRefresh"
+	testOAuthAccess2  = "shikiAccess2"
+	testStateTTL      = 10 * time.Minute
+)
+
+// Fake Shikimori server. It issues tokens for testOAuthCode with testOAuthVerifier,
+// refreshes testOAuthRefresh to testOAuthAccess2 and returns user 42 for testOAuthAccess.
+func newFakeShikimori(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("grant_type") == "refresh_token" {
+			if r.PostForm.Get("refresh_token") != testOAuthRefresh {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": testOAuthAccess2,
+				"token_type":   "Bearer",
+				"expires_in":   86400,
+			})
+			return
+		}
+		if r.PostForm.Get("code") != testOAuthCode || r.PostForm.Get("code_verifier") != testOAuthVerifier {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  testOAuthAccess,
+			"refresh_token": testOAuthRefresh,
+			"token_type":    "Bearer",
+			"expires_in":    86400,
+		})
+	})
+	mux.HandleFunc("/api/users/whoami", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testOAuthAccess {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "nickname": "cheasezz"})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func initOAuthDeps(t *testing.T) (deps, *mock_psql.MockOAuth, *OAuthService) {
+	d, authSrv := initMFADeps(t)
+	srv := newFakeShikimori(t)
+
+	repo := mock_psql.NewMockOAuth(gomock.NewController(t))
+	providers := map[string]Provider{
+		core.OAuthProviderShikimori: newOAuth2Provider(config.OAuthProvider{
+			ClientID:      "client",
+			AuthURL:       srv.URL + "/oauth/authorize",
+			TokenURL:      srv.URL + "/oauth/token",
+			ProfileURL:    srv.URL + "/api/users/whoami",
+			IdField:       "id",
+			UsernameField: "nickname",
+			RedirectURL:   "http://localhost:5173/oauth/shikimori/callback",
+			Scopes:        []string{"user_rates"},
+		}, srv.Client()),
+	}
+
+	return d, repo, newOAuthService(repo, authSrv, providers, testStateTTL)
+}
+
+func TestOAuth_StartOAuth(t *testing.T) {
+	type mockBehavior func(r *mock_psql.MockOAuth, st *core.OAuthState)
+
+	_, repo, oauthSrv := initOAuthDeps(t)
+
+	tests := []struct {
+		name         string
+		provider     string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:     "OK",
+			provider: core.OAuthProviderShikimori,
+			mockBehavior: func(r *mock_psql.MockOAuth, st *core.OAuthState) {
+				r.EXPECT().SetOAuthState(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, s core.OAuthState) error {
+						*st = s
+						return nil
+					})
+			},
+		},
+		{
+			name:         "unknown provider",
+			provider:     "github",
+			expErr:       ErrUnknownOAuthProvider,
+			mockBehavior: func(r *mock_psql.MockOAuth, st *core.OAuthState) {},
+		},
+		{
+			name:     "repo error",
+			provider: core.OAuthProviderShikimori,
+			expErr:   errRepo,
+			mockBehavior: func(r *mock_psql.MockOAuth, st *core.OAuthState) {
+				r.EXPECT().SetOAuthState(gomock.Any(), gomock.Any()).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var st core.OAuthState
+			tt.mockBehavior(repo, &st)
+
+			authURL, state, err := oauthSrv.StartOAuth(context.Background(), tt.provider)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, authURL)
+				require.Empty(t, state)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, st.State, state)
+
+			require.Equal(t, core.OAuthProviderShikimori, st.Provider)
+			require.WithinDuration(t, time.Now().UTC().Add(testStateTTL), st.ExpiresAt, time.Minute)
+
+			u, err := url.Parse(authURL)
+			require.NoError(t, err)
+			q := u.Query()
+			require.Equal(t, st.State, q.Get("state"))
+			require.Equal(t, oauth.CodeChallenge(st.CodeVerifier), q.Get("code_challenge"))
+			require.Equal(t, "S256", q.Get("code_challenge_method"))
+		})
+	}
+}
+
+func TestOAuth_OAuthCallback(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockOAuth)
+
+	d, repo, oauthSrv := initOAuthDeps(t)
+	tokens := initTokens()
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "shikimori+42@oauth.invalid"}
+	state := core.OAuthState{State: "state", Provider: core.OAuthProviderShikimori, CodeVerifier: testOAuthVerifier}
+	session := core.Session{UserId: testUUID, RefreshToken: tokens.Refresh.Token, ExpiresAt: tokens.Refresh.ExpiresAt}
+	account := gomock.Cond(func(a core.LinkedAccount) bool {
+		return a.Provider == core.OAuthProviderShikimori && a.ProviderUserId == "42" && a.Username == "cheasezz" &&
+			a.AccessToken == testOAuthAccess && a.RefreshToken == testOAuthRefresh && !a.TokenExpiresAt.IsZero()
+	})
+	createSession := func(d deps) {
+		d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(user, nil)
+		d.tm.EXPECT().NewJWT(newClaimsMatcher(testUUID, false)).Return(tokens.Access.Token, nil)
+		d.tm.EXPECT().NewRefreshToken(gomock.Any()).Return(tokens.Refresh, nil)
+		d.r.EXPECT().SetSession(gomock.Any(), newSessionMatcher(session), testMaxSessions).Return(nil, nil)
+		d.rv.EXPECT().Revoke(gomock.Any()).Return(nil)
+	}
+
+	tests := []struct {
+		name         string
+		provider     string
+		input        core.OAuthCallback
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:     "OK new user",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().CreateUserWithAccount(gomock.Any(), "shikimori+42@oauth.invalid", account).Return(testUUID, nil)
+				createSession(d)
+			},
+		},
+		{
+			name:     "OK linked user",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{UserId: testUUID}, nil)
+				r.EXPECT().UpdateLinkedAccount(gomock.Any(), account).Return(nil)
+				createSession(d)
+			},
+		},
+		{
+			name:     "mfa required",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:   ErrMFARequired,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{UserId: testUUID}, nil)
+				r.EXPECT().UpdateLinkedAccount(gomock.Any(), account).Return(nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), testUUID).Return(core.User{Id: testUUID, TOTPEnabled: true}, nil)
+				d.tm.EXPECT().NewMFAToken(testUUID.String()).Return("mfaToken", nil)
+			},
+		},
+		{
+			name:         "unknown provider",
+			provider:     "github",
+			input:        core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:       ErrUnknownOAuthProvider,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {},
+		},
+		{
+			name:     "invalid state",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:   ErrInvalidOAuthState,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(core.OAuthState{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:     "state of link request",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:   ErrInvalidOAuthState,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				linkState := state
+				linkState.UserId = testUUID
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(linkState, nil)
+			},
+		},
+		{
+			name:     "state of other provider",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:   ErrInvalidOAuthState,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(core.OAuthState{Provider: "github"}, nil)
+			},
+		},
+		{
+			name:     "invalid code",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: "wrong", State: "state"},
+			expErr:   ErrInvalidOAuthCode,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+			},
+		},
+		{
+			name:     "repo create user error",
+			provider: core.OAuthProviderShikimori,
+			input:    core.OAuthCallback{Code: testOAuthCode, State: "state"},
+			expErr:   errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().CreateUserWithAccount(gomock.Any(), "shikimori+42@oauth.invalid", account).Return(uuid.UUID{}, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo)
+
+			got, err := oauthSrv.OAuthCallback(context.Background(), tt.provider, tt.input, testDevice)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tokens, got)
+			}
+		})
+	}
+}
+
+func TestOAuth_StartLink(t *testing.T) {
+	type mockBehavior func(r *mock_psql.MockOAuth, userId uuid.UUID)
+
+	_, repo, oauthSrv := initOAuthDeps(t)
+	testUUID := uuid.New()
+
+	tests := []struct {
+		name         string
+		provider     string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:     "OK",
+			provider: core.OAuthProviderShikimori,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().SetOAuthState(gomock.Any(), gomock.Cond(func(s core.OAuthState) bool {
+					return s.UserId == userId && s.Provider == core.OAuthProviderShikimori
+				})).Return(nil)
+			},
+		},
+		{
+			name:         "unknown provider",
+			provider:     "github",
+			expErr:       ErrUnknownOAuthProvider,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {},
+		},
+		{
+			name:     "already linked",
+			provider: core.OAuthProviderShikimori,
+			expErr:   ErrProviderAlreadyLinked,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(core.LinkedAccount{UserId: userId}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(repo, testUUID)
+
+			authURL, err := oauthSrv.StartLink(context.Background(), testUUID, tt.provider)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, authURL)
+			} else {
+				require.NoError(t, err)
+				require.NotEmpty(t, authURL)
+			}
+		})
+	}
+}
+
+func TestOAuth_LinkCallback(t *testing.T) {
+	type mockBehavior func(r *mock_psql.MockOAuth)
+
+	_, repo, oauthSrv := initOAuthDeps(t)
+	testUUID := uuid.New()
+	linkedAt := time.Now().Add(-time.Hour).UTC()
+	state := core.OAuthState{State: "state", Provider: core.OAuthProviderShikimori, CodeVerifier: testOAuthVerifier, UserId: testUUID}
+	account := gomock.Cond(func(a core.LinkedAccount) bool {
+		return a.UserId == testUUID && a.Provider == core.OAuthProviderShikimori && a.ProviderUserId == "42" &&
+			a.AccessToken == testOAuthAccess && a.RefreshToken == testOAuthRefresh
+	})
+	input := core.OAuthCallback{Code: testOAuthCode, State: "state"}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		expLinkedAt  time.Time
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK new link",
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), testUUID, core.OAuthProviderShikimori).Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().LinkAccount(gomock.Any(), account).Return(nil)
+			},
+		},
+		{
+			name:        "OK relink",
+			expLinkedAt: linkedAt,
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{UserId: testUUID, CreatedAt: linkedAt}, nil)
+				r.EXPECT().UpdateLinkedAccount(gomock.Any(), account).Return(nil)
+			},
+		},
+		{
+			name:   "linked to other user",
+			expErr: ErrAccountAlreadyLinked,
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{UserId: uuid.New()}, nil)
+			},
+		},
+		{
+			name:   "other profile of provider linked",
+			expErr: ErrProviderAlreadyLinked,
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), testUUID, core.OAuthProviderShikimori).Return(core.LinkedAccount{UserId: testUUID}, nil)
+			},
+		},
+		{
+			name:   "sign in state",
+			expErr: ErrInvalidOAuthState,
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				signInState := state
+				signInState.UserId = uuid.Nil
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(signInState, nil)
+			},
+		},
+		{
+			name:   "repo link error",
+			expErr: errRepo,
+			mockBehavior: func(r *mock_psql.MockOAuth) {
+				r.EXPECT().ConsumeOAuthState(gomock.Any(), "state", gomock.Any()).Return(state, nil)
+				r.EXPECT().GetLinkedAccount(gomock.Any(), core.OAuthProviderShikimori, "42").Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), testUUID, core.OAuthProviderShikimori).Return(core.LinkedAccount{}, pgx.ErrNoRows)
+				r.EXPECT().LinkAccount(gomock.Any(), account).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(repo)
+
+			got, err := oauthSrv.LinkCallback(context.Background(), testUUID, core.OAuthProviderShikimori, input)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, got)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "cheasezz", got.Username)
+			if !tt.expLinkedAt.IsZero() {
+				require.Equal(t, tt.expLinkedAt, got.CreatedAt)
+			} else {
+				require.WithinDuration(t, time.Now().UTC(), got.CreatedAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestOAuth_Unlink(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID)
+
+	d, repo, oauthSrv := initOAuthDeps(t)
+	testUUID := uuid.New()
+	shikimori := core.LinkedAccount{UserId: testUUID, Provider: core.OAuthProviderShikimori}
+	kinopoisk := core.LinkedAccount{UserId: testUUID, Provider: core.OAuthProviderKinopoisk}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK user with password",
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, PasswordHash: "hash"}, nil)
+				r.EXPECT().DeleteLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(nil)
+			},
+		},
+		{
+			name: "OK user without password with other provider",
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId}, nil)
+				r.EXPECT().GetUserLinkedAccounts(gomock.Any(), userId).Return([]core.LinkedAccount{shikimori, kinopoisk}, nil)
+				r.EXPECT().DeleteLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(nil)
+			},
+		},
+		{
+			name:   "last sign in method",
+			expErr: ErrLastSignInMethod,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId}, nil)
+				r.EXPECT().GetUserLinkedAccounts(gomock.Any(), userId).Return([]core.LinkedAccount{shikimori}, nil)
+			},
+		},
+		{
+			name:   "not linked",
+			expErr: ErrProviderNotLinked,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, PasswordHash: "hash"}, nil)
+				r.EXPECT().DeleteLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo get user error",
+			expErr: errRepoGetUserById,
+			mockBehavior: func(d deps, r *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{}, errRepoGetUserById)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			err := oauthSrv.Unlink(context.Background(), testUUID, core.OAuthProviderShikimori)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOAuth_ProviderToken(t *testing.T) {
+	type mockBehavior func(r *mock_psql.MockOAuth, userId uuid.UUID)
+
+	_, repo, oauthSrv := initOAuthDeps(t)
+	testUUID := uuid.New()
+	valid := core.LinkedAccount{
+		UserId:         testUUID,
+		Provider:       core.OAuthProviderShikimori,
+		AccessToken:    testOAuthAccess,
+		RefreshToken:   testOAuthRefresh,
+		TokenExpiresAt: time.Now().Add(time.Hour).UTC(),
+	}
+	expired := valid
+	expired.TokenExpiresAt = time.Now().Add(-time.Hour).UTC()
+
+	tests := []struct {
+		name         string
+		expErr       error
+		expAccess    string
+		mockBehavior mockBehavior
+	}{
+		{
+			name:      "OK valid token",
+			expAccess: testOAuthAccess,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(valid, nil)
+			},
+		},
+		{
+			name:      "OK refreshed token",
+			expAccess: testOAuthAccess2,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(expired, nil)
+				r.EXPECT().UpdateLinkedAccount(gomock.Any(), gomock.Cond(func(a core.LinkedAccount) bool {
+					return a.AccessToken == testOAuthAccess2 && a.RefreshToken == testOAuthRefresh && a.TokenExpiresAt.After(time.Now())
+				})).Return(nil)
+			},
+		},
+		{
+			name:   "refresh rejected",
+			expErr: ErrProviderTokenExpired,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				revoked := expired
+				revoked.RefreshToken = "revoked"
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(revoked, nil)
+			},
+		},
+		{
+			name:   "not linked",
+			expErr: ErrProviderNotLinked,
+			mockBehavior: func(r *mock_psql.MockOAuth, userId uuid.UUID) {
+				r.EXPECT().GetUserLinkedAccount(gomock.Any(), userId, core.OAuthProviderShikimori).Return(core.LinkedAccount{}, pgx.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(repo, testUUID)
+
+			token, err := oauthSrv.ProviderToken(context.Background(), testUUID, core.OAuthProviderShikimori)
+			if tt.expErr != nil {
+				require.EqualError(t, err, tt.expErr.Error())
+				require.Empty(t, token)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expAccess, token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestProfileField(t *testing.T) {
+	require.Equal(t, "42", profileField(json.RawMessage(`42`)))
+	require.Equal(t, "abc-42", profileField(json.RawMessage(`"abc-42"`)))
+	require.Equal(t, "", profileField(json.RawMessage(`null`)))
+	require.Equal(t, "", profileField(nil))
+}
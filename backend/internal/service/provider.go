@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/oauth"
+)
+
+var errProviderProfile = errors.New("provider profile has no user id")
+
+// Provider is external OAuth2 or OIDC identity provider.
+// Users sign in with it and link it to their account.
+type Provider interface {
+	AuthCodeURL(state, codeChallenge string) string
+	Exchange(ctx context.Context, code, codeVerifier string) (oauth.Token, error)
+	Refresh(ctx context.Context, refreshToken string) (oauth.Token, error)
+	Profile(ctx context.Context, token oauth.Token) (core.OAuthProfile, error)
+}
+
+// Provider configured by endpoints and profile fields only.
+// Fits OAuth2 providers with JSON profile api and OIDC userinfo.
+type oauth2Provider struct {
+	client        *oauth.Client
+	profileURL    string
+	idField       string
+	usernameField string
+}
+
+func newOAuth2Provider(cfg config.OAuthProvider, httpClient *http.Client) *oauth2Provider {
+	return &oauth2Provider{
+		client: oauth.NewClient(oauth.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			AuthURL:      cfg.AuthURL,
+			TokenURL:     cfg.TokenURL,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			UserAgent:    cfg.UserAgent,
+		}, httpClient),
+		profileURL:    cfg.ProfileURL,
+		idField:       cfg.IdField,
+		usernameField: cfg.UsernameField,
+	}
+}
+
+func (p *oauth2Provider) AuthCodeURL(state, codeChallenge string) string {
+	return p.client.AuthCodeURL(state, codeChallenge)
+}
+
+func (p *oauth2Provider) Exchange(ctx context.Context, code, codeVerifier string) (oauth.Token, error) {
+	return p.client.Exchange(ctx, code, codeVerifier)
+}
+
+func (p *oauth2Provider) Refresh(ctx context.Context, refreshToken string) (oauth.Token, error) {
+	return p.client.Refresh(ctx, refreshToken)
+}
+
+// Fetch user that authorized client from profile url.
+// Id field may be JSON string or number.
+func (p *oauth2Provider) Profile(ctx context.Context, token oauth.Token) (core.OAuthProfile, error) {
+	var profile map[string]json.RawMessage
+	if err := p.client.Get(ctx, p.profileURL, token, &profile); err != nil {
+		return core.OAuthProfile{}, err
+	}
+
+	id := profileField(profile[p.idField])
+	if id == "" {
+		return core.OAuthProfile{}, errProviderProfile
+	}
+
+	return core.OAuthProfile{
+		Id:       id,
+		Username: profileField(profile[p.usernameField]),
+	}, nil
+}
+
+func profileField(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// Providers with client id configured, by name.
+func newProviders(cfg config.OAuth) map[string]Provider {
+	configs := map[string]config.OAuthProvider{
+		core.OAuthProviderShikimori: cfg.Shikimori,
+		core.OAuthProviderKinopoisk: cfg.Kinopoisk,
+		core.OAuthProviderLitres:    cfg.Litres,
+	}
+
+	providers := make(map[string]Provider)
+	for name, c := range configs {
+		if c.ClientID != "" {
+			providers[name] = newOAuth2Provider(c, nil)
+		}
+	}
+	return providers
+}
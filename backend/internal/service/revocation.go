@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/google/uuid"
+)
+
+// Revocation is a denylist of sessions whose access tokens must be rejected
+// before they expire. Entries live as long as access token does.
+type Revocation interface {
+	Revoke(ctx context.Context, sessionIds ...uuid.UUID) error
+	IsRevoked(sessionId uuid.UUID) bool
+	Sync(ctx context.Context) error
+}
+
+// RevocationStore keeps denylist in memory, so check doesn't hit db on every request.
+// Revocations are written to db too and Sync loads ones made by other instances.
+type RevocationStore struct {
+	repo psql.Revocation
+	ttl  time.Duration
+
+	mu      sync.RWMutex
+	revoked map[uuid.UUID]time.Time
+}
+
+func newRevocationStore(r psql.Revocation, accessTokenTTL time.Duration) *RevocationStore {
+	return &RevocationStore{
+		repo:    r,
+		ttl:     accessTokenTTL,
+		revoked: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Revoke access tokens of sessions issued up to now.
+func (s *RevocationStore) Revoke(ctx context.Context, sessionIds ...uuid.UUID) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+
+	expiresAt := time.Now().UTC().Add(s.ttl)
+	if err := s.repo.RevokeSessions(ctx, sessionIds, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range sessionIds {
+		s.revoked[id] = expiresAt
+	}
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(sessionId uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, ok := s.revoked[sessionId]
+	return ok && time.Now().UTC().Before(expiresAt)
+}
+
+// Replace in memory denylist with not expired revocations from db.
+func (s *RevocationStore) Sync(ctx context.Context) error {
+	revoked, err := s.repo.GetRevokedSessions(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	m := make(map[uuid.UUID]time.Time, len(revoked))
+	for _, r := range revoked {
+		m[r.SessionId] = r.ExpiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Keep local entries that could be written after db read.
+	for id, expiresAt := range s.revoked {
+		if _, ok := m[id]; !ok && time.Now().UTC().Before(expiresAt) {
+			m[id] = expiresAt
+		}
+	}
+	s.revoked = m
+	return nil
+}
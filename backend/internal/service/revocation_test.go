@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRevocationStore_Revoke(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock_psql.NewMockRevocation(ctrl)
+	store := newRevocationStore(repo, time.Minute)
+	sessionId := uuid.New()
+
+	repo.EXPECT().RevokeSessions(gomock.Any(), []uuid.UUID{sessionId}, gomock.Any()).Return(errRepo)
+	require.EqualError(t, store.Revoke(context.Background(), sessionId), errRepo.Error())
+	require.False(t, store.IsRevoked(sessionId))
+
+	repo.EXPECT().RevokeSessions(gomock.Any(), []uuid.UUID{sessionId}, gomock.Any()).Return(nil)
+	require.NoError(t, store.Revoke(context.Background(), sessionId))
+	require.True(t, store.IsRevoked(sessionId))
+	require.False(t, store.IsRevoked(uuid.New()))
+
+	// Nothing to revoke, repo isn't called.
+	require.NoError(t, store.Revoke(context.Background()))
+}
+
+func TestRevocationStore_Sync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock_psql.NewMockRevocation(ctrl)
+	store := newRevocationStore(repo, time.Minute)
+
+	local, remote, expired := uuid.New(), uuid.New(), uuid.New()
+	store.revoked[local] = time.Now().UTC().Add(time.Minute)
+	store.revoked[expired] = time.Now().UTC().Add(-time.Minute)
+
+	repo.EXPECT().GetRevokedSessions(gomock.Any(), gomock.Any()).Return([]core.RevokedSession{
+		{SessionId: remote, ExpiresAt: time.Now().UTC().Add(time.Minute)},
+	}, nil)
+	require.NoError(t, store.Sync(context.Background()))
+
+	require.True(t, store.IsRevoked(local))
+	require.True(t, store.IsRevoked(remote))
+	require.False(t, store.IsRevoked(expired))
+	require.Len(t, store.revoked, 2)
+
+	repo.EXPECT().GetRevokedSessions(gomock.Any(), gomock.Any()).Return(nil, errRepo)
+	require.EqualError(t, store.Sync(context.Background()), errRepo.Error())
+	require.True(t, store.IsRevoked(remote))
+}
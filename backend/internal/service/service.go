@@ -1,6 +1,9 @@
 package service
 
 import (
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
 	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	"github.com/Cheasezz/anSpace/backend/pkg/email"
@@ -9,6 +12,11 @@ import (
 
 type Services struct {
 	Auth
+	Revocation
+	LoginLimiter
+	OAuth
+	Users
+	Admin
 }
 
 type Deps struct {
@@ -16,10 +24,25 @@ type Deps struct {
 	Hasher       hasher.PasswordHasher
 	TokenManager auth.TokenManager
 	EmailSender  email.Sender
+	// Max number of sessions (token families) one user can have.
+	MaxSessionsPerUser int
+	AccessTokenTTL     time.Duration
+	Lockout            config.Lockout
+	OAuth              config.OAuth
+	Users              config.Users
 }
 
 func NewServices(d Deps) *Services {
+	revocation := newRevocationStore(d.Repos.Storage.Revocation, d.AccessTokenTTL)
+	limiter := newLockoutLimiter(d.Repos.Lockout, d.Lockout)
+	authService := newAuthService(d.Repos.Storage.Auth, d.Repos.Tx, d.Hasher, d.TokenManager, d.EmailSender, revocation, limiter, d.MaxSessionsPerUser)
+
 	return &Services{
-		Auth: newAuthService(d.Repos.Psql.Auth, d.Hasher, d.TokenManager, d.EmailSender),
+		Auth:         authService,
+		Revocation:   revocation,
+		LoginLimiter: limiter,
+		OAuth:        newOAuthService(d.Repos.Storage.OAuth, authService, newProviders(d.OAuth), d.OAuth.StateTTL),
+		Users:        newUsersService(d.Repos.Storage.Users, d.Repos.Storage.Auth, d.Repos.Storage.OAuth, d.Repos.Tx, d.Hasher, d.EmailSender, revocation, limiter, d.Users),
+		Admin:        newAdminService(d.Repos.Storage.Admin, d.Repos.Storage.Auth, revocation, limiter),
 	}
 }
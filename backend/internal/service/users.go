@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	repositories "github.com/Cheasezz/anSpace/backend/internal/repository"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/Cheasezz/anSpace/backend/pkg/email"
+	"github.com/Cheasezz/anSpace/backend/pkg/hasher"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type Users interface {
+	UpdateUsername(ctx context.Context, userId uuid.UUID, username string) (core.User, error)
+	ChangePassword(ctx context.Context, userId uuid.UUID, input core.PasswordChange, refreshToken string, device core.Device) error
+	RequestEmailChange(ctx context.Context, userId uuid.UUID, input core.EmailChange, device core.Device) error
+	ConfirmEmailChange(ctx context.Context, userId uuid.UUID, code string) (core.User, error)
+	ScheduleDeletion(ctx context.Context, userId uuid.UUID) (time.Time, error)
+	CancelDeletion(ctx context.Context, input core.DeletionCancel) error
+	Export(ctx context.Context, userId uuid.UUID) (core.UserExport, error)
+}
+
+const emailChangeCodeTTL = time.Hour
+
+var (
+	ErrUsernameTaken         = core.NewError(core.ErrConflict, "username_taken", "username is already taken")
+	ErrUsernameReserved      = core.NewError(core.ErrValidation, "username_reserved", "username is reserved")
+	ErrUsernameChangeTooSoon = core.NewError(core.ErrRateLimited, "username_change_too_soon", "username was changed recently")
+
+	ErrWrongPassword  = core.NewError(core.ErrForbidden, "wrong_password", "current password is incorrect")
+	ErrPasswordNotSet = core.NewError(core.ErrConflict, "password_not_set", "user has no password, set it with password reset")
+
+	ErrEmailTaken             = core.NewError(core.ErrConflict, "email_taken", "email is already taken")
+	ErrSameEmail              = core.NewError(core.ErrValidation, "same_email", "new email is the same as current")
+	ErrInvalidEmailChangeCode = core.NewError(core.ErrValidation, "invalid_email_change_code", "invalid email change code")
+	ErrEmailChangeCodeExpired = core.NewError(core.ErrValidation, "email_change_code_expired", "email change code is expired")
+
+	ErrDeletionScheduled         = core.NewError(core.ErrConflict, "deletion_scheduled", "account deletion is already scheduled")
+	ErrAccountPendingDeletion    = core.NewError(core.ErrForbidden, "account_pending_deletion", "account deletion is scheduled, cancel it to sign in")
+	ErrInvalidDeletionCancelCode = core.NewError(core.ErrValidation, "invalid_deletion_cancel_code", "invalid deletion cancel code")
+)
+
+// Names of routes, roles and the service itself, users can't take them.
+var reservedUsernames = map[string]struct{}{
+	"admin":         {},
+	"administrator": {},
+	"anspace":       {},
+	"api":           {},
+	"auth":          {},
+	"help":          {},
+	"me":            {},
+	"moderator":     {},
+	"null":          {},
+	"root":          {},
+	"settings":      {},
+	"support":       {},
+	"system":        {},
+	"undefined":     {},
+	"user":          {},
+	"users":         {},
+}
+
+// UsernameChangeError is returned when username is changed before change interval passed.
+// errors.Is(err, ErrUsernameChangeTooSoon) is true for it.
+type UsernameChangeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *UsernameChangeError) Error() string {
+	return ErrUsernameChangeTooSoon.Error()
+}
+
+func (e *UsernameChangeError) Unwrap() error {
+	return ErrUsernameChangeTooSoon
+}
+
+func (e *UsernameChangeError) RetryIn() time.Duration {
+	return e.RetryAfter
+}
+
+type UsersService struct {
+	repo        psql.Users
+	authRepo    psql.Auth
+	oauthRepo   psql.OAuth
+	tx          repositories.TxManager
+	hasher      hasher.PasswordHasher
+	emailSender email.Sender
+	revocation  Revocation
+	limiter     LoginLimiter
+	cfg         config.Users
+}
+
+func newUsersService(r psql.Users, ar psql.Auth, or psql.OAuth, tx repositories.TxManager, h hasher.PasswordHasher, es email.Sender, rv Revocation, ll LoginLimiter, cfg config.Users) *UsersService {
+	return &UsersService{
+		repo:        r,
+		authRepo:    ar,
+		oauthRepo:   or,
+		tx:          tx,
+		hasher:      h,
+		emailSender: es,
+		revocation:  rv,
+		limiter:     ll,
+		cfg:         cfg,
+	}
+}
+
+// Set username of user. Username is unique ignoring case and can be changed
+// once per s.cfg.UsernameChangeInterval, first change of random default username isn't limited.
+// Setting current username again is no-op.
+func (s *UsersService) UpdateUsername(ctx context.Context, userId uuid.UUID, username string) (core.User, error) {
+	if _, ok := reservedUsernames[strings.ToLower(username)]; ok {
+		return core.User{}, ErrUsernameReserved
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return core.User{}, err
+	}
+	if user.Username == username {
+		return user, nil
+	}
+
+	now := time.Now().UTC()
+	if retryAfter := user.UsernameChangedAt.Add(s.cfg.UsernameChangeInterval).Sub(now); retryAfter > 0 {
+		return core.User{}, &UsernameChangeError{RetryAfter: retryAfter}
+	}
+
+	user, err = s.repo.UpdateUsername(ctx, userId, username, now, now.Add(-s.cfg.UsernameChangeInterval))
+	if err != nil {
+		if errors.Is(err, psql.ErrDuplicate) {
+			return core.User{}, ErrUsernameTaken
+		}
+		// Changed by concurrent request.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.User{}, &UsernameChangeError{RetryAfter: s.cfg.UsernameChangeInterval}
+		}
+		return core.User{}, err
+	}
+
+	return user, nil
+}
+
+// Check current password and set new one. Other sessions of user are deleted
+// and their access tokens revoked, session refresh token belongs to stays.
+// User without password (created by oauth sign in) gets ErrPasswordNotSet.
+func (s *UsersService) ChangePassword(ctx context.Context, userId uuid.UUID, input core.PasswordChange, refreshToken string, device core.Device) error {
+	session, err := s.authRepo.GetUserSessionByRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if session.UserId != userId || session.Rotated {
+		return ErrInvalidRefreshToken
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" {
+		return ErrPasswordNotSet
+	}
+	if err := s.verifyPassword(ctx, user, input.CurrentPassword, device.IP); err != nil {
+		return err
+	}
+
+	pass, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	sessionIds, err := s.repo.ChangePassword(ctx, userId, session.FamilyId, pass)
+	if err != nil {
+		return err
+	}
+	return s.revocation.Revoke(ctx, sessionIds...)
+}
+
+// Send email change code on new email. Email isn't changed until code is confirmed,
+// previous not confirmed request is replaced. User with password must pass it.
+func (s *UsersService) RequestEmailChange(ctx context.Context, userId uuid.UUID, input core.EmailChange, device core.Device) error {
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, input.Email) {
+		return ErrSameEmail
+	}
+	// User created by oauth sign in has no password and placeholder email to replace.
+	if user.PasswordHash != "" {
+		if err := s.verifyPassword(ctx, user, input.Password, device.IP); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.authRepo.GetUserByEmail(ctx, input.Email); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	code, err := newCode(user.Email, core.CodeTypeEmailChange, emailChangeCodeTTL)
+	if err != nil {
+		return err
+	}
+	code.NewEmail = input.Email
+
+	if err := s.repo.SetEmailChangeCode(ctx, code); err != nil {
+		return err
+	}
+	return s.emailSender.Send(input.Email, fmt.Sprintf("This is your email change code:%s", code.Code))
+}
+
+// Check email change code and its expiration time.
+// Set new email as verified email of user and delete code.
+func (s *UsersService) ConfirmEmailChange(ctx context.Context, userId uuid.UUID, code string) (core.User, error) {
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return core.User{}, err
+	}
+
+	changeCode, err := s.authRepo.GetCode(ctx, core.CodeCredentials{
+		Email:    user.Email,
+		Code:     code,
+		CodeType: core.CodeTypeEmailChange,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.User{}, ErrInvalidEmailChangeCode
+		}
+		return core.User{}, err
+	}
+
+	if time.Now().UTC().After(changeCode.ExpiresAt) {
+		if err := s.authRepo.DeleteCode(ctx, changeCode); err != nil {
+			return core.User{}, err
+		}
+		return core.User{}, ErrEmailChangeCodeExpired
+	}
+
+	user, err = s.repo.ChangeEmail(ctx, changeCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.User{}, ErrInvalidEmailChangeCode
+		}
+		if errors.Is(err, psql.ErrDuplicate) {
+			return core.User{}, ErrEmailTaken
+		}
+		return core.User{}, err
+	}
+
+	return user, nil
+}
+
+// Schedule deletion of user after grace period and send cancellation link on user email.
+// Deletion is scheduled in one transaction with sending link, so user isn't locked
+// out without way to cancel. All user sessions are deleted and their access tokens
+// revoked, user can't sign in until deletion is cancelled.
+// Return time account will be deleted at.
+func (s *UsersService) ScheduleDeletion(ctx context.Context, userId uuid.UUID) (time.Time, error) {
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.DeletionScheduled() {
+		return time.Time{}, ErrDeletionScheduled
+	}
+
+	code, err := newCode(user.Email, core.CodeTypeDeletionCancel, s.cfg.DeletionGracePeriod)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var sessionIds []uuid.UUID
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		sessionIds, err = s.repo.ScheduleDeletion(ctx, userId, code)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrDeletionScheduled
+			}
+			return err
+		}
+
+		// Email goes last, nothing can be rolled back after it is sent.
+		link := s.cfg.DeletionCancelURL + "?" + url.Values{"email": {user.Email}, "code": {code.Code}}.Encode()
+		message := fmt.Sprintf("Your account will be deleted at %s. Follow the link to cancel deletion:%s",
+			code.ExpiresAt.Format(time.RFC1123), link)
+		return s.emailSender.Send(user.Email, message)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Sessions are deleted already, revocation only cuts their access tokens short.
+	if err := s.revocation.Revoke(ctx, sessionIds...); err != nil {
+		return time.Time{}, err
+	}
+
+	return code.ExpiresAt, nil
+}
+
+// Check deletion cancel code from email and unschedule deletion of user.
+func (s *UsersService) CancelDeletion(ctx context.Context, input core.DeletionCancel) error {
+	code, err := s.authRepo.GetCode(ctx, core.CodeCredentials{
+		Email:    input.Email,
+		Code:     input.Code,
+		CodeType: core.CodeTypeDeletionCancel,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidDeletionCancelCode
+		}
+		return err
+	}
+
+	// Account is deleted by next sweep.
+	if time.Now().UTC().After(code.ExpiresAt) {
+		return ErrInvalidDeletionCancelCode
+	}
+
+	if err := s.repo.CancelDeletion(ctx, code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidDeletionCancelCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Return everything stored about user: profile, sessions, linked accounts and codes.
+func (s *UsersService) Export(ctx context.Context, userId uuid.UUID) (core.UserExport, error) {
+	user, err := s.authRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return core.UserExport{}, err
+	}
+
+	sessions, err := s.authRepo.GetUserSessions(ctx, userId)
+	if err != nil {
+		return core.UserExport{}, err
+	}
+
+	accounts, err := s.oauthRepo.GetUserLinkedAccounts(ctx, userId)
+	if err != nil {
+		return core.UserExport{}, err
+	}
+
+	codes, err := s.repo.GetUserCodes(ctx, user.Email)
+	if err != nil {
+		return core.UserExport{}, err
+	}
+
+	return core.UserExport{
+		User:           user,
+		Sessions:       sessions,
+		LinkedAccounts: accounts,
+		Codes:          codes,
+	}, nil
+}
+
+// Verify current password of signed in user. Failed attempts are counted
+// per email and ip, locked ones get *LockoutError.
+func (s *UsersService) verifyPassword(ctx context.Context, user core.User, password, ip string) error {
+	if err := s.limiter.Check(ctx, limitScopeReauth, user.Email, ip); err != nil {
+		return err
+	}
+
+	ok, err := s.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := s.limiter.Fail(ctx, limitScopeReauth, user.Email, ip); err != nil {
+			return err
+		}
+		return ErrWrongPassword
+	}
+
+	return s.limiter.Succeed(ctx, limitScopeReauth, user.Email)
+}
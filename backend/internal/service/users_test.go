@@ -0,0 +1,655 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	mock_psql "github.com/Cheasezz/anSpace/backend/internal/repository/psql/mocks"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	mock_email "github.com/Cheasezz/anSpace/backend/pkg/email/mocks"
+	mock_hash "github.com/Cheasezz/anSpace/backend/pkg/hasher/mocks"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testUsernameChangeInterval = 30 * 24 * time.Hour
+
+var testUsersConfig = config.Users{
+	UsernameChangeInterval: testUsernameChangeInterval,
+	DeletionGracePeriod:    14 * 24 * time.Hour,
+	DeletionCancelURL:      "http://localhost:5173/account/restore",
+}
+
+func initUsersDeps(t *testing.T) (deps, *mock_psql.MockUsers, *mock_psql.MockOAuth, *UsersService) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	hash := mock_hash.NewMockPasswordHasher(ctrl)
+	authRepo := mock_psql.NewMockAuth(ctrl)
+	es := mock_email.NewMockSender(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	ll := mock_service.NewMockLoginLimiter(ctrl)
+	repo := mock_psql.NewMockUsers(ctrl)
+	oauthRepo := mock_psql.NewMockOAuth(ctrl)
+
+	srv := newUsersService(repo, authRepo, oauthRepo, newTxMock(ctrl), hash, es, rv, ll, testUsersConfig)
+	return initDeps(hash, authRepo, nil, es, rv, ll), repo, oauthRepo, srv
+}
+
+func TestUsers_UpdateUsername(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, userId uuid.UUID)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	defaultUser := core.User{Id: testUUID, Username: "user_5f2b9c", UsernameChangedAt: time.Unix(0, 0).UTC()}
+	updatedUser := core.User{Id: testUUID, Username: "cheasezz", UsernameChangedAt: time.Now().UTC()}
+
+	tests := []struct {
+		name          string
+		username      string
+		expUser       core.User
+		expErr        error
+		expRetryAfter time.Duration
+		mockBehavior  mockBehavior
+	}{
+		{
+			name:     "OK: first change",
+			username: "cheasezz",
+			expUser:  updatedUser,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(defaultUser, nil)
+				r.EXPECT().UpdateUsername(gomock.Any(), userId, "cheasezz", gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uuid.UUID, _ string, now, changedBefore time.Time) (core.User, error) {
+						require.Equal(t, testUsernameChangeInterval, now.Sub(changedBefore))
+						return updatedUser, nil
+					})
+			},
+		},
+		{
+			name:     "OK: same username",
+			username: "cheasezz",
+			expUser:  updatedUser,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(updatedUser, nil)
+			},
+		},
+		{
+			name:     "reserved",
+			username: "Admin",
+			expErr:   ErrUsernameReserved,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+			},
+		},
+		{
+			name:          "changed recently",
+			username:      "cheasezz2",
+			expErr:        ErrUsernameChangeTooSoon,
+			expRetryAfter: testUsernameChangeInterval - time.Hour,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Username: "cheasezz", UsernameChangedAt: time.Now().UTC().Add(-time.Hour)}, nil)
+			},
+		},
+		{
+			name:     "taken",
+			username: "cheasezz",
+			expErr:   ErrUsernameTaken,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(defaultUser, nil)
+				r.EXPECT().UpdateUsername(gomock.Any(), userId, "cheasezz", gomock.Any(), gomock.Any()).Return(core.User{}, psql.ErrDuplicate)
+			},
+		},
+		{
+			name:          "changed concurrently",
+			username:      "cheasezz",
+			expErr:        ErrUsernameChangeTooSoon,
+			expRetryAfter: testUsernameChangeInterval,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(defaultUser, nil)
+				r.EXPECT().UpdateUsername(gomock.Any(), userId, "cheasezz", gomock.Any(), gomock.Any()).Return(core.User{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:     "repo get user error",
+			username: "cheasezz",
+			expErr:   errRepoGetUserById,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{}, errRepoGetUserById)
+			},
+		},
+		{
+			name:     "repo update error",
+			username: "cheasezz",
+			expErr:   errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(defaultUser, nil)
+				r.EXPECT().UpdateUsername(gomock.Any(), userId, "cheasezz", gomock.Any(), gomock.Any()).Return(core.User{}, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			user, err := usersSrv.UpdateUsername(context.Background(), testUUID, tt.username)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+				require.Empty(t, user)
+
+				var changeErr *UsernameChangeError
+				if errors.As(err, &changeErr) {
+					require.InDelta(t, tt.expRetryAfter, changeErr.RetryAfter, float64(time.Second))
+				}
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expUser, user)
+			}
+		})
+	}
+}
+
+func TestUsers_ChangePassword(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, userId uuid.UUID)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	familyId := uuid.New()
+	otherSessions := []uuid.UUID{uuid.New(), uuid.New()}
+	user := core.User{Id: testUUID, Email: "user@example.com", PasswordHash: "oldHash"}
+	session := core.Session{UserId: testUUID, RefreshToken: "token", FamilyId: familyId}
+	input := core.PasswordChange{CurrentPassword: "qwerty123456", NewPassword: "qwerty1234567"}
+	lockoutErr := &LockoutError{RetryAfter: time.Minute}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(session, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+				d.h.EXPECT().Verify(input.CurrentPassword, "oldHash").Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeReauth, user.Email).Return(nil)
+				d.h.EXPECT().Hash(input.NewPassword).Return("newHash", nil)
+				r.EXPECT().ChangePassword(gomock.Any(), userId, familyId, "newHash").Return(otherSessions, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), otherSessions[0], otherSessions[1]).Return(nil)
+			},
+		},
+		{
+			name:   "session not found",
+			expErr: ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(core.Session{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "session of other user",
+			expErr: ErrInvalidRefreshToken,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(core.Session{UserId: uuid.New(), FamilyId: familyId}, nil)
+			},
+		},
+		{
+			name:   "user without password",
+			expErr: ErrPasswordNotSet,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(session, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, Email: user.Email}, nil)
+			},
+		},
+		{
+			name:   "wrong password",
+			expErr: ErrWrongPassword,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(session, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+				d.h.EXPECT().Verify(input.CurrentPassword, "oldHash").Return(false, nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:   "locked",
+			expErr: lockoutErr,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(session, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(lockoutErr)
+			},
+		},
+		{
+			name:   "repo change error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserSessionByRefreshToken(gomock.Any(), "token").Return(session, nil)
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+				d.h.EXPECT().Verify(input.CurrentPassword, "oldHash").Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeReauth, user.Email).Return(nil)
+				d.h.EXPECT().Hash(input.NewPassword).Return("newHash", nil)
+				r.EXPECT().ChangePassword(gomock.Any(), userId, familyId, "newHash").Return(nil, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			err := usersSrv.ChangePassword(context.Background(), testUUID, input, "token", testDevice)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUsers_RequestEmailChange(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, userId uuid.UUID)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com", PasswordHash: "hash"}
+	oauthUser := core.User{Id: testUUID, Email: "shikimori+1@oauth.invalid"}
+	input := core.EmailChange{Email: "new@example.com", Password: "qwerty123456"}
+
+	codeMatcher := func(email string) gomock.Matcher {
+		return gomock.Cond(func(x any) bool {
+			code := x.(core.CodeCredentials)
+			return code.Email == email && code.NewEmail == input.Email &&
+				code.CodeType == core.CodeTypeEmailChange && code.Code != "" && code.ExpiresAt.After(time.Now())
+		})
+	}
+
+	tests := []struct {
+		name         string
+		input        core.EmailChange
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:  "OK",
+			input: input,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+				d.h.EXPECT().Verify(input.Password, "hash").Return(true, nil)
+				d.ll.EXPECT().Succeed(gomock.Any(), limitScopeReauth, user.Email).Return(nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), input.Email).Return(core.User{}, pgx.ErrNoRows)
+				r.EXPECT().SetEmailChangeCode(gomock.Any(), codeMatcher(user.Email)).Return(nil)
+				d.es.EXPECT().Send(input.Email, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:  "OK: user without password",
+			input: core.EmailChange{Email: input.Email},
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(oauthUser, nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), input.Email).Return(core.User{}, pgx.ErrNoRows)
+				r.EXPECT().SetEmailChangeCode(gomock.Any(), codeMatcher(oauthUser.Email)).Return(nil)
+				d.es.EXPECT().Send(input.Email, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:   "same email",
+			input:  core.EmailChange{Email: "User@Example.com", Password: input.Password},
+			expErr: ErrSameEmail,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+			},
+		},
+		{
+			name:   "wrong password",
+			input:  input,
+			expErr: ErrWrongPassword,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.ll.EXPECT().Check(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+				d.h.EXPECT().Verify(input.Password, "hash").Return(false, nil)
+				d.ll.EXPECT().Fail(gomock.Any(), limitScopeReauth, user.Email, testDevice.IP).Return(nil)
+			},
+		},
+		{
+			name:   "email taken",
+			input:  core.EmailChange{Email: input.Email},
+			expErr: ErrEmailTaken,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(oauthUser, nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), input.Email).Return(core.User{Id: uuid.New(), Email: input.Email}, nil)
+			},
+		},
+		{
+			name:   "repo set code error",
+			input:  core.EmailChange{Email: input.Email},
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(oauthUser, nil)
+				d.r.EXPECT().GetUserByEmail(gomock.Any(), input.Email).Return(core.User{}, pgx.ErrNoRows)
+				r.EXPECT().SetEmailChangeCode(gomock.Any(), gomock.Any()).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			err := usersSrv.RequestEmailChange(context.Background(), testUUID, tt.input, testDevice)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUsers_ConfirmEmailChange(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, userId uuid.UUID)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com"}
+	updatedUser := core.User{Id: testUUID, Email: "new@example.com", EmailVerified: true}
+	codeQuery := core.CodeCredentials{Email: user.Email, Code: "code", CodeType: core.CodeTypeEmailChange}
+	code := core.CodeCredentials{Email: user.Email, Code: "code", CodeType: core.CodeTypeEmailChange, NewEmail: "new@example.com", ExpiresAt: time.Now().Add(time.Hour)}
+	expiredCode := core.CodeCredentials{Email: user.Email, Code: "code", CodeType: core.CodeTypeEmailChange, NewEmail: "new@example.com", ExpiresAt: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name         string
+		expUser      core.User
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:    "OK",
+			expUser: updatedUser,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().ChangeEmail(gomock.Any(), code).Return(updatedUser, nil)
+			},
+		},
+		{
+			name:   "invalid code",
+			expErr: ErrInvalidEmailChangeCode,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "expired code",
+			expErr: ErrEmailChangeCodeExpired,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(expiredCode, nil)
+				d.r.EXPECT().DeleteCode(gomock.Any(), expiredCode).Return(nil)
+			},
+		},
+		{
+			name:   "code consumed concurrently",
+			expErr: ErrInvalidEmailChangeCode,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().ChangeEmail(gomock.Any(), code).Return(core.User{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "email taken",
+			expErr: ErrEmailTaken,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().ChangeEmail(gomock.Any(), code).Return(core.User{}, psql.ErrDuplicate)
+			},
+		},
+		{
+			name:   "repo get user error",
+			expErr: errRepoGetUserById,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{}, errRepoGetUserById)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			user, err := usersSrv.ConfirmEmailChange(context.Background(), testUUID, "code")
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+				require.Empty(t, user)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expUser, user)
+			}
+		})
+	}
+}
+
+func TestUsers_ScheduleDeletion(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, userId uuid.UUID)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	sessions := []uuid.UUID{uuid.New(), uuid.New()}
+	user := core.User{Id: testUUID, Email: "user@example.com"}
+
+	codeMatcher := gomock.Cond(func(x any) bool {
+		code := x.(core.CodeCredentials)
+		deleteAt := time.Now().Add(testUsersConfig.DeletionGracePeriod)
+		return code.Email == user.Email && code.CodeType == core.CodeTypeDeletionCancel &&
+			code.Code != "" && code.ExpiresAt.Sub(deleteAt).Abs() < time.Minute
+	})
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				r.EXPECT().ScheduleDeletion(gomock.Any(), userId, codeMatcher).Return(sessions, nil)
+				d.rv.EXPECT().Revoke(gomock.Any(), sessions[0], sessions[1]).Return(nil)
+				d.es.EXPECT().Send(user.Email, gomock.Cond(func(x any) bool {
+					return strings.Contains(x.(string), testUsersConfig.DeletionCancelURL+"?code=")
+				})).Return(nil)
+			},
+		},
+		{
+			name:   "already scheduled",
+			expErr: ErrDeletionScheduled,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(core.User{Id: userId, DeleteAt: time.Now().Add(time.Hour)}, nil)
+			},
+		},
+		{
+			name:   "scheduled concurrently",
+			expErr: ErrDeletionScheduled,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				r.EXPECT().ScheduleDeletion(gomock.Any(), userId, codeMatcher).Return(nil, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo schedule error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				r.EXPECT().ScheduleDeletion(gomock.Any(), userId, codeMatcher).Return(nil, errRepo)
+			},
+		},
+		{
+			name:   "email sender error",
+			expErr: errEmailSender,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				r.EXPECT().ScheduleDeletion(gomock.Any(), userId, codeMatcher).Return(sessions, nil)
+				d.es.EXPECT().Send(user.Email, gomock.Any()).Return(errEmailSender)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo, testUUID)
+
+			deleteAt, err := usersSrv.ScheduleDeletion(context.Background(), testUUID)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+				require.Zero(t, deleteAt)
+			} else {
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now().Add(testUsersConfig.DeletionGracePeriod), deleteAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestUsers_CancelDeletion(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers)
+
+	d, repo, _, usersSrv := initUsersDeps(t)
+	input := core.DeletionCancel{Email: "user@example.com", Code: "code"}
+	codeQuery := core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypeDeletionCancel}
+	code := core.CodeCredentials{Email: input.Email, Code: input.Code, CodeType: core.CodeTypeDeletionCancel, ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name         string
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(d deps, r *mock_psql.MockUsers) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().CancelDeletion(gomock.Any(), code).Return(nil)
+			},
+		},
+		{
+			name:   "invalid code",
+			expErr: ErrInvalidDeletionCancelCode,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{}, pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "grace period is over",
+			expErr: ErrInvalidDeletionCancelCode,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(core.CodeCredentials{Email: input.Email, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+			},
+		},
+		{
+			name:   "code consumed concurrently",
+			expErr: ErrInvalidDeletionCancelCode,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().CancelDeletion(gomock.Any(), code).Return(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:   "repo cancel error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers) {
+				d.r.EXPECT().GetCode(gomock.Any(), codeQuery).Return(code, nil)
+				r.EXPECT().CancelDeletion(gomock.Any(), code).Return(errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, repo)
+
+			err := usersSrv.CancelDeletion(context.Background(), input)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUsers_Export(t *testing.T) {
+	type mockBehavior func(d deps, r *mock_psql.MockUsers, or *mock_psql.MockOAuth, userId uuid.UUID)
+
+	d, usersRepo, oauthRepo, usersSrv := initUsersDeps(t)
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "user@example.com"}
+	sessions := []core.Session{{UserId: testUUID, FamilyId: uuid.New()}}
+	accounts := []core.LinkedAccount{{UserId: testUUID, Provider: core.OAuthProviderShikimori}}
+	codes := []core.CodeCredentials{{Email: user.Email, Code: "code", CodeType: core.CodeTypeEmailChange, NewEmail: "new@example.com"}}
+
+	tests := []struct {
+		name         string
+		expExport    core.UserExport
+		expErr       error
+		mockBehavior mockBehavior
+	}{
+		{
+			name:      "OK",
+			expExport: core.UserExport{User: user, Sessions: sessions, LinkedAccounts: accounts, Codes: codes},
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, or *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetUserSessions(gomock.Any(), userId).Return(sessions, nil)
+				or.EXPECT().GetUserLinkedAccounts(gomock.Any(), userId).Return(accounts, nil)
+				r.EXPECT().GetUserCodes(gomock.Any(), user.Email).Return(codes, nil)
+			},
+		},
+		{
+			name:   "repo get sessions error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, or *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetUserSessions(gomock.Any(), userId).Return(nil, errRepo)
+			},
+		},
+		{
+			name:   "repo get linked accounts error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, or *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetUserSessions(gomock.Any(), userId).Return(sessions, nil)
+				or.EXPECT().GetUserLinkedAccounts(gomock.Any(), userId).Return(nil, errRepo)
+			},
+		},
+		{
+			name:   "repo get codes error",
+			expErr: errRepo,
+			mockBehavior: func(d deps, r *mock_psql.MockUsers, or *mock_psql.MockOAuth, userId uuid.UUID) {
+				d.r.EXPECT().GetUserById(gomock.Any(), userId).Return(user, nil)
+				d.r.EXPECT().GetUserSessions(gomock.Any(), userId).Return(sessions, nil)
+				or.EXPECT().GetUserLinkedAccounts(gomock.Any(), userId).Return(accounts, nil)
+				r.EXPECT().GetUserCodes(gomock.Any(), user.Email).Return(nil, errRepo)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(d, usersRepo, oauthRepo, testUUID)
+
+			export, err := usersSrv.Export(context.Background(), testUUID)
+			if tt.expErr != nil {
+				require.ErrorIs(t, err, tt.expErr)
+				require.Empty(t, export)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expExport, export)
+			}
+		})
+	}
+}
@@ -19,7 +19,7 @@ func corsMiddleware(origins []string) gin.HandlerFunc {
 			http.MethodDelete,
 		},
 		AllowHeaders:     []string{"Origin", "Content-type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Content-type"},
+		ExposeHeaders:    []string{"Content-Length", "Content-type", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           24 * time.Hour,
 	})
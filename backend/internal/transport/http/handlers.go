@@ -1,7 +1,10 @@
 package httpHandlers
 
 import (
+	"net/http"
+
 	v1 "github.com/Cheasezz/anSpace/backend/internal/transport/http/v1"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -10,12 +13,14 @@ import (
 )
 
 type Handlers struct {
-	v1 *v1.Handlers
+	v1           *v1.Handlers
+	tokenManager auth.TokenManager
 }
 
 func NewHandlers(d v1.Deps) *Handlers {
 	return &Handlers{
-		v1: v1.NewHandlers(d),
+		v1:           v1.NewHandlers(d),
+		tokenManager: d.TokenManager,
 	}
 }
 
@@ -24,9 +29,8 @@ func (h *Handlers) Init() *gin.Engine {
 	router.Use(gin.Recovery(), corsMiddleware(h.v1.Config.FrontendOrigins))
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.NoRoute(func(c *gin.Context) {
-		c.JSON(404, v1.ErrorResponse{Message: "Page not found"})
-	})
+	router.GET("/.well-known/jwks.json", h.jwks)
+	router.NoRoute(v1.NoRoute)
 
 	api := router.Group("/api")
 	{
@@ -34,3 +38,15 @@ func (h *Handlers) Init() *gin.Engine {
 	}
 	return router
 }
+
+// @Tags auth
+// @Summary public keys
+// @Description return public keys for access token verification in JWK Set format
+// @ID jwks
+// @Produce  json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Handlers) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokenManager.JWKS())
+}
@@ -0,0 +1,273 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errInvalidUserId = core.NewError(core.ErrValidation, "invalid_user_id", "invalid user id")
+
+type Admin struct {
+	service service.Admin
+	log     logger.Logger
+	mdlwrs  *Middlewares
+}
+
+func NewAdminHandler(d Deps, m *Middlewares) *Admin {
+	return &Admin{
+		service: d.Services.Admin,
+		log:     d.Log,
+		mdlwrs:  m,
+	}
+}
+
+func (h *Admin) initAdminRoutes(router *gin.RouterGroup) {
+	limitAuth := h.mdlwrs.rateLimit(rateGroupAuth)
+	requireAdmin := h.mdlwrs.requireRole(core.RoleAdmin)
+
+	admin := router.Group("/admin", h.mdlwrs.userIdentity, h.mdlwrs.emailVerified,
+		h.mdlwrs.requireRole(core.RoleModerator, core.RoleAdmin), limitAuth)
+	{
+		admin.GET("/users", h.searchUsers)
+		admin.POST("/users/:id/ban", h.banUser)
+		admin.DELETE("/users/:id/ban", h.unbanUser)
+		admin.POST("/users/:id/logout", h.logoutUser)
+		admin.PUT("/users/:id/role", requireAdmin, h.setRole)
+		admin.GET("/lockouts", h.getLockouts)
+		admin.DELETE("/lockouts/:key", h.clearLockout)
+	}
+}
+
+// @Tags admin
+// @Summary search users
+// @Description return page of users whose email or username contains query ignoring case. Available to moderators and admins
+// @ID admin-search-users
+// @Produce  json
+// @Param query query string false "part of email or username"
+// @Param limit query int false "page size, 20 by default, 100 max"
+// @Param offset query int false "number of users to skip"
+// @Success 200 {object} adminUsersResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/users [get]
+func (h *Admin) searchUsers(c *gin.Context) {
+	var input core.UserSearch
+	if err := c.ShouldBindQuery(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	users, err := h.service.SearchUsers(c, input)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAdminUsersResponse(users))
+}
+
+// @Tags admin
+// @Summary ban user
+// @Description ban user with role below own one and sign them out of every session. Banned user can't sign in or refresh tokens
+// @ID admin-ban-user
+// @Accept  json
+// @Param id path string true "user id"
+// @Param input body core.Ban true "ban reason"
+// @Success 200 "user banned"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/users/{id}/ban [post]
+func (h *Admin) banUser(c *gin.Context) {
+	actor, userId, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	var input core.Ban
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	if err := h.service.BanUser(c, actor, userId, input.Reason); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags admin
+// @Summary unban user
+// @Description lift ban of user with role below own one
+// @ID admin-unban-user
+// @Param id path string true "user id"
+// @Success 200 "user unbanned"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/users/{id}/ban [delete]
+func (h *Admin) unbanUser(c *gin.Context) {
+	actor, userId, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnbanUser(c, actor, userId); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags admin
+// @Summary force logout
+// @Description delete every session of user with role below own one and revoke their access tokens
+// @ID admin-logout-user
+// @Param id path string true "user id"
+// @Success 200 "user signed out"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *Admin) logoutUser(c *gin.Context) {
+	actor, userId, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.LogoutUser(c, actor, userId); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags admin
+// @Summary set user role
+// @Description set role of user with role below own one. Available to admins only. New role is applied on next token refresh
+// @ID admin-set-role
+// @Accept  json
+// @Produce  json
+// @Param id path string true "user id"
+// @Param input body core.RoleChange true "new role"
+// @Success 200 {object} adminUserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/users/{id}/role [put]
+func (h *Admin) setRole(c *gin.Context) {
+	actor, userId, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	var input core.RoleChange
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	user, err := h.service.SetRole(c, actor, userId, input.Role)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// @Tags admin
+// @Summary get lockouts
+// @Description return currently locked emails and ips
+// @ID admin-get-lockouts
+// @Produce  json
+// @Success 200 {object} lockoutsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/lockouts [get]
+func (h *Admin) getLockouts(c *gin.Context) {
+	lockouts, err := h.service.GetLockouts(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newLockoutsResponse(lockouts))
+}
+
+// @Tags admin
+// @Summary clear lockout
+// @Description unlock email or ip and forget its failed attempts
+// @ID admin-clear-lockout
+// @Param key path string true "lockout key"
+// @Success 200 "lockout cleared"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/admin/lockouts/{key} [delete]
+func (h *Admin) clearLockout(c *gin.Context) {
+	if err := h.service.ClearLockout(c, c.Param("key")); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// Return signed in actor and id of user from path. Error response is sent if ok is false.
+func (h *Admin) moderationTarget(c *gin.Context) (core.Actor, uuid.UUID, bool) {
+	actor, err := h.mdlwrs.getActorFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return core.Actor{}, uuid.UUID{}, false
+	}
+
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, h.log, errInvalidUserId)
+		return core.Actor{}, uuid.UUID{}, false
+	}
+
+	return actor, userId, true
+}
@@ -0,0 +1,377 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	errServiceSearchUsers = fmt.Errorf("service searchUsers error")
+	errServiceGetLockouts = fmt.Errorf("service getLockouts error")
+)
+
+func roleClaims(userId uuid.UUID, roles ...string) auth.Claims {
+	claims := testClaims(userId.String())
+	claims.Roles = roles
+	return claims
+}
+
+func TestMiddlewares_requireRole(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	targetId := uuid.New()
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		inputBody    string
+		roles        []string
+		expStatCode  int
+		mockBehavior func(s *mock_service.MockAdmin, l *mock_logger.MockLogger)
+	}{
+		{
+			name:        "user can't moderate",
+			method:      http.MethodGet,
+			target:      "/v1/admin/users",
+			roles:       []string{core.RoleUser},
+			expStatCode: 403,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(service.ErrInsufficientRole)
+			},
+		},
+		{
+			name:        "token without roles",
+			method:      http.MethodGet,
+			target:      "/v1/admin/users",
+			expStatCode: 403,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(service.ErrInsufficientRole)
+			},
+		},
+		{
+			name:        "moderator can search",
+			method:      http.MethodGet,
+			target:      "/v1/admin/users",
+			roles:       []string{core.RoleModerator},
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{}).Return(nil, nil)
+			},
+		},
+		{
+			name:        "moderator can't set role",
+			method:      http.MethodPut,
+			target:      "/v1/admin/users/" + targetId.String() + "/role",
+			inputBody:   `{"role":"user"}`,
+			roles:       []string{core.RoleModerator},
+			expStatCode: 403,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(service.ErrInsufficientRole)
+			},
+		},
+		{
+			name:        "admin can set role",
+			method:      http.MethodPut,
+			target:      "/v1/admin/users/" + targetId.String() + "/role",
+			inputBody:   `{"role":"moderator"}`,
+			roles:       []string{core.RoleAdmin},
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				actor := core.Actor{Id: testUUID, Role: core.RoleAdmin}
+				s.EXPECT().SetRole(gomock.Any(), actor, targetId, core.RoleModerator).
+					Return(core.User{Id: targetId, Role: core.RoleModerator}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, tt.roles...), nil)
+			tt.mockBehavior(mockDeps.am, mockDeps.lm)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.expStatCode == 403 {
+				require.Equal(t, errorJSON(service.ErrInsufficientRole), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdmin_searchUsers(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdmin, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	bannedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	users := []core.User{
+		{Id: uuid.New(), Email: "user@example.com", Username: "user", Role: core.RoleUser, PasswordHash: "hash"},
+		{Id: uuid.New(), Email: "spam@example.com", Username: "spam", Role: core.RoleUser, BannedAt: bannedAt, BanReason: "spam"},
+	}
+
+	tests := []struct {
+		name         string
+		target       string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			target:      "/v1/admin/users?query=example&limit=10&offset=20",
+			expStatCode: 200,
+			expReqBody:  newAdminUsersResponse(users),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{Query: "example", Limit: 10, Offset: 20}).Return(users, nil)
+			},
+		},
+		{
+			name:        "ok: nothing found",
+			target:      "/v1/admin/users?query=nobody",
+			expStatCode: 200,
+			expReqBody:  adminUsersResponse{Users: []adminUserResponse{}},
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{Query: "nobody"}).Return(nil, nil)
+			},
+		},
+		{
+			name:        "Bad request: limit too big",
+			target:      "/v1/admin/users?limit=1000",
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'UserSearch.Limit' Error:Field validation for 'Limit' failed on the 'max' tag"},
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Server error: service search error",
+			target:      "/v1/admin/users",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceSearchUsers),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().SearchUsers(gomock.Any(), core.UserSearch{}).Return(nil, errServiceSearchUsers)
+				l.EXPECT().Error(errServiceSearchUsers)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, core.RoleModerator), nil)
+			tt.mockBehavior(mockDeps.am, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAdmin_banUser(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdmin, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	targetId := uuid.New()
+	actor := core.Actor{Id: testUUID, Role: core.RoleModerator}
+
+	tests := []struct {
+		name         string
+		target       string
+		inputBody    string
+		expStatCode  int
+		expReqBody   string
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			target:      "/v1/admin/users/" + targetId.String() + "/ban",
+			inputBody:   `{"reason":"spam"}`,
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().BanUser(gomock.Any(), actor, targetId, "spam").Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: invalid user id",
+			target:      "/v1/admin/users/123/ban",
+			inputBody:   `{"reason":"spam"}`,
+			expStatCode: 400,
+			expReqBody:  errorJSON(errInvalidUserId),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errInvalidUserId)
+			},
+		},
+		{
+			name:        "Forbidden: target of same role",
+			target:      "/v1/admin/users/" + targetId.String() + "/ban",
+			inputBody:   `{"reason":"spam"}`,
+			expStatCode: 403,
+			expReqBody:  errorJSON(service.ErrInsufficientRole),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().BanUser(gomock.Any(), actor, targetId, "spam").Return(service.ErrInsufficientRole)
+				l.EXPECT().Error(service.ErrInsufficientRole)
+			},
+		},
+		{
+			name:        "Not found: no such user",
+			target:      "/v1/admin/users/" + targetId.String() + "/ban",
+			inputBody:   `{}`,
+			expStatCode: 404,
+			expReqBody:  errorJSON(service.ErrUserNotFound),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().BanUser(gomock.Any(), actor, targetId, "").Return(service.ErrUserNotFound)
+				l.EXPECT().Error(service.ErrUserNotFound)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, core.RoleModerator), nil)
+			tt.mockBehavior(mockDeps.am, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expReqBody, w.Body.String())
+		})
+	}
+}
+
+func TestAdmin_unbanAndLogoutUser(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	targetId := uuid.New()
+	actor := core.Actor{Id: testUUID, Role: core.RoleAdmin}
+
+	mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, core.RoleAdmin), nil).Times(2)
+	mockDeps.am.EXPECT().UnbanUser(gomock.Any(), actor, targetId).Return(nil)
+	mockDeps.am.EXPECT().LogoutUser(gomock.Any(), actor, targetId).Return(nil)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodDelete, "/v1/admin/users/"+targetId.String()+"/ban", nil),
+		httptest.NewRequest(http.MethodPost, "/v1/admin/users/"+targetId.String()+"/logout", nil),
+	} {
+		req.Header.Add(authorizationHeader, "Bearer acToken")
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, 200, w.Code)
+	}
+}
+
+func TestAdmin_setRole(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	targetId := uuid.New()
+
+	mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, core.RoleAdmin), nil)
+	mockDeps.lm.EXPECT().Error(gomock.Any())
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/admin/users/"+targetId.String()+"/role", bytes.NewBufferString(`{"role":"root"}`))
+	req.Header.Add(authorizationHeader, "Bearer acToken")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, 400, w.Code)
+	res, _ := json.Marshal(ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'RoleChange.Role' Error:Field validation for 'Role' failed on the 'oneof' tag"})
+	require.Equal(t, string(res), w.Body.String())
+}
+
+func TestAdmin_lockouts(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	lockedUntil := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lockouts := []core.Lockout{{Key: "signin:email:user@example.com", Failures: 7, LastFailureAt: lockedUntil.Add(-time.Minute), LockedUntil: lockedUntil}}
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		expStatCode  int
+		expReqBody   string
+		mockBehavior func(s *mock_service.MockAdmin, l *mock_logger.MockLogger)
+	}{
+		{
+			name:        "get",
+			method:      http.MethodGet,
+			target:      "/v1/admin/lockouts",
+			expStatCode: 200,
+			expReqBody:  `{"lockouts":[{"key":"signin:email:user@example.com","failures":7,"lastFailureAt":"2024-05-01T11:59:00Z","lockedUntil":"2024-05-01T12:00:00Z"}]}`,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().GetLockouts(gomock.Any()).Return(lockouts, nil)
+			},
+		},
+		{
+			name:        "get: service error",
+			method:      http.MethodGet,
+			target:      "/v1/admin/lockouts",
+			expStatCode: 500,
+			expReqBody:  errorJSON(errServiceGetLockouts),
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().GetLockouts(gomock.Any()).Return(nil, errServiceGetLockouts)
+				l.EXPECT().Error(errServiceGetLockouts)
+			},
+		},
+		{
+			name:        "clear",
+			method:      http.MethodDelete,
+			target:      "/v1/admin/lockouts/signin:email:user@example.com",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAdmin, l *mock_logger.MockLogger) {
+				s.EXPECT().ClearLockout(gomock.Any(), "signin:email:user@example.com").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(roleClaims(testUUID, core.RoleModerator), nil)
+			tt.mockBehavior(mockDeps.am, mockDeps.lm)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expReqBody, w.Body.String())
+		})
+	}
+}
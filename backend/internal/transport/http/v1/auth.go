@@ -1,7 +1,7 @@
 package v1
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -11,36 +11,55 @@ import (
 	"github.com/Cheasezz/anSpace/backend/pkg/logger"
 	"github.com/asaskevich/govalidator"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 var (
-	errEmptyEmailOrPass = fmt.Errorf("all fields must be completed")
-	errShortPass        = fmt.Errorf("password must be more than 11 characters")
-	errIncorrectEmail   = fmt.Errorf("incorrect email")
+	errEmptyEmailOrPass = core.NewError(core.ErrValidation, "empty_email_or_password", "all fields must be completed")
+	errShortPass        = core.NewError(core.ErrValidation, "short_password", "password must be more than 11 characters")
+	errIncorrectEmail   = core.NewError(core.ErrValidation, "incorrect_email", "incorrect email")
+	errInvalidSessionId = core.NewError(core.ErrValidation, "invalid_session_id", "invalid session id")
 )
 
+const maxUserAgentLen = 512
+
 type Auth struct {
 	service service.Auth
+	oauth   service.OAuth
 	config  config.HTTP
 	log     logger.Logger
 	mdlwrs  *Middlewares
 }
 
 func (h *Auth) initAuthRoutes(router *gin.RouterGroup) {
+	limitAuth := h.mdlwrs.rateLimit(rateGroupAuth)
+	limitRead := h.mdlwrs.rateLimit(rateGroupRead)
+	limitRefresh := h.mdlwrs.rateLimit(rateGroupRefresh)
+	limitPassReset := h.mdlwrs.rateLimit(rateGroupPassReset)
+
 	auth := router.Group("/auth")
 	{
-		auth.POST("/signup", h.signUp)
-		auth.POST("/signin", h.signIn)
-		auth.DELETE("/logout", h.logOut)
-		auth.POST("/genpasrcode", h.genPassResetCode)
-		auth.POST("/refresh", h.refreshAccessToken)
-		auth.GET("/me", h.mdlwrs.userIdentity, h.me)
+		auth.POST("/signup", limitAuth, h.signUp)
+		auth.POST("/signin", limitAuth, h.signIn)
+		auth.DELETE("/logout", limitAuth, h.logOut)
+		auth.POST("/genpasrcode", limitAuth, limitPassReset, h.genPassResetCode)
+		auth.POST("/resetpass", limitAuth, h.resetPassword)
+		auth.POST("/verifyemail", limitAuth, h.verifyEmail)
+		auth.POST("/resendverify", h.mdlwrs.userIdentity, limitAuth, h.resendEmailVerifyCode)
+		auth.POST("/refresh", limitRefresh, h.refreshAccessToken)
+		auth.GET("/me", h.mdlwrs.userIdentity, limitRead, h.me)
+		auth.GET("/sessions", h.mdlwrs.userIdentity, limitRead, h.getSessions)
+		auth.DELETE("/sessions", h.mdlwrs.userIdentity, limitAuth, h.revokeOtherSessions)
+		auth.DELETE("/sessions/:id", h.mdlwrs.userIdentity, limitAuth, h.revokeSession)
+		h.initMFARoutes(auth, limitAuth)
+		h.initOAuthRoutes(auth, limitAuth, limitRead)
 	}
 }
 
 func NewAuthHandler(d Deps, m *Middlewares) *Auth {
 	return &Auth{
 		service: d.Services.Auth,
+		oauth:   d.Services.OAuth,
 		config:  d.ConfigHTTP,
 		log:     d.Log,
 		mdlwrs:  m,
@@ -57,6 +76,8 @@ func NewAuthHandler(d Deps, m *Middlewares) *Auth {
 // @Success 200 {object} auth.ATknInfo
 // @Header 200 {string} Set-Cookie "refreshToken. Example: "RefreshToken=9838c59cff93e21; Path=/; Max-Age=2628000; HttpOnly; Secure; SameSite=None" "
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "email is already taken"
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Router /api/v1/auth/signup [post]
@@ -64,22 +85,22 @@ func (h *Auth) signUp(c *gin.Context) {
 	var input core.AuthCredentials
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+		newErrorResponse(c, h.log, errInvalidRequest(err))
 		return
 	}
 
-	if err := h.validateEmail(input.Email); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+	if err := validateEmail(input.Email); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
-	if err := h.validatePass(input.Password); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+	if err := validatePass(input.Password); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
-	tokens, err := h.service.SignUp(c, input)
+	tokens, err := h.service.SignUp(c, input, deviceFromCtx(c))
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusInternalServerError, err)
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
@@ -95,7 +116,12 @@ func (h *Auth) signUp(c *gin.Context) {
 // @Param input body core.AuthCredentials true "signin input"
 // @Success 200 {object} auth.ATknInfo
 // @Header 200 {string} Set-Cookie "refreshToken. Example: "RefreshToken=9838c59cff93e21; Path=/; Max-Age=2628000; HttpOnly; Secure; SameSite=None" "
+// @Success 202 {object} mfaPendingResponse "user has 2fa enabled, tokens are returned by /auth/2fa/verify"
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "account deletion is scheduled"
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until email or ip is unlocked"
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Router /api/v1/auth/signin [post]
@@ -103,22 +129,27 @@ func (h *Auth) signIn(c *gin.Context) {
 	var input core.AuthCredentials
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+		newErrorResponse(c, h.log, errInvalidRequest(err))
 		return
 	}
 
-	if err := h.validateEmail(input.Email); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+	if err := validateEmail(input.Email); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
-	if err := h.validatePass(input.Password); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+	if err := validatePass(input.Password); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
-	tokens, err := h.service.SignIn(c, input)
+	tokens, err := h.service.SignIn(c, input, deviceFromCtx(c))
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusInternalServerError, err)
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			c.JSON(http.StatusAccepted, mfaPendingResponse{MFARequired: true, MFAToken: mfaErr.Token})
+			return
+		}
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
@@ -133,19 +164,20 @@ func (h *Auth) signIn(c *gin.Context) {
 // @Param Cookie header string true "refresh token in cookies"
 // @Success 200 {object} auth.ATknInfo "response has emty accessToken"
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Router /api/v1/auth/logout [delete]
 func (h *Auth) logOut(c *gin.Context) {
 	rt, err := c.Cookie(rtCookieName)
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusUnauthorized, err)
+		newErrorResponse(c, h.log, errNoRefreshToken)
 		return
 	}
 
 	tkns, err := h.service.LogOut(c, rt)
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusInternalServerError, err)
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
@@ -153,36 +185,32 @@ func (h *Auth) logOut(c *gin.Context) {
 }
 
 // @Tags auth
-// @Summary refresh access token
-// @Description accept refresh token from cookie, and return new access token
+// @Summary refresh tokens
+// @Description accept refresh token from cookie, and return new access token and new refresh token in cookies. Presented refresh token becomes invalid, its reuse revokes the whole session.
 // @ID refresh-access-token
 // @Produce  json
 // @Param Cookie header string true "refresh token in cookies"
 // @Success 200 {object} auth.ATknInfo
 // @Header 200 {string} Set-Cookie "refreshToken. Example: "RefreshToken=9838c59cff93e21; Path=/; Max-Age=2628000; HttpOnly; Secure; SameSite=None" "
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Router /api/v1/auth/refresh [post]
 func (h *Auth) refreshAccessToken(c *gin.Context) {
 	refreshToken, err := c.Cookie(rtCookieName)
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusUnauthorized, err)
+		newErrorResponse(c, h.log, errNoRefreshToken)
 		return
 	}
 
-	tokens, err := h.service.RefreshAccessToken(c, refreshToken)
+	tokens, err := h.service.RefreshAccessToken(c, refreshToken, deviceFromCtx(c))
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusInternalServerError, err)
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
-	if tokens.Refresh.Token != "" {
-		newTokenResponse(c, tokens, h.config)
-		return
-	}
-
-	c.JSON(http.StatusOK, tokens.Access)
+	newTokenResponse(c, tokens, h.config)
 }
 
 // @Tags auth
@@ -192,6 +220,8 @@ func (h *Auth) refreshAccessToken(c *gin.Context) {
 // @Produce  json
 // @Success 200 {object} userResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Security		bearerAuth
@@ -199,27 +229,127 @@ func (h *Auth) refreshAccessToken(c *gin.Context) {
 func (h *Auth) me(c *gin.Context) {
 	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusUnauthorized, err)
+		newErrorResponse(c, h.log, err)
 		return
 	}
 	user, err := h.service.GetUser(c, usrId)
 	if err != nil {
-		newErrorResponse(c, h.log, http.StatusUnauthorized, err)
+		newErrorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// @Tags auth
+// @Summary list active sessions
+// @Description return active sessions (devices) of current user, most recently used first
+// @ID get-sessions
+// @Produce  json
+// @Param Cookie header string false "refresh token in cookies, used to mark current session"
+// @Success 200 {object} sessionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/sessions [get]
+func (h *Auth) getSessions(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	sessions, err := h.service.GetSessions(c, usrId)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	rt, _ := c.Cookie(rtCookieName)
+	c.JSON(http.StatusOK, newSessionsResponse(sessions, rt))
+}
+
+// @Tags auth
+// @Summary revoke session
+// @Description delete session with given id of current user
+// @ID revoke-session
+// @Produce  json
+// @Param id path string true "session id"
+// @Success 200 "session deleted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *Auth) revokeSession(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	sessionId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, h.log, errInvalidSessionId)
+		return
+	}
+
+	if err := h.service.RevokeSession(c, usrId, sessionId); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags auth
+// @Summary revoke other sessions
+// @Description delete all sessions of current user except the one refresh token from cookie belongs to
+// @ID revoke-other-sessions
+// @Produce  json
+// @Param Cookie header string true "refresh token in cookies"
+// @Success 200 "other sessions deleted"
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/sessions [delete]
+func (h *Auth) revokeOtherSessions(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	rt, err := c.Cookie(rtCookieName)
+	if err != nil {
+		newErrorResponse(c, h.log, errNoRefreshToken)
+		return
+	}
+
+	if err := h.service.RevokeOtherSessions(c, usrId, rt); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
-	c.JSON(http.StatusOK, userResponse{
-		User: user,
-	})
+
+	c.AbortWithStatus(http.StatusOK)
 }
 
 // @Tags auth
 // @Summary generate password reset code
-// @Description generate and save password reset code into db. Sends code to email
+// @Description generate and save password reset code into db. Sends code to email. Unknown email gets the same response without code, so it doesn't tell whether email has account
 // @ID gen_pass_reset_code
 // @Param email body core.Email true "email input"
 // @Produce  json
-// @Success 200 "password reset code saved in db and sent on specified email"
+// @Success 200 "password reset code sent on specified email if it has account"
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until email is unlocked or ip can send next request"
 // @Failure 500 {object} ErrorResponse
 // @Failure default {object} ErrorResponse
 // @Router /api/v1/genpasrcode [post]
@@ -227,24 +357,136 @@ func (h *Auth) genPassResetCode(c *gin.Context) {
 	var email core.Email
 
 	if err := c.ShouldBindJSON(&email); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+		newErrorResponse(c, h.log, errInvalidRequest(err))
 		return
 	}
 
-	if err := h.validateEmail(email.Email); err != nil {
-		newErrorResponse(c, h.log, http.StatusBadRequest, err)
+	if err := validateEmail(email.Email); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
 	if err := h.service.GenPassResetCode(c, email.Email); err != nil {
-		newErrorResponse(c, h.log, http.StatusInternalServerError, err)
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags auth
+// @Summary reset password
+// @Description check password reset code, set new password and delete all user sessions
+// @ID reset_pass
+// @Accept  json
+// @Param input body core.PassResetCredentials true "reset password input"
+// @Produce  json
+// @Success 200 "password updated, all user sessions deleted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/auth/resetpass [post]
+func (h *Auth) resetPassword(c *gin.Context) {
+	var input core.PassResetCredentials
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	if err := validateEmail(input.Email); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+	if err := validatePass(input.Password); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	if err := h.service.ResetPassword(c, input); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags auth
+// @Summary verify email
+// @Description check email verification code and mark user email as verified
+// @ID verify_email
+// @Accept  json
+// @Param input body core.EmailVerifyCredentials true "verify email input"
+// @Produce  json
+// @Success 200 "email verified"
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/auth/verifyemail [post]
+func (h *Auth) verifyEmail(c *gin.Context) {
+	var input core.EmailVerifyCredentials
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	if err := validateEmail(input.Email); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	if err := h.service.VerifyEmail(c, input); err != nil {
+		newErrorResponse(c, h.log, err)
 		return
 	}
 
 	c.AbortWithStatus(http.StatusOK)
 }
 
-func (h *Auth) validateEmail(email string) error {
+// @Tags auth
+// @Summary resend email verification code
+// @Description generate new email verification code and send it on current user email
+// @ID resend_email_verify_code
+// @Produce  json
+// @Success 200 "email verification code saved in db and sent on user email"
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/resendverify [post]
+func (h *Auth) resendEmailVerifyCode(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	if err := h.service.ResendEmailVerifyCode(c, usrId); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// Return client device info from request. User agent is cut to fit db column.
+func deviceFromCtx(c *gin.Context) core.Device {
+	ua := c.Request.UserAgent()
+	if len(ua) > maxUserAgentLen {
+		ua = ua[:maxUserAgentLen]
+	}
+	return core.Device{
+		UserAgent: ua,
+		IP:        c.ClientIP(),
+	}
+}
+
+func validateEmail(email string) error {
 	var (
 		trimE = strings.TrimSpace(email)
 	)
@@ -259,7 +501,7 @@ func (h *Auth) validateEmail(email string) error {
 	return nil
 }
 
-func (h *Auth) validatePass(pass string) error {
+func validatePass(pass string) error {
 	var (
 		trimP = strings.TrimSpace(pass)
 	)
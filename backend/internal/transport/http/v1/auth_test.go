@@ -18,6 +18,7 @@ import (
 	mock_auth "github.com/Cheasezz/anSpace/backend/pkg/auth/mocks"
 	"github.com/Cheasezz/anSpace/backend/pkg/logger"
 	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -32,6 +33,10 @@ var (
 	errServiceLogOut             = fmt.Errorf("service logout error")
 	errServiceRefreshAccessToken = fmt.Errorf("service refreshAccessToken error")
 	errServiceGetUser            = fmt.Errorf("service getUser error")
+	errServiceGenPassResetCode   = fmt.Errorf("service genPassResetCode error")
+	errServiceResetPassword      = fmt.Errorf("service resetPassword error")
+	errServiceVerifyEmail        = fmt.Errorf("service verifyEmail error")
+	errServiceGetSessions        = fmt.Errorf("service getSessions error")
 )
 
 func TestMain(m *testing.M) {
@@ -45,7 +50,6 @@ func TestMain(m *testing.M) {
 func TestAuthHandler_validateEmail(t *testing.T) {
 	tests := []struct {
 		name   string
-		h      *Auth
 		l      string
 		expErr error
 	}{
@@ -67,7 +71,7 @@ func TestAuthHandler_validateEmail(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.h.validateEmail(tt.l)
+			err := validateEmail(tt.l)
 
 			if tt.expErr == nil {
 				require.NoError(t, err)
@@ -83,7 +87,6 @@ func TestAuthHandler_validateEmail(t *testing.T) {
 func TestAuthHandler_validatePass(t *testing.T) {
 	tests := []struct {
 		name   string
-		h      *Auth
 		p      string
 		expErr error
 	}{
@@ -104,7 +107,7 @@ func TestAuthHandler_validatePass(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.h.validatePass(tt.p)
+			err := validatePass(tt.p)
 
 			if tt.expErr == nil {
 				require.NoError(t, err)
@@ -145,8 +148,16 @@ func initTokens() auth.Tokens {
 	}
 }
 
+func testClaims(userId string) auth.Claims {
+	return auth.Claims{StandardClaims: jwt.StandardClaims{Subject: userId}, SessionId: uuid.NewString(), EmailVerified: true}
+}
+
 type Mocks struct {
 	sam *mock_service.MockAuth
+	rvm *mock_service.MockRevocation
+	om  *mock_service.MockOAuth
+	um  *mock_service.MockUsers
+	am  *mock_service.MockAdmin
 	tmm *mock_auth.MockTokenManager
 	lm  *mock_logger.MockLogger
 	cm  config.HTTP
@@ -159,15 +170,26 @@ func initMocks(t *testing.T) (Mocks, *gin.Engine) {
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	l := mock_logger.NewMockLogger(ctrl)
 
-	services := &service.Services{Auth: authSrv}
+	rv := mock_service.NewMockRevocation(ctrl)
+	rv.EXPECT().IsRevoked(gomock.Any()).Return(false).AnyTimes()
+
+	oauthSrv := mock_service.NewMockOAuth(ctrl)
+	usersSrv := mock_service.NewMockUsers(ctrl)
+	adminSrv := mock_service.NewMockAdmin(ctrl)
+
+	services := &service.Services{Auth: authSrv, Revocation: rv, OAuth: oauthSrv, Users: usersSrv, Admin: adminSrv}
 	deps := initDeps(services, tm, l)
 	mdlwrs := NewMiddlewares(deps)
 	handler := NewAuthHandler(deps, mdlwrs)
+	usersHandler := NewUsersHandler(deps, mdlwrs)
+	adminHandler := NewAdminHandler(deps, mdlwrs)
 
 	r := gin.New()
 	v1 := r.Group("/v1")
 	handler.initAuthRoutes(v1)
-	return Mocks{sam: authSrv, tmm: tm, lm: l, cm: deps.ConfigHTTP}, r
+	usersHandler.initUsersRoutes(v1)
+	adminHandler.initAdminRoutes(v1)
+	return Mocks{sam: authSrv, rvm: rv, om: oauthSrv, um: usersSrv, am: adminSrv, tmm: tm, lm: l, cm: deps.ConfigHTTP}, r
 }
 
 func TestAuthHandler_signUp(t *testing.T) {
@@ -190,7 +212,7 @@ func TestAuthHandler_signUp(t *testing.T) {
 			inputBody:       `{"email": "Cheasezz@gmail.com","password":"qwerty123456"}`,
 			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, AuthCredentials core.AuthCredentials) {
-				s.EXPECT().SignUp(gomock.Any(), AuthCredentials).Return(tokens, nil)
+				s.EXPECT().SignUp(gomock.Any(), AuthCredentials, gomock.Any()).Return(tokens, nil)
 			},
 			expStatCode: 200,
 			okReqBody:   tokens.Access,
@@ -204,7 +226,7 @@ func TestAuthHandler_signUp(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: "Key: 'AuthCredentials.Email' Error:Field validation for 'Email' failed on the 'required' tag"},
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'AuthCredentials.Email' Error:Field validation for 'Email' failed on the 'required' tag"},
 		},
 		{
 			name:            "Bad request: empty password",
@@ -215,7 +237,7 @@ func TestAuthHandler_signUp(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: "Key: 'AuthCredentials.Password' Error:Field validation for 'Password' failed on the 'required' tag"},
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'AuthCredentials.Password' Error:Field validation for 'Password' failed on the 'required' tag"},
 		},
 		{
 			name:            "Bad request: empty email after trim",
@@ -226,7 +248,7 @@ func TestAuthHandler_signUp(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errEmptyEmailOrPass.Error()},
+			errReqBody:  errorBody(errEmptyEmailOrPass),
 		},
 		{
 			name:            "Bad request: empty passwrod after trim",
@@ -237,7 +259,7 @@ func TestAuthHandler_signUp(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errEmptyEmailOrPass.Error()},
+			errReqBody:  errorBody(errEmptyEmailOrPass),
 		},
 		{
 			name:            "Bad request: password less then 12 char",
@@ -248,19 +270,19 @@ func TestAuthHandler_signUp(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errShortPass.Error()},
+			errReqBody:  errorBody(errShortPass),
 		},
 		{
 			name:            "Server error: Service Sign Up error",
 			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
 			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, AuthCredentials core.AuthCredentials) {
-				s.EXPECT().SignUp(gomock.Any(), AuthCredentials).Return(auth.Tokens{}, errServiceSignUp)
+				s.EXPECT().SignUp(gomock.Any(), AuthCredentials, gomock.Any()).Return(auth.Tokens{}, errServiceSignUp)
 				l.EXPECT().Error(errServiceSignUp)
 			},
 			expStatCode: 500,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errServiceSignUp.Error()},
+			errReqBody:  errorBody(errServiceSignUp),
 		},
 	}
 	for _, tt := range tests {
@@ -298,13 +320,14 @@ func TestAuthHandler_signIn(t *testing.T) {
 		okReqBody       auth.ATknInfo
 		isErr           bool
 		errReqBody      ErrorResponse
+		expRetryAfter   string
 	}{
 		{
 			name:            "OK",
 			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
 			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.AuthCredentials) {
-				s.EXPECT().SignIn(gomock.Any(), input).Return(tokens, nil)
+				s.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(tokens, nil)
 			},
 			expStatCode: 200,
 			okReqBody:   tokens.Access,
@@ -318,7 +341,7 @@ func TestAuthHandler_signIn(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: "Key: 'AuthCredentials.Email' Error:Field validation for 'Email' failed on the 'required' tag"},
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'AuthCredentials.Email' Error:Field validation for 'Email' failed on the 'required' tag"},
 		},
 		{
 			name:            "Bad request: empty password",
@@ -329,19 +352,57 @@ func TestAuthHandler_signIn(t *testing.T) {
 			},
 			expStatCode: 400,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: "Key: 'AuthCredentials.Password' Error:Field validation for 'Password' failed on the 'required' tag"},
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'AuthCredentials.Password' Error:Field validation for 'Password' failed on the 'required' tag"},
+		},
+		{
+			name:            "Unauthorized: invalid credentials",
+			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
+			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.AuthCredentials) {
+				s.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, service.ErrInvalidCredentials)
+				l.EXPECT().Error(service.ErrInvalidCredentials)
+			},
+			expStatCode: 401,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrInvalidCredentials),
+		},
+		{
+			name:            "Forbidden: deletion scheduled",
+			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
+			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.AuthCredentials) {
+				s.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, service.ErrAccountPendingDeletion)
+				l.EXPECT().Error(service.ErrAccountPendingDeletion)
+			},
+			expStatCode: 403,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrAccountPendingDeletion),
+		},
+		{
+			name:            "Too many requests: locked out",
+			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
+			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.AuthCredentials) {
+				err := &service.LockoutError{RetryAfter: 90*time.Second + time.Millisecond}
+				s.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, err)
+				l.EXPECT().Error(err)
+			},
+			expStatCode:   429,
+			isErr:         true,
+			errReqBody:    errorBody(service.ErrTooManyAttempts),
+			expRetryAfter: "91",
 		},
 		{
 			name:            "Server error: Service Sign In error",
 			inputBody:       `{"email":"Cheasezz@gmail.com","password":"qwerty123456"}`,
 			AuthCredentials: core.AuthCredentials{Email: "Cheasezz@gmail.com", Password: "qwerty123456"},
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.AuthCredentials) {
-				s.EXPECT().SignIn(gomock.Any(), input).Return(auth.Tokens{}, errServiceSignIn)
+				s.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, errServiceSignIn)
 				l.EXPECT().Error(errServiceSignIn)
 			},
 			expStatCode: 500,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errServiceSignIn.Error()},
+			errReqBody:  errorBody(errServiceSignIn),
 		},
 	}
 	for _, tt := range tests {
@@ -353,6 +414,7 @@ func TestAuthHandler_signIn(t *testing.T) {
 			r.ServeHTTP(w, req)
 
 			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expRetryAfter, w.Header().Get("Retry-After"))
 			if tt.isErr {
 				res, _ := json.Marshal(tt.errReqBody)
 				require.Equal(t, w.Body.String(), string(res))
@@ -395,9 +457,21 @@ func TestAuth_logOut(t *testing.T) {
 			name:        "empty cookie name",
 			expStatCode: 401,
 			isErr:       true,
-			errRqBody:   ErrorResponse{Message: http.ErrNoCookie.Error()},
+			errRqBody:   errorBody(errNoRefreshToken),
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, rt string, expTkn auth.Tokens) {
-				l.EXPECT().Error(http.ErrNoCookie)
+				l.EXPECT().Error(errNoRefreshToken)
+			},
+		},
+		{
+			name:        "expired refresh token",
+			cookieName:  rtCookieName,
+			rToken:      "token",
+			expStatCode: 401,
+			isErr:       true,
+			errRqBody:   errorBody(service.ErrRefreshTokenExpired),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, rt string, expTkn auth.Tokens) {
+				s.EXPECT().LogOut(gomock.Any(), rt).Return(expTkn, service.ErrRefreshTokenExpired)
+				l.EXPECT().Error(service.ErrRefreshTokenExpired)
 			},
 		},
 		{
@@ -406,7 +480,7 @@ func TestAuth_logOut(t *testing.T) {
 			rToken:      "token",
 			expStatCode: 500,
 			isErr:       true,
-			errRqBody:   ErrorResponse{Message: errServiceLogOut.Error()},
+			errRqBody:   errorBody(errServiceLogOut),
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, rt string, expTkn auth.Tokens) {
 				s.EXPECT().LogOut(gomock.Any(), rt).Return(expTkn, errServiceLogOut)
 				l.EXPECT().Error(errServiceLogOut)
@@ -464,7 +538,7 @@ func TestAuth_refreshAccessToken(t *testing.T) {
 		{
 			name: "ok (upd both tokens)",
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, refreshToken string) {
-				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken).Return(tokens, nil)
+				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken, gomock.Any()).Return(tokens, nil)
 			},
 			cookieName:   "RefreshToken",
 			refreshToken: "token",
@@ -472,35 +546,49 @@ func TestAuth_refreshAccessToken(t *testing.T) {
 			okReqBody:    tokens.Access,
 		},
 		{
-			name: "ok (upd access token only)",
+			name: "StatusUnauthorized: refresh token reused",
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, refreshToken string) {
-				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken).Return(auth.Tokens{Access: auth.ATknInfo{Token: "token"}}, nil)
+				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken, gomock.Any()).Return(auth.Tokens{}, service.ErrRefreshTokenReused)
+				l.EXPECT().Error(service.ErrRefreshTokenReused)
 			},
 			cookieName:   "RefreshToken",
 			refreshToken: "token",
-			expStatCode:  200,
-			okReqBody:    tokens.Access,
+			expStatCode:  401,
+			isErr:        true,
+			errReqBody:   errorBody(service.ErrRefreshTokenReused),
+		},
+		{
+			name: "StatusUnauthorized: invalid refresh token",
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, refreshToken string) {
+				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken, gomock.Any()).Return(auth.Tokens{}, service.ErrInvalidRefreshToken)
+				l.EXPECT().Error(service.ErrInvalidRefreshToken)
+			},
+			cookieName:   "RefreshToken",
+			refreshToken: "token",
+			expStatCode:  401,
+			isErr:        true,
+			errReqBody:   errorBody(service.ErrInvalidRefreshToken),
 		},
 		{
 			name: "StatusUnauthorized: empty cookie name",
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, refreshToken string) {
-				l.EXPECT().Error(http.ErrNoCookie)
+				l.EXPECT().Error(errNoRefreshToken)
 			},
 			expStatCode: 401,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: http.ErrNoCookie.Error()},
+			errReqBody:  errorBody(errNoRefreshToken),
 		},
 		{
 			name: "Server error: Service Refresh Access Token error",
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, refreshToken string) {
-				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken).Return(auth.Tokens{}, errServiceRefreshAccessToken).Times(1)
+				s.EXPECT().RefreshAccessToken(gomock.Any(), refreshToken, gomock.Any()).Return(auth.Tokens{}, errServiceRefreshAccessToken).Times(1)
 				l.EXPECT().Error(errServiceRefreshAccessToken)
 			},
 			cookieName:   "RefreshToken",
 			refreshToken: "token",
 			expStatCode:  500,
 			isErr:        true,
-			errReqBody:   ErrorResponse{Message: errServiceRefreshAccessToken.Error()},
+			errReqBody:   errorBody(errServiceRefreshAccessToken),
 		},
 	}
 	for _, tt := range tests {
@@ -555,9 +643,9 @@ func TestAuth_me(t *testing.T) {
 			name:        "ok",
 			accessToken: "acToken",
 			expStatCode: 200,
-			okReqBody:   userResponse{User: core.User{Email: "kappa@gmail.com", Username: "qwertasd", PasswordHash: "fj487sj"}},
+			okReqBody:   userResponse{User: profileResponse{Email: "kappa@gmail.com", Username: "qwertasd"}},
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
-				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testUUID.String(), nil).Times(1)
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil).Times(1)
 				s.EXPECT().GetUser(gomock.Any(), testUUID).Return(core.User{
 					Id:           testUUID,
 					Email:        "kappa@gmail.com",
@@ -569,11 +657,11 @@ func TestAuth_me(t *testing.T) {
 		{
 			name:        "error service GetUser",
 			accessToken: "acToken",
-			expStatCode: 401,
+			expStatCode: 500,
 			isErr:       true,
-			errReqBody:  ErrorResponse{Message: errServiceGetUser.Error()},
+			errReqBody:  errorBody(errServiceGetUser),
 			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
-				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testUUID.String(), nil).Times(1)
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil).Times(1)
 				s.EXPECT().GetUser(gomock.Any(), testUUID).Return(core.User{}, errServiceGetUser).Times(1)
 				l.EXPECT().Error(errServiceGetUser)
 			},
@@ -596,6 +684,535 @@ func TestAuth_me(t *testing.T) {
 			} else {
 				res, _ := json.Marshal(tt.okReqBody)
 				require.Equal(t, w.Body.String(), string(res))
+				require.NotContains(t, w.Body.String(), "fj487sj")
+			}
+		})
+	}
+}
+
+func TestAuth_genPassResetCode(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, email string)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name          string
+		inputBody     string
+		email         string
+		expStatCode   int
+		isErr         bool
+		errReqBody    ErrorResponse
+		expRetryAfter string
+		mockBehavior  mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"email":"kappa@example.com"}`,
+			email:       "kappa@example.com",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, email string) {
+				s.EXPECT().GenPassResetCode(gomock.Any(), email).Return(nil)
+			},
+		},
+		{
+			name:        "Too many requests: locked out",
+			inputBody:   `{"email":"kappa@example.com"}`,
+			email:       "kappa@example.com",
+			expStatCode: 429,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrTooManyAttempts),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, email string) {
+				err := &service.LockoutError{RetryAfter: time.Minute}
+				s.EXPECT().GenPassResetCode(gomock.Any(), email).Return(err)
+				l.EXPECT().Error(err)
+			},
+			expRetryAfter: "60",
+		},
+		{
+			name:        "Server error: service gen pass reset code error",
+			inputBody:   `{"email":"kappa@example.com"}`,
+			email:       "kappa@example.com",
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceGenPassResetCode),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, email string) {
+				s.EXPECT().GenPassResetCode(gomock.Any(), email).Return(errServiceGenPassResetCode)
+				l.EXPECT().Error(errServiceGenPassResetCode)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.email)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/genpasrcode", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expRetryAfter, w.Header().Get("Retry-After"))
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_resetPassword(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name         string
+		inputBody    string
+		input        core.PassResetCredentials
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"email":"kappa@example.com","code":"code","password":"qwerty123456"}`,
+			input:       core.PassResetCredentials{Email: "kappa@example.com", Code: "code", Password: "qwerty123456"},
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials) {
+				s.EXPECT().ResetPassword(gomock.Any(), input).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			inputBody:   `{"email":"kappa@example.com","password":"qwerty123456"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'PassResetCredentials.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: short password",
+			inputBody:   `{"email":"kappa@example.com","code":"code","password":"qwerty"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(errShortPass),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials) {
+				l.EXPECT().Error(errShortPass)
+			},
+		},
+		{
+			name:        "Bad request: code expired",
+			inputBody:   `{"email":"kappa@example.com","code":"code","password":"qwerty123456"}`,
+			input:       core.PassResetCredentials{Email: "kappa@example.com", Code: "code", Password: "qwerty123456"},
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrPassResetCodeExpired),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials) {
+				s.EXPECT().ResetPassword(gomock.Any(), input).Return(service.ErrPassResetCodeExpired)
+				l.EXPECT().Error(service.ErrPassResetCodeExpired)
+			},
+		},
+		{
+			name:        "Server error: service reset password error",
+			inputBody:   `{"email":"kappa@example.com","code":"code","password":"qwerty123456"}`,
+			input:       core.PassResetCredentials{Email: "kappa@example.com", Code: "code", Password: "qwerty123456"},
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceResetPassword),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.PassResetCredentials) {
+				s.EXPECT().ResetPassword(gomock.Any(), input).Return(errServiceResetPassword)
+				l.EXPECT().Error(errServiceResetPassword)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.input)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/resetpass", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_verifyEmail(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.EmailVerifyCredentials)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name         string
+		inputBody    string
+		input        core.EmailVerifyCredentials
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"email":"kappa@example.com","code":"code"}`,
+			input:       core.EmailVerifyCredentials{Email: "kappa@example.com", Code: "code"},
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.EmailVerifyCredentials) {
+				s.EXPECT().VerifyEmail(gomock.Any(), input).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			inputBody:   `{"email":"kappa@example.com"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'EmailVerifyCredentials.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.EmailVerifyCredentials) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: invalid code",
+			inputBody:   `{"email":"kappa@example.com","code":"code"}`,
+			input:       core.EmailVerifyCredentials{Email: "kappa@example.com", Code: "code"},
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrInvalidEmailVerifyCode),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.EmailVerifyCredentials) {
+				s.EXPECT().VerifyEmail(gomock.Any(), input).Return(service.ErrInvalidEmailVerifyCode)
+				l.EXPECT().Error(service.ErrInvalidEmailVerifyCode)
+			},
+		},
+		{
+			name:        "Server error: service verify email error",
+			inputBody:   `{"email":"kappa@example.com","code":"code"}`,
+			input:       core.EmailVerifyCredentials{Email: "kappa@example.com", Code: "code"},
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceVerifyEmail),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.EmailVerifyCredentials) {
+				s.EXPECT().VerifyEmail(gomock.Any(), input).Return(errServiceVerifyEmail)
+				l.EXPECT().Error(errServiceVerifyEmail)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.input)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/verifyemail", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_resendEmailVerifyCode(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		accessToken  string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			accessToken: "acToken",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().ResendEmailVerifyCode(gomock.Any(), testUUID).Return(nil)
+			},
+		},
+		{
+			name:        "Conflict: email already verified",
+			accessToken: "acToken",
+			expStatCode: 409,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrEmailAlreadyVerified),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().ResendEmailVerifyCode(gomock.Any(), testUUID).Return(service.ErrEmailAlreadyVerified)
+				l.EXPECT().Error(service.ErrEmailAlreadyVerified)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.accessToken)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/resendverify", nil)
+			req.Header.Add(authorizationHeader, fmt.Sprintf("Bearer %s", tt.accessToken))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_getSessions(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	sessions := []core.Session{
+		{UserId: testUUID, RefreshToken: "token", FamilyId: uuid.New(), CreatedAt: now, LastUsedAt: now, UserAgent: "Firefox", IP: "10.0.0.1"},
+		{UserId: testUUID, RefreshToken: "otherToken", FamilyId: uuid.New(), CreatedAt: now, LastUsedAt: now, UserAgent: "Chrome", IP: "10.0.0.2"},
+	}
+	tests := []struct {
+		name         string
+		accessToken  string
+		refreshToken string
+		expStatCode  int
+		okReqBody    sessionsResponse
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:         "ok (current session marked)",
+			accessToken:  "acToken",
+			refreshToken: "token",
+			expStatCode:  200,
+			okReqBody: sessionsResponse{Sessions: []sessionResponse{
+				{Id: sessions[0].FamilyId, UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: now, LastUsedAt: now, Current: true},
+				{Id: sessions[1].FamilyId, UserAgent: "Chrome", IP: "10.0.0.2", CreatedAt: now, LastUsedAt: now},
+			}},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().GetSessions(gomock.Any(), testUUID).Return(sessions, nil)
+			},
+		},
+		{
+			name:        "ok (without refresh token cookie)",
+			accessToken: "acToken",
+			expStatCode: 200,
+			okReqBody: sessionsResponse{Sessions: []sessionResponse{
+				{Id: sessions[0].FamilyId, UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: now, LastUsedAt: now},
+				{Id: sessions[1].FamilyId, UserAgent: "Chrome", IP: "10.0.0.2", CreatedAt: now, LastUsedAt: now},
+			}},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().GetSessions(gomock.Any(), testUUID).Return(sessions, nil)
+			},
+		},
+		{
+			name:        "Server error: service GetSessions error",
+			accessToken: "acToken",
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceGetSessions),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().GetSessions(gomock.Any(), testUUID).Return(nil, errServiceGetSessions)
+				l.EXPECT().Error(errServiceGetSessions)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.accessToken)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/sessions", nil)
+			req.Header.Add(authorizationHeader, fmt.Sprintf("Bearer %s", tt.accessToken))
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: rtCookieName, Value: tt.refreshToken})
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				res, _ := json.Marshal(tt.okReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			}
+		})
+	}
+}
+
+func TestAuth_revokeSession(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	sessionId := uuid.New()
+	tests := []struct {
+		name         string
+		accessToken  string
+		sessionId    string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			accessToken: "acToken",
+			sessionId:   sessionId.String(),
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().RevokeSession(gomock.Any(), testUUID, sessionId).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: invalid session id",
+			accessToken: "acToken",
+			sessionId:   "qwerty",
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(errInvalidSessionId),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				l.EXPECT().Error(errInvalidSessionId)
+			},
+		},
+		{
+			name:        "Not found: session not found",
+			accessToken: "acToken",
+			sessionId:   sessionId.String(),
+			expStatCode: 404,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrSessionNotFound),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().RevokeSession(gomock.Any(), testUUID, sessionId).Return(service.ErrSessionNotFound)
+				l.EXPECT().Error(service.ErrSessionNotFound)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.accessToken)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions/"+tt.sessionId, nil)
+			req.Header.Add(authorizationHeader, fmt.Sprintf("Bearer %s", tt.accessToken))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_revokeOtherSessions(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		accessToken  string
+		refreshToken string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:         "ok",
+			accessToken:  "acToken",
+			refreshToken: "token",
+			expStatCode:  200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().RevokeOtherSessions(gomock.Any(), testUUID, "token").Return(nil)
+			},
+		},
+		{
+			name:        "StatusUnauthorized: no refresh token cookie",
+			accessToken: "acToken",
+			expStatCode: 401,
+			isErr:       true,
+			errReqBody:  errorBody(errNoRefreshToken),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				l.EXPECT().Error(errNoRefreshToken)
+			},
+		},
+		{
+			name:         "StatusUnauthorized: invalid refresh token",
+			accessToken:  "acToken",
+			refreshToken: "token",
+			expStatCode:  401,
+			isErr:        true,
+			errReqBody:   errorBody(service.ErrInvalidRefreshToken),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, accessToken string) {
+				mockDeps.tmm.EXPECT().Parse(accessToken).Return(testClaims(testUUID.String()), nil)
+				s.EXPECT().RevokeOtherSessions(gomock.Any(), testUUID, "token").Return(service.ErrInvalidRefreshToken)
+				l.EXPECT().Error(service.ErrInvalidRefreshToken)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.accessToken)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions", nil)
+			req.Header.Add(authorizationHeader, fmt.Sprintf("Bearer %s", tt.accessToken))
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: rtCookieName, Value: tt.refreshToken})
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
 			}
 		})
 	}
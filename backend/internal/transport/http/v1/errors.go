@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+)
+
+// Code of request body or query that failed binding.
+const codeInvalidRequest = "invalid_request"
+
+var (
+	// Errors that aren't *core.Error are hidden behind this one.
+	errInternal = core.NewError("", "internal", "internal server error")
+
+	errNoRefreshToken = core.NewError(core.ErrUnauthorized, "no_refresh_token", "refresh token cookie is missing")
+	errPageNotFound   = core.NewError(core.ErrNotFound, "not_found", "Page not found")
+
+	// pkg/auth knows nothing about core, message of expired token is kept for clients relying on it.
+	errTokenExpired = core.NewError(core.ErrUnauthorized, "token_expired", auth.ErrTokenExpired.Error())
+	errTokenInvalid = core.NewError(core.ErrUnauthorized, "invalid_token", auth.ErrTokenInvalid.Error())
+)
+
+var kindStatus = map[core.ErrorKind]int{
+	core.ErrValidation:   http.StatusBadRequest,
+	core.ErrUnauthorized: http.StatusUnauthorized,
+	core.ErrForbidden:    http.StatusForbidden,
+	core.ErrNotFound:     http.StatusNotFound,
+	core.ErrConflict:     http.StatusConflict,
+	core.ErrRateLimited:  http.StatusTooManyRequests,
+}
+
+// Wrap error of request binding. Its message describes invalid field, so it is shown to client.
+func errInvalidRequest(err error) error {
+	return &core.Error{Kind: core.ErrValidation, Code: codeInvalidRequest, Message: err.Error(), Err: err}
+}
+
+// Translate error into status code and domain error shown to client.
+// Unknown errors are internal, client gets 500 without their details.
+func translateError(err error) (int, *core.Error) {
+	var domainErr *core.Error
+	switch {
+	case errors.As(err, &domainErr):
+	case errors.Is(err, auth.ErrTokenExpired):
+		domainErr = errTokenExpired
+	case errors.Is(err, auth.ErrTokenInvalid):
+		domainErr = errTokenInvalid
+	default:
+		return http.StatusInternalServerError, errInternal
+	}
+
+	status, ok := kindStatus[domainErr.Kind]
+	if !ok {
+		return http.StatusInternalServerError, errInternal
+	}
+	return status, domainErr
+}
@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/repository/psql"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// Expected body of error response.
+func errorBody(err error) ErrorResponse {
+	_, domainErr := translateError(err)
+	return ErrorResponse{Code: domainErr.Code, Message: domainErr.Message}
+}
+
+func errorJSON(err error) string {
+	res, _ := json.Marshal(errorBody(err))
+	return string(res)
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expStatus  int
+		expCode    string
+		expMessage string
+	}{
+		{
+			name:       "validation",
+			err:        service.ErrInvalidPassResetCode,
+			expStatus:  http.StatusBadRequest,
+			expCode:    "invalid_pass_reset_code",
+			expMessage: "invalid password reset code",
+		},
+		{
+			name:       "binding",
+			err:        errInvalidRequest(fmt.Errorf("Key: 'TOTPCode.Code' Error:Field validation")),
+			expStatus:  http.StatusBadRequest,
+			expCode:    codeInvalidRequest,
+			expMessage: "Key: 'TOTPCode.Code' Error:Field validation",
+		},
+		{
+			name:       "unauthorized",
+			err:        service.ErrInvalidCredentials,
+			expStatus:  http.StatusUnauthorized,
+			expCode:    "invalid_credentials",
+			expMessage: "invalid email or password",
+		},
+		{
+			name:       "forbidden",
+			err:        service.ErrWrongPassword,
+			expStatus:  http.StatusForbidden,
+			expCode:    "wrong_password",
+			expMessage: "current password is incorrect",
+		},
+		{
+			name:       "not found",
+			err:        fmt.Errorf("get user: %w", psql.ErrNotFound),
+			expStatus:  http.StatusNotFound,
+			expCode:    "not_found",
+			expMessage: "not found",
+		},
+		{
+			name:       "conflict",
+			err:        service.ErrEmailTaken,
+			expStatus:  http.StatusConflict,
+			expCode:    "email_taken",
+			expMessage: "email is already taken",
+		},
+		{
+			name:       "lockout",
+			err:        &service.LockoutError{RetryAfter: time.Minute},
+			expStatus:  http.StatusTooManyRequests,
+			expCode:    "too_many_attempts",
+			expMessage: "too many attempts, try again later",
+		},
+		{
+			name:       "username change",
+			err:        &service.UsernameChangeError{RetryAfter: time.Minute},
+			expStatus:  http.StatusTooManyRequests,
+			expCode:    "username_change_too_soon",
+			expMessage: "username was changed recently",
+		},
+		{
+			name:       "expired access token",
+			err:        auth.ErrTokenExpired,
+			expStatus:  http.StatusUnauthorized,
+			expCode:    "token_expired",
+			expMessage: "Token is expired",
+		},
+		{
+			name:       "invalid access token",
+			err:        fmt.Errorf("parse: %w", auth.ErrTokenInvalid),
+			expStatus:  http.StatusUnauthorized,
+			expCode:    "invalid_token",
+			expMessage: "invalid access token",
+		},
+		{
+			name:       "internal",
+			err:        fmt.Errorf("no rows in result set"),
+			expStatus:  http.StatusInternalServerError,
+			expCode:    "internal",
+			expMessage: "internal server error",
+		},
+		{
+			name:       "unknown kind",
+			err:        core.NewError("unknown", "unknown", "unknown"),
+			expStatus:  http.StatusInternalServerError,
+			expCode:    "internal",
+			expMessage: "internal server error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, domainErr := translateError(tt.err)
+
+			require.Equal(t, tt.expStatus, status)
+			require.Equal(t, tt.expCode, domainErr.Code)
+			require.Equal(t, tt.expMessage, domainErr.Message)
+		})
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	r := gin.New()
+	r.NoRoute(NoRoute)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/unknown", nil)
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, `{"code":"not_found","message":"Page not found"}`, w.Body.String())
+}
@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"time"
+
 	"github.com/Cheasezz/anSpace/backend/config"
 	"github.com/Cheasezz/anSpace/backend/internal/service"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
@@ -12,13 +14,18 @@ type Handlers struct {
 	Config config.HTTP
 	*Middlewares
 	*Auth
+	*Users
+	*Admin
 }
 
 type Deps struct {
-	Services     *service.Services
-	TokenManager auth.TokenManager
-	ConfigHTTP   config.HTTP
-	Log          logger.Logger
+	Services        *service.Services
+	TokenManager    auth.TokenManager
+	ConfigHTTP      config.HTTP
+	ConfigRateLimit config.RateLimit
+	// Token refresh rate limit is checked against it.
+	AccessTokenTTL time.Duration
+	Log            logger.Logger
 }
 
 func NewHandlers(d Deps) *Handlers {
@@ -27,6 +34,8 @@ func NewHandlers(d Deps) *Handlers {
 		Config:      d.ConfigHTTP,
 		Middlewares: mdlwrs,
 		Auth:        NewAuthHandler(d, mdlwrs),
+		Users:       NewUsersHandler(d, mdlwrs),
+		Admin:       NewAdminHandler(d, mdlwrs),
 	}
 }
 
@@ -35,5 +44,7 @@ func (h *Handlers) InitRoutes(router *gin.RouterGroup) {
 	v1 := router.Group("/v1")
 	{
 		h.initAuthRoutes(v1)
+		h.initUsersRoutes(v1)
+		h.initAdminRoutes(v1)
 	}
 }
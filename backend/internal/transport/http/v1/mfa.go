@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Auth) initMFARoutes(auth *gin.RouterGroup, limit gin.HandlerFunc) {
+	mfa := auth.Group("/2fa")
+	{
+		mfa.POST("/enroll", h.mdlwrs.userIdentity, h.mdlwrs.emailVerified, limit, h.enrollTOTP)
+		mfa.POST("/confirm", h.mdlwrs.userIdentity, h.mdlwrs.emailVerified, limit, h.confirmTOTP)
+		mfa.POST("/verify", limit, h.verifyMFA)
+		mfa.DELETE("", h.mdlwrs.userIdentity, limit, h.disableTOTP)
+	}
+}
+
+// @Tags 2fa
+// @Summary enroll TOTP
+// @Description generate new TOTP secret of current user. It is enabled after confirmation with first code
+// @ID enroll-totp
+// @Produce  json
+// @Success 200 {object} totpEnrollmentResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "email not verified"
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/2fa/enroll [post]
+func (h *Auth) enrollTOTP(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(c, usrId)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTOTPEnrollmentResponse(enrollment))
+}
+
+// @Tags 2fa
+// @Summary confirm TOTP
+// @Description check first code of enrolled TOTP secret, enable 2fa and return recovery codes. They are shown only once
+// @ID confirm-totp
+// @Accept  json
+// @Produce  json
+// @Param input body core.TOTPCode true "code from authenticator app"
+// @Success 200 {object} recoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "email not verified"
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/2fa/confirm [post]
+func (h *Auth) confirmTOTP(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.TOTPCode
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTP(c, usrId, input.Code)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Tags 2fa
+// @Summary verify second factor
+// @Description exchange mfa pending token from signin and TOTP or recovery code for access token in JSON and refresh token in cookies. Pending token and TOTP code are accepted once
+// @ID verify-mfa
+// @Accept  json
+// @Produce  json
+// @Param input body core.MFACredentials true "mfa token and code"
+// @Success 200 {object} auth.ATknInfo
+// @Header 200 {string} Set-Cookie "refreshToken. Example: "RefreshToken=9838c59cff93e21; Path=/; Max-Age=2628000; HttpOnly; Secure; SameSite=None" "
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "account deletion is scheduled"
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until email or ip is unlocked"
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/auth/2fa/verify [post]
+func (h *Auth) verifyMFA(c *gin.Context) {
+	var input core.MFACredentials
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	tokens, err := h.service.VerifyMFA(c, input, deviceFromCtx(c))
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	newTokenResponse(c, tokens, h.config)
+}
+
+// @Tags 2fa
+// @Summary disable TOTP
+// @Description check TOTP or recovery code, disable 2fa and delete recovery codes
+// @ID disable-totp
+// @Accept  json
+// @Param input body core.TOTPCode true "code from authenticator app or recovery code"
+// @Success 200 "2fa disabled"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/2fa [delete]
+func (h *Auth) disableTOTP(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.TOTPCode
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	if err := h.service.DisableTOTP(c, usrId, input.Code, deviceFromCtx(c)); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
@@ -0,0 +1,351 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	errServiceEnrollTOTP  = fmt.Errorf("service enrollTOTP error")
+	errServiceConfirmTOTP = fmt.Errorf("service confirmTOTP error")
+	errServiceVerifyMFA   = fmt.Errorf("service verifyMFA error")
+	errServiceDisableTOTP = fmt.Errorf("service disableTOTP error")
+)
+
+func TestAuthHandler_signInMFARequired(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	input := core.AuthCredentials{Email: "user@example.com", Password: "qwerty123456"}
+	mockDeps.sam.EXPECT().SignIn(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, &service.MFARequiredError{Token: "mfaToken"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/signin", bytes.NewBufferString(`{"email":"user@example.com","password":"qwerty123456"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Equal(t, `{"mfaRequired":true,"mfaToken":"mfaToken"}`, w.Body.String())
+	require.Empty(t, w.Result().Cookies())
+}
+
+func TestAuth_enrollTOTP(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	enrollment := core.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/anspace:user@example.com?secret=SECRET"}
+	tests := []struct {
+		name         string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			expReqBody:  enrollment,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().EnrollTOTP(gomock.Any(), testUUID).Return(enrollment, nil)
+			},
+		},
+		{
+			name:        "Conflict: already enabled",
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrTOTPAlreadyEnabled),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().EnrollTOTP(gomock.Any(), testUUID).Return(core.TOTPEnrollment{}, service.ErrTOTPAlreadyEnabled)
+				l.EXPECT().Error(service.ErrTOTPAlreadyEnabled)
+			},
+		},
+		{
+			name:        "Server error: service enroll error",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceEnrollTOTP),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().EnrollTOTP(gomock.Any(), testUUID).Return(core.TOTPEnrollment{}, errServiceEnrollTOTP)
+				l.EXPECT().Error(errServiceEnrollTOTP)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/enroll", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAuth_confirmTOTP(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	codes := []string{"1a2b3-c4d5e", "f6a7b-8c9d0"}
+	tests := []struct {
+		name         string
+		inputBody    string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"code":"123456"}`,
+			expStatCode: 200,
+			expReqBody:  recoveryCodesResponse{RecoveryCodes: codes},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmTOTP(gomock.Any(), testUUID, "123456").Return(codes, nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			inputBody:   `{}`,
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'TOTPCode.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Unauthorized: invalid code",
+			inputBody:   `{"code":"123456"}`,
+			expStatCode: 401,
+			expReqBody:  errorBody(service.ErrInvalidMFACode),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmTOTP(gomock.Any(), testUUID, "123456").Return(nil, service.ErrInvalidMFACode)
+				l.EXPECT().Error(service.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:        "Conflict: already enabled",
+			inputBody:   `{"code":"123456"}`,
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrTOTPAlreadyEnabled),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmTOTP(gomock.Any(), testUUID, "123456").Return(nil, service.ErrTOTPAlreadyEnabled)
+				l.EXPECT().Error(service.ErrTOTPAlreadyEnabled)
+			},
+		},
+		{
+			name:        "Server error: service confirm error",
+			inputBody:   `{"code":"123456"}`,
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceConfirmTOTP),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmTOTP(gomock.Any(), testUUID, "123456").Return(nil, errServiceConfirmTOTP)
+				l.EXPECT().Error(errServiceConfirmTOTP)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/confirm", bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAuth_verifyMFA(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name          string
+		inputBody     string
+		input         core.MFACredentials
+		expStatCode   int
+		expReqBody    interface{}
+		expRetryAfter string
+		mockBehavior  mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"mfaToken":"mfaToken","code":"123456"}`,
+			input:       core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expStatCode: 200,
+			expReqBody:  tokens.Access,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				s.EXPECT().VerifyMFA(gomock.Any(), input, gomock.Any()).Return(tokens, nil)
+			},
+		},
+		{
+			name:        "Bad request: empty mfa token",
+			inputBody:   `{"code":"123456"}`,
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'MFACredentials.MFAToken' Error:Field validation for 'MFAToken' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Unauthorized: invalid code",
+			inputBody:   `{"mfaToken":"mfaToken","code":"123456"}`,
+			input:       core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expStatCode: 401,
+			expReqBody:  errorBody(service.ErrInvalidMFACode),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				s.EXPECT().VerifyMFA(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, service.ErrInvalidMFACode)
+				l.EXPECT().Error(service.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:        "Unauthorized: expired mfa token",
+			inputBody:   `{"mfaToken":"mfaToken","code":"123456"}`,
+			input:       core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expStatCode: 401,
+			expReqBody:  errorBody(auth.ErrTokenExpired),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				s.EXPECT().VerifyMFA(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, auth.ErrTokenExpired)
+				l.EXPECT().Error(auth.ErrTokenExpired)
+			},
+		},
+		{
+			name:        "Too many requests: locked out",
+			inputBody:   `{"mfaToken":"mfaToken","code":"123456"}`,
+			input:       core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expStatCode: 429,
+			expReqBody:  errorBody(service.ErrTooManyAttempts),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				err := &service.LockoutError{RetryAfter: 2 * time.Minute}
+				s.EXPECT().VerifyMFA(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, err)
+				l.EXPECT().Error(err)
+			},
+			expRetryAfter: "120",
+		},
+		{
+			name:        "Server error: service verify error",
+			inputBody:   `{"mfaToken":"mfaToken","code":"123456"}`,
+			input:       core.MFACredentials{MFAToken: "mfaToken", Code: "123456"},
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceVerifyMFA),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger, input core.MFACredentials) {
+				s.EXPECT().VerifyMFA(gomock.Any(), input, gomock.Any()).Return(auth.Tokens{}, errServiceVerifyMFA)
+				l.EXPECT().Error(errServiceVerifyMFA)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm, tt.input)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/verify", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expRetryAfter, w.Header().Get("Retry-After"))
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAuth_disableTOTP(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().DisableTOTP(gomock.Any(), testUUID, "123456", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:        "Conflict: not enabled",
+			expStatCode: 409,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrTOTPNotEnabled),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().DisableTOTP(gomock.Any(), testUUID, "123456", gomock.Any()).Return(service.ErrTOTPNotEnabled)
+				l.EXPECT().Error(service.ErrTOTPNotEnabled)
+			},
+		},
+		{
+			name:        "Unauthorized: invalid code",
+			expStatCode: 401,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrInvalidMFACode),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().DisableTOTP(gomock.Any(), testUUID, "123456", gomock.Any()).Return(service.ErrInvalidMFACode)
+				l.EXPECT().Error(service.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:        "Server error: service disable error",
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceDisableTOTP),
+			mockBehavior: func(s *mock_service.MockAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().DisableTOTP(gomock.Any(), testUUID, "123456", gomock.Any()).Return(errServiceDisableTOTP)
+				l.EXPECT().Error(errServiceDisableTOTP)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.sam, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/auth/2fa", bytes.NewBufferString(`{"code":"123456"}`))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
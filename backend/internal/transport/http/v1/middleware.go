@@ -1,75 +1,241 @@
 package v1
 
 import (
-	"fmt"
-	"net/http"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+	"github.com/Cheasezz/anSpace/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 const (
 	authorizationHeader = "Authorization"
-	userCtx             = "userId"
+	claimsCtx           = "claims"
+)
+
+// Route groups of rate limit rules in config.
+const (
+	rateGroupAuth = "auth"
+	rateGroupRead = "read"
+	// Token refresh is counted per session (token family), clients behind
+	// one ip refresh independently. Session refreshes once per access token TTL.
+	rateGroupRefresh = "refresh"
+	// Password reset codes are sent per ip at slow steady rate,
+	// so clients behind one ip aren't locked out for long.
+	rateGroupPassReset = "passreset"
 )
 
 var (
-	errEmptyAuthHeader      = fmt.Errorf("empty auth header")
-	errInvalidAuthHeader    = fmt.Errorf("invalid auth header")
-	errUserIdNotFound       = fmt.Errorf("user id not found")
-	errAccessTokenIsExpired = fmt.Errorf("Token is expired")
+	errEmptyAuthHeader   = core.NewError(core.ErrUnauthorized, "empty_auth_header", "empty auth header")
+	errInvalidAuthHeader = core.NewError(core.ErrUnauthorized, "invalid_auth_header", "invalid auth header")
+	errUserIdNotFound    = core.NewError(core.ErrUnauthorized, "user_id_not_found", "user id not found")
+	errEmailNotVerified  = core.NewError(core.ErrForbidden, "email_not_verified", "email not verified")
+	errTokenRevoked      = core.NewError(core.ErrUnauthorized, "token_revoked", "token is revoked")
+	errRateLimitExceeded = core.NewError(core.ErrRateLimited, "rate_limit_exceeded", "rate limit exceeded")
 )
 
 type Middlewares struct {
 	TokenManager auth.TokenManager
+	revocation   service.Revocation
+	limiters     map[string]*ratelimit.Limiter
 	log          logger.Logger
 }
 
 func NewMiddlewares(d Deps) *Middlewares {
+	limiters := make(map[string]*ratelimit.Limiter)
+	if d.ConfigRateLimit.Enabled {
+		for group, rule := range d.ConfigRateLimit.Groups {
+			if rule.Rate > 0 && rule.Burst > 0 {
+				limiters[group] = ratelimit.New(rule.Rate, rule.Burst)
+			}
+		}
+		if rule, ok := d.ConfigRateLimit.Groups[rateGroupRefresh]; ok && d.AccessTokenTTL > 0 &&
+			rule.Rate*d.AccessTokenTTL.Seconds() < 1 {
+			d.Log.Warn("rate limit of %s group is below one request per access token TTL (%s), sessions will be logged out",
+				rateGroupRefresh, d.AccessTokenTTL)
+		}
+	}
+
 	return &Middlewares{
 		TokenManager: d.TokenManager,
+		revocation:   d.Services.Revocation,
+		limiters:     limiters,
 		log:          d.Log,
 	}
 }
 
-// Middleware for identify user with auth header
+// Middleware for identify user with auth header.
+// Tokens of revoked sessions are rejected.
+// Put access token claims into gin context.
 func (m *Middlewares) userIdentity(c *gin.Context) {
 	header := c.GetHeader(authorizationHeader)
 	if header == "" {
-		newErrorResponse(c, m.log, http.StatusUnauthorized, errEmptyAuthHeader)
+		newErrorResponse(c, m.log, errEmptyAuthHeader)
 		return
 	}
 	headerParts := strings.Split(header, " ")
 	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-		newErrorResponse(c, m.log, http.StatusUnauthorized, errInvalidAuthHeader)
+		newErrorResponse(c, m.log, errInvalidAuthHeader)
 		return
 	}
 
-	userId, err := m.TokenManager.Parse(headerParts[1])
+	claims, err := m.TokenManager.Parse(headerParts[1])
 	if err != nil {
-		if err.Error() == errAccessTokenIsExpired.Error() {
-			newErrorResponse(c, m.log, http.StatusUnauthorized, err)
+		newErrorResponse(c, m.log, err)
+		return
+	}
+
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		newErrorResponse(c, m.log, errTokenInvalid)
+		return
+	}
+	if m.revocation.IsRevoked(sessionId) {
+		newErrorResponse(c, m.log, errTokenRevoked)
+		return
+	}
+
+	c.Set(claimsCtx, claims)
+}
+
+// Middleware for limit request rate of route group with token bucket.
+// Requests are counted per user id when used after userIdentity, per ip otherwise.
+// Requests of refresh group are counted per session of refresh token cookie.
+// Limit state is sent in X-RateLimit-* headers. Group without rule isn't limited.
+func (m *Middlewares) rateLimit(group string) gin.HandlerFunc {
+	limiter, ok := m.limiters[group]
+	if !ok {
+		return func(c *gin.Context) {}
+	}
+
+	key := m.rateLimitKey
+	if group == rateGroupRefresh {
+		key = refreshRateLimitKey
+	}
+
+	return func(c *gin.Context) {
+		res := limiter.Allow(key(c))
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", ceilSeconds(res.ResetAfter))
+
+		if !res.Allowed {
+			c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+			newErrorResponse(c, m.log, errRateLimitExceeded)
 			return
 		}
-		newErrorResponse(c, m.log, http.StatusInternalServerError, err)
+	}
+}
+
+func (m *Middlewares) rateLimitKey(c *gin.Context) string {
+	if claims, err := m.getClaimsFrmCtx(c); err == nil {
+		return "user:" + claims.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Family id of refresh token isn't verified here. Forged one only gets
+// its own bucket, token is still rejected by lookup.
+func refreshRateLimitKey(c *gin.Context) string {
+	if rt, err := c.Cookie(rtCookieName); err == nil {
+		if familyId, ok := auth.RefreshTokenFamily(rt); ok {
+			return "family:" + familyId.String()
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// Middleware for allow access only for users with verified email.
+// Email state comes from access token claims, so verification takes effect
+// with next token refresh. Must be used after userIdentity.
+func (m *Middlewares) emailVerified(c *gin.Context) {
+	claims, err := m.getClaimsFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, m.log, err)
 		return
 	}
 
-	c.Set(userCtx, userId)
+	if !claims.EmailVerified {
+		newErrorResponse(c, m.log, errEmailNotVerified)
+		return
+	}
 }
 
-// This function return user id from gin context
-func (m *Middlewares) getUserIdFrmCtx(c *gin.Context) (uuid.UUID, error) {
-	id, ok := c.Get(userCtx)
+// Middleware for allow access only for users with one of roles.
+// Role comes from access token claims, must be used after userIdentity.
+func (m *Middlewares) requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := m.getClaimsFrmCtx(c)
+		if err != nil {
+			newErrorResponse(c, m.log, err)
+			return
+		}
+
+		for _, role := range claims.Roles {
+			if slices.Contains(roles, role) {
+				return
+			}
+		}
+		newErrorResponse(c, m.log, service.ErrInsufficientRole)
+	}
+}
+
+// This function return access token claims from gin context
+func (m *Middlewares) getClaimsFrmCtx(c *gin.Context) (auth.Claims, error) {
+	v, ok := c.Get(claimsCtx)
 	if !ok {
-		return uuid.UUID{}, errUserIdNotFound
+		return auth.Claims{}, errUserIdNotFound
+	}
+	claims, ok := v.(auth.Claims)
+	if !ok {
+		return auth.Claims{}, errUserIdNotFound
 	}
-	parsedId, err := uuid.Parse(id.(string))
+	return claims, nil
+}
+
+// This function return user id from gin context
+func (m *Middlewares) getUserIdFrmCtx(c *gin.Context) (uuid.UUID, error) {
+	claims, err := m.getClaimsFrmCtx(c)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
+	parsedId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, errUserIdNotFound
+	}
 	return parsedId, nil
 }
+
+// This function return signed in user as actor of moderation action.
+// Actor has the highest of roles in access token claims.
+func (m *Middlewares) getActorFrmCtx(c *gin.Context) (core.Actor, error) {
+	userId, err := m.getUserIdFrmCtx(c)
+	if err != nil {
+		return core.Actor{}, err
+	}
+	claims, err := m.getClaimsFrmCtx(c)
+	if err != nil {
+		return core.Actor{}, err
+	}
+
+	actor := core.Actor{Id: userId}
+	for _, role := range claims.Roles {
+		if core.RoleRank(role) > core.RoleRank(actor.Role) {
+			actor.Role = role
+		}
+	}
+	return actor, nil
+}
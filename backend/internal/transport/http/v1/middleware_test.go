@@ -2,11 +2,15 @@ package v1
 
 import (
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/Cheasezz/anSpace/backend/config"
 	"github.com/Cheasezz/anSpace/backend/internal/service"
 	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	mock_auth "github.com/Cheasezz/anSpace/backend/pkg/auth/mocks"
 	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
 	"github.com/gin-gonic/gin"
@@ -27,8 +31,9 @@ func TestAuth_userIdentity(t *testing.T) {
 	authSrv := mock_service.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	l := mock_logger.NewMockLogger(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
 
-	services := &service.Services{Auth: authSrv}
+	services := &service.Services{Auth: authSrv, Revocation: rv}
 	deps := initDeps(services, tm, l)
 	mdlwrs := NewMiddlewares(deps)
 	handler := NewAuthHandler(deps, mdlwrs)
@@ -38,8 +43,8 @@ func TestAuth_userIdentity(t *testing.T) {
 	handler.initAuthRoutes(v1)
 
 	r.GET("/protected", handler.mdlwrs.userIdentity, func(ctx *gin.Context) {
-		id, _ := ctx.Get(userCtx)
-		ctx.String(200, id.(string))
+		claims, _ := handler.mdlwrs.getClaimsFrmCtx(ctx)
+		ctx.String(200, claims.Subject)
 	})
 
 	tests := []struct {
@@ -57,11 +62,41 @@ func TestAuth_userIdentity(t *testing.T) {
 			headerValue: "Bearer token",
 			token:       "token",
 			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
-				s.EXPECT().Parse(token).Return("1", nil)
+				claims := testClaims("1")
+				s.EXPECT().Parse(token).Return(claims, nil)
+				rv.EXPECT().IsRevoked(uuid.MustParse(claims.SessionId)).Return(false)
 			},
 			expStatCode: 200,
 			expReqBody:  "1",
 		},
+		{
+			name:        "Revoked session",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
+				claims := testClaims("1")
+				s.EXPECT().Parse(token).Return(claims, nil)
+				rv.EXPECT().IsRevoked(uuid.MustParse(claims.SessionId)).Return(true)
+				l.EXPECT().Error(errTokenRevoked)
+			},
+			expStatCode: 401,
+			expReqBody:  errorJSON(errTokenRevoked),
+		},
+		{
+			name:        "Token without session id",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
+				claims := testClaims("1")
+				claims.SessionId = ""
+				s.EXPECT().Parse(token).Return(claims, nil)
+				l.EXPECT().Error(errTokenInvalid)
+			},
+			expStatCode: 401,
+			expReqBody:  errorJSON(auth.ErrTokenInvalid),
+		},
 		{
 			name:       "Empty auth header",
 			headerName: "",
@@ -69,7 +104,7 @@ func TestAuth_userIdentity(t *testing.T) {
 				l.EXPECT().Error(errEmptyAuthHeader)
 			},
 			expStatCode: 401,
-			expReqBody:  fmt.Sprintf(`{"message":"%s"}`, errEmptyAuthHeader),
+			expReqBody:  errorJSON(errEmptyAuthHeader),
 		},
 		{
 			name:        "One word in auth header value",
@@ -80,7 +115,7 @@ func TestAuth_userIdentity(t *testing.T) {
 				l.EXPECT().Error(errInvalidAuthHeader)
 			},
 			expStatCode: 401,
-			expReqBody:  fmt.Sprintf(`{"message":"%s"}`, errInvalidAuthHeader),
+			expReqBody:  errorJSON(errInvalidAuthHeader),
 		},
 		{
 			name:        "Uncorrect Bearer key word in auth header",
@@ -91,7 +126,7 @@ func TestAuth_userIdentity(t *testing.T) {
 				l.EXPECT().Error(errInvalidAuthHeader)
 			},
 			expStatCode: 401,
-			expReqBody:  fmt.Sprintf(`{"message":"%s"}`, errInvalidAuthHeader),
+			expReqBody:  errorJSON(errInvalidAuthHeader),
 		},
 		{
 			name:        "Empty token in auth header",
@@ -102,7 +137,7 @@ func TestAuth_userIdentity(t *testing.T) {
 				l.EXPECT().Error(errInvalidAuthHeader)
 			},
 			expStatCode: 401,
-			expReqBody:  fmt.Sprintf(`{"message":"%s"}`, errInvalidAuthHeader),
+			expReqBody:  errorJSON(errInvalidAuthHeader),
 		},
 		{
 			name:        "Token manager parse error",
@@ -110,11 +145,35 @@ func TestAuth_userIdentity(t *testing.T) {
 			headerValue: "Bearer token",
 			token:       "token",
 			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
-				s.EXPECT().Parse(token).Return("", errTmParse)
+				s.EXPECT().Parse(token).Return(auth.Claims{}, errTmParse)
 				l.EXPECT().Error(errTmParse)
 			},
 			expStatCode: 500,
-			expReqBody:  fmt.Sprintf(`{"message":"%s"}`, errTmParse),
+			expReqBody:  errorJSON(errTmParse),
+		},
+		{
+			name:        "Expired token",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
+				s.EXPECT().Parse(token).Return(auth.Claims{}, auth.ErrTokenExpired)
+				l.EXPECT().Error(auth.ErrTokenExpired)
+			},
+			expStatCode: 401,
+			expReqBody:  `{"code":"token_expired","message":"Token is expired"}`,
+		},
+		{
+			name:        "Invalid token",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(s *mock_auth.MockTokenManager, l *mock_logger.MockLogger, token string) {
+				s.EXPECT().Parse(token).Return(auth.Claims{}, auth.ErrTokenInvalid)
+				l.EXPECT().Error(auth.ErrTokenInvalid)
+			},
+			expStatCode: 401,
+			expReqBody:  errorJSON(auth.ErrTokenInvalid),
 		},
 	}
 	for _, tt := range tests {
@@ -133,10 +192,177 @@ func TestAuth_userIdentity(t *testing.T) {
 	}
 }
 
+func TestAuth_emailVerified(t *testing.T) {
+	type mockBehavior func(tm *mock_auth.MockTokenManager, l *mock_logger.MockLogger)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	authSrv := mock_service.NewMockAuth(ctrl)
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	l := mock_logger.NewMockLogger(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	rv.EXPECT().IsRevoked(gomock.Any()).Return(false).AnyTimes()
+
+	services := &service.Services{Auth: authSrv, Revocation: rv}
+	deps := initDeps(services, tm, l)
+	mdlwrs := NewMiddlewares(deps)
+
+	r := gin.New()
+	r.GET("/verified", mdlwrs.userIdentity, mdlwrs.emailVerified, func(ctx *gin.Context) {
+		ctx.String(200, "ok")
+	})
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		expStatCode  int
+		expReqBody   string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(tm *mock_auth.MockTokenManager, l *mock_logger.MockLogger) {
+				tm.EXPECT().Parse("token").Return(testClaims(testUUID.String()), nil)
+			},
+			expStatCode: 200,
+			expReqBody:  "ok",
+		},
+		{
+			name: "Email not verified",
+			mockBehavior: func(tm *mock_auth.MockTokenManager, l *mock_logger.MockLogger) {
+				claims := testClaims(testUUID.String())
+				claims.EmailVerified = false
+				tm.EXPECT().Parse("token").Return(claims, nil)
+				l.EXPECT().Error(errEmailNotVerified)
+			},
+			expStatCode: 403,
+			expReqBody:  errorJSON(errEmailNotVerified),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tm, l)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/verified", nil)
+			req.Header.Set(authorizationHeader, "Bearer token")
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			require.Equal(t, tt.expReqBody, w.Body.String())
+		})
+	}
+}
+
+func TestMiddlewares_rateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	tm := mock_auth.NewMockTokenManager(ctrl)
+	l := mock_logger.NewMockLogger(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
+	rv.EXPECT().IsRevoked(gomock.Any()).Return(false).AnyTimes()
+
+	services := &service.Services{Auth: mock_service.NewMockAuth(ctrl), Revocation: rv}
+	deps := initDeps(services, tm, l)
+	deps.ConfigRateLimit = config.RateLimit{
+		Enabled: true,
+		Groups: map[string]config.RateLimitRule{
+			rateGroupAuth:    {Rate: 0.5, Burst: 2},
+			rateGroupRefresh: {Rate: 0.5, Burst: 2},
+		},
+	}
+	// Refresh rule below one request per access token TTL is reported.
+	deps.AccessTokenTTL = time.Second
+	l.EXPECT().Warn(gomock.Any(), rateGroupRefresh, time.Second)
+	mdlwrs := NewMiddlewares(deps)
+
+	r := gin.New()
+	r.GET("/limited", mdlwrs.rateLimit(rateGroupAuth), func(ctx *gin.Context) {
+		ctx.String(200, "ok")
+	})
+	r.GET("/user", mdlwrs.userIdentity, mdlwrs.rateLimit(rateGroupAuth), func(ctx *gin.Context) {
+		ctx.String(200, "ok")
+	})
+	r.GET("/unlimited", mdlwrs.rateLimit(rateGroupRead), func(ctx *gin.Context) {
+		ctx.String(200, "ok")
+	})
+	r.GET("/refresh", mdlwrs.rateLimit(rateGroupRefresh), func(ctx *gin.Context) {
+		ctx.String(200, "ok")
+	})
+
+	type expResp struct {
+		code       int
+		remaining  string
+		retryAfter string
+	}
+	do := func(path, ip, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if token != "" {
+			req.Header.Set(authorizationHeader, "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	check := func(w *httptest.ResponseRecorder, exp expResp) {
+		require.Equal(t, exp.code, w.Code)
+		require.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		require.Equal(t, exp.remaining, w.Header().Get("X-RateLimit-Remaining"))
+		require.Equal(t, exp.retryAfter, w.Header().Get("Retry-After"))
+	}
+
+	// Anonymous requests are counted per ip.
+	check(do("/limited", "10.0.0.1", ""), expResp{code: 200, remaining: "1"})
+	check(do("/limited", "10.0.0.1", ""), expResp{code: 200, remaining: "0"})
+	l.EXPECT().Error(errRateLimitExceeded)
+	w := do("/limited", "10.0.0.1", "")
+	check(w, expResp{code: 429, remaining: "0", retryAfter: "2"})
+	require.Equal(t, errorJSON(errRateLimitExceeded), w.Body.String())
+	check(do("/limited", "10.0.0.2", ""), expResp{code: 200, remaining: "1"})
+
+	// Authenticated requests are counted per user, whatever ip is.
+	tm.EXPECT().Parse("token").Return(testClaims("1"), nil).Times(3)
+	check(do("/user", "10.0.0.1", "token"), expResp{code: 200, remaining: "1"})
+	check(do("/user", "10.0.0.3", "token"), expResp{code: 200, remaining: "0"})
+	l.EXPECT().Error(errRateLimitExceeded)
+	check(do("/user", "10.0.0.4", "token"), expResp{code: 429, remaining: "0", retryAfter: "2"})
+
+	// Token refresh is counted per session of refresh token, whatever ip is.
+	doRefresh := func(ip, refreshToken string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/refresh", nil)
+		req.RemoteAddr = ip + ":1234"
+		if refreshToken != "" {
+			req.AddCookie(&http.Cookie{Name: rtCookieName, Value: refreshToken})
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	family, other := uuid.New().String()+".aa", uuid.New().String()+".bb"
+	check(doRefresh("10.0.0.5", family), expResp{code: 200, remaining: "1"})
+	check(doRefresh("10.0.0.6", family), expResp{code: 200, remaining: "0"})
+	l.EXPECT().Error(errRateLimitExceeded)
+	check(doRefresh("10.0.0.5", family), expResp{code: 429, remaining: "0", retryAfter: "2"})
+	// Other session behind the same ip isn't affected.
+	check(doRefresh("10.0.0.5", other), expResp{code: 200, remaining: "1"})
+	// Token without family is counted per ip.
+	check(doRefresh("10.0.0.5", "legacy"), expResp{code: 200, remaining: "1"})
+	check(doRefresh("10.0.0.5", ""), expResp{code: 200, remaining: "0"})
+
+	// Group without rule isn't limited.
+	for i := 0; i < 5; i++ {
+		w := do("/unlimited", "10.0.0.1", "")
+		require.Equal(t, 200, w.Code)
+		require.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
 func Test_getUserIdFrmCtx(t *testing.T) {
 	var getContext = func(id uuid.UUID) *gin.Context {
 		c := &gin.Context{}
-		c.Set(userCtx, id.String())
+		c.Set(claimsCtx, testClaims(id.String()))
 		return c
 	}
 	testUUID := uuid.New()
@@ -146,8 +372,9 @@ func Test_getUserIdFrmCtx(t *testing.T) {
 	authSrv := mock_service.NewMockAuth(ctrl)
 	tm := mock_auth.NewMockTokenManager(ctrl)
 	l := mock_logger.NewMockLogger(ctrl)
+	rv := mock_service.NewMockRevocation(ctrl)
 
-	services := &service.Services{Auth: authSrv}
+	services := &service.Services{Auth: authSrv, Revocation: rv}
 	deps := initDeps(services, tm, l)
 
 	mdlwrs := NewMiddlewares(deps)
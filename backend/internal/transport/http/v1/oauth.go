@@ -0,0 +1,234 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Returned when state of callback isn't the one started by this browser,
+// e.g. attacker's state passed to sign victim in to attacker's account.
+var errOAuthStateMismatch = core.NewError(core.ErrValidation, "oauth_state_mismatch", "oauth state wasn't started by this browser")
+
+func (h *Auth) initOAuthRoutes(auth *gin.RouterGroup, limit, limitRead gin.HandlerFunc) {
+	oauth := auth.Group("/oauth/:provider")
+	{
+		oauth.GET("/start", limit, h.startOAuth)
+		oauth.GET("/callback", limit, h.oauthCallback)
+	}
+
+	linked := auth.Group("/linked", h.mdlwrs.userIdentity)
+	{
+		linked.GET("", limitRead, h.getLinkedAccounts)
+		linked.POST("/:provider", h.mdlwrs.emailVerified, limit, h.startLink)
+		linked.GET("/:provider/callback", limit, h.linkCallback)
+		linked.DELETE("/:provider", limit, h.unlink)
+	}
+}
+
+// @Tags oauth
+// @Summary start oauth sign in
+// @Description return provider authorization url, user should be redirected to it. Provider redirects back to frontend with code and state. State is also set in cookie, callback must be called by the same browser
+// @ID start-oauth
+// @Produce  json
+// @Param provider path string true "identity provider" Enums(shikimori, kinopoisk, litres)
+// @Success 200 {object} oauthURLResponse
+// @Header 200 {string} Set-Cookie "oauth state. Example: "OAuthState=s8hOQjBtA0; Path=/; HttpOnly; Secure; SameSite=None" "
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/auth/oauth/{provider}/start [get]
+func (h *Auth) startOAuth(c *gin.Context) {
+	url, state, err := h.oauth.StartOAuth(c, c.Param("provider"))
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	setOAuthStateCookie(c, state, h.config)
+	c.JSON(http.StatusOK, oauthURLResponse{URL: url})
+}
+
+// @Tags oauth
+// @Summary finish oauth sign in
+// @Description exchange code from provider redirect, sign in linked user or create new one. Return access token in JSON and refresh token in cookies
+// @ID oauth-callback
+// @Produce  json
+// @Param provider path string true "identity provider" Enums(shikimori, kinopoisk, litres)
+// @Param code query string true "authorization code from provider redirect"
+// @Param state query string true "state from provider redirect"
+// @Param Cookie header string true "oauth state in cookies, set by start"
+// @Success 200 {object} auth.ATknInfo
+// @Header 200 {string} Set-Cookie "refreshToken. Example: "RefreshToken=9838c59cff93e21; Path=/; Max-Age=2628000; HttpOnly; Secure; SameSite=None" "
+// @Success 202 {object} mfaPendingResponse "user has 2fa enabled, tokens are returned by /auth/2fa/verify"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "account deletion is scheduled"
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/auth/oauth/{provider}/callback [get]
+func (h *Auth) oauthCallback(c *gin.Context) {
+	var input core.OAuthCallback
+
+	if err := c.ShouldBindQuery(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	// State is used once whatever callback result is.
+	browserState, _ := c.Cookie(oauthStateCookieName)
+	clearOAuthStateCookie(c, h.config)
+	if subtle.ConstantTimeCompare([]byte(browserState), []byte(input.State)) != 1 {
+		newErrorResponse(c, h.log, errOAuthStateMismatch)
+		return
+	}
+
+	tokens, err := h.oauth.OAuthCallback(c, c.Param("provider"), input, deviceFromCtx(c))
+	if err != nil {
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			c.JSON(http.StatusAccepted, mfaPendingResponse{MFARequired: true, MFAToken: mfaErr.Token})
+			return
+		}
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	newTokenResponse(c, tokens, h.config)
+}
+
+// @Tags oauth
+// @Summary list linked providers
+// @Description return providers linked to current user, oldest link first
+// @ID get-linked-accounts
+// @Produce  json
+// @Success 200 {object} linkedAccountsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/linked [get]
+func (h *Auth) getLinkedAccounts(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	accounts, err := h.oauth.GetLinkedAccounts(c, usrId)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newLinkedAccountsResponse(accounts))
+}
+
+// @Tags oauth
+// @Summary start linking provider
+// @Description return provider authorization url to link provider to current user. Provider redirects back to frontend with code and state
+// @ID start-link
+// @Produce  json
+// @Param provider path string true "identity provider" Enums(shikimori, kinopoisk, litres)
+// @Success 200 {object} oauthURLResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "email not verified"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/linked/{provider} [post]
+func (h *Auth) startLink(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	url, err := h.oauth.StartLink(c, usrId, c.Param("provider"))
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthURLResponse{URL: url})
+}
+
+// @Tags oauth
+// @Summary finish linking provider
+// @Description exchange code from provider redirect and link provider account to current user
+// @ID link-callback
+// @Produce  json
+// @Param provider path string true "identity provider" Enums(shikimori, kinopoisk, litres)
+// @Param code query string true "authorization code from provider redirect"
+// @Param state query string true "state from provider redirect"
+// @Success 200 {object} linkedAccountResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/linked/{provider}/callback [get]
+func (h *Auth) linkCallback(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.OAuthCallback
+	if err := c.ShouldBindQuery(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	account, err := h.oauth.LinkCallback(c, usrId, c.Param("provider"), input)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newLinkedAccountResponse(account))
+}
+
+// @Tags oauth
+// @Summary unlink provider
+// @Description unlink provider from current user. User without password can't unlink last provider
+// @ID unlink
+// @Produce  json
+// @Param provider path string true "identity provider" Enums(shikimori, kinopoisk, litres)
+// @Success 200 "provider unlinked"
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/auth/linked/{provider} [delete]
+func (h *Auth) unlink(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	if err := h.oauth.Unlink(c, usrId, c.Param("provider")); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
@@ -0,0 +1,529 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	"github.com/Cheasezz/anSpace/backend/pkg/auth"
+	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	errServiceStartOAuth        = fmt.Errorf("service startOAuth error")
+	errServiceOAuthCallback     = fmt.Errorf("service oauthCallback error")
+	errServiceGetLinkedAccounts = fmt.Errorf("service getLinkedAccounts error")
+	errServiceStartLink         = fmt.Errorf("service startLink error")
+	errServiceLinkCallback      = fmt.Errorf("service linkCallback error")
+	errServiceUnlink            = fmt.Errorf("service unlink error")
+)
+
+func TestAuth_startOAuth(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name         string
+		provider     string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			provider:    "shikimori",
+			expStatCode: 200,
+			expReqBody:  oauthURLResponse{URL: "https://shikimori.one/oauth/authorize?state=state"},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartOAuth(gomock.Any(), "shikimori").Return("https://shikimori.one/oauth/authorize?state=state", "state", nil)
+			},
+		},
+		{
+			name:        "Not found: unknown provider",
+			provider:    "github",
+			expStatCode: 404,
+			expReqBody:  errorBody(service.ErrUnknownOAuthProvider),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartOAuth(gomock.Any(), "github").Return("", "", service.ErrUnknownOAuthProvider)
+				l.EXPECT().Error(service.ErrUnknownOAuthProvider)
+			},
+		},
+		{
+			name:        "Server error: service start error",
+			provider:    "shikimori",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceStartOAuth),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartOAuth(gomock.Any(), "shikimori").Return("", "", errServiceStartOAuth)
+				l.EXPECT().Error(errServiceStartOAuth)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.om, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/"+tt.provider+"/start", nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+			if tt.expStatCode == http.StatusOK {
+				require.Equal(t, "state", responseCookie(w, oauthStateCookieName).Value)
+				require.True(t, responseCookie(w, oauthStateCookieName).HttpOnly)
+			} else {
+				require.Nil(t, responseCookie(w, oauthStateCookieName))
+			}
+		})
+	}
+}
+
+func TestAuth_oauthCallback(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback)
+
+	mockDeps, r := initMocks(t)
+
+	tests := []struct {
+		name         string
+		query        string
+		input        core.OAuthCallback
+		expStatCode  int
+		expReqBody   interface{}
+		stateCookie  string
+		expCookie    bool
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 200,
+			expReqBody:  tokens.Access,
+			expCookie:   true,
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(tokens, nil)
+			},
+		},
+		{
+			name:        "Accepted: mfa required",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 202,
+			expReqBody:  mfaPendingResponse{MFARequired: true, MFAToken: "mfaToken"},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(auth.Tokens{}, &service.MFARequiredError{Token: "mfaToken"})
+			},
+		},
+		{
+			name:        "Bad request: empty state",
+			query:       "?code=code",
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'OAuthCallback.State' Error:Field validation for 'State' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: no state cookie",
+			query:       "?code=code&state=state",
+			expStatCode: 400,
+			expReqBody:  errorBody(errOAuthStateMismatch),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				l.EXPECT().Error(errOAuthStateMismatch)
+			},
+		},
+		{
+			name:        "Bad request: state started by other browser",
+			query:       "?code=code&state=attackerState",
+			stateCookie: "state",
+			expStatCode: 400,
+			expReqBody:  errorBody(errOAuthStateMismatch),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				l.EXPECT().Error(errOAuthStateMismatch)
+			},
+		},
+		{
+			name:        "Bad request: invalid state",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 400,
+			expReqBody:  errorBody(service.ErrInvalidOAuthState),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(auth.Tokens{}, service.ErrInvalidOAuthState)
+				l.EXPECT().Error(service.ErrInvalidOAuthState)
+			},
+		},
+		{
+			name:        "Bad request: invalid code",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 400,
+			expReqBody:  errorBody(service.ErrInvalidOAuthCode),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(auth.Tokens{}, service.ErrInvalidOAuthCode)
+				l.EXPECT().Error(service.ErrInvalidOAuthCode)
+			},
+		},
+		{
+			name:        "Not found: unknown provider",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 404,
+			expReqBody:  errorBody(service.ErrUnknownOAuthProvider),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(auth.Tokens{}, service.ErrUnknownOAuthProvider)
+				l.EXPECT().Error(service.ErrUnknownOAuthProvider)
+			},
+		},
+		{
+			name:        "Server error: service callback error",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			stateCookie: "state",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceOAuthCallback),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().OAuthCallback(gomock.Any(), "shikimori", input, gomock.Any()).Return(auth.Tokens{}, errServiceOAuthCallback)
+				l.EXPECT().Error(errServiceOAuthCallback)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.om, mockDeps.lm, tt.input)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/shikimori/callback"+tt.query, nil)
+			if tt.stateCookie != "" {
+				req.AddCookie(&http.Cookie{Name: oauthStateCookieName, Value: tt.stateCookie})
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+			if tt.expCookie {
+				require.Equal(t, tokens.Refresh.Token, responseCookie(w, rtCookieName).Value)
+			}
+			if tt.stateCookie != "" {
+				require.Negative(t, responseCookie(w, oauthStateCookieName).MaxAge)
+			}
+		})
+	}
+}
+
+func TestAuth_getLinkedAccounts(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	linkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	accounts := []core.LinkedAccount{{UserId: testUUID, Provider: "shikimori", Username: "cheasezz", AccessToken: "secret", CreatedAt: linkedAt}}
+	tests := []struct {
+		name         string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			expReqBody: linkedAccountsResponse{Accounts: []linkedAccountResponse{
+				{Provider: "shikimori", Username: "cheasezz", LinkedAt: linkedAt},
+			}},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().GetLinkedAccounts(gomock.Any(), testUUID).Return(accounts, nil)
+			},
+		},
+		{
+			name:        "ok: no linked accounts",
+			expStatCode: 200,
+			expReqBody:  linkedAccountsResponse{Accounts: []linkedAccountResponse{}},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().GetLinkedAccounts(gomock.Any(), testUUID).Return(nil, nil)
+			},
+		},
+		{
+			name:        "Server error: service get error",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceGetLinkedAccounts),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().GetLinkedAccounts(gomock.Any(), testUUID).Return(nil, errServiceGetLinkedAccounts)
+				l.EXPECT().Error(errServiceGetLinkedAccounts)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.om, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/linked", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+			require.NotContains(t, w.Body.String(), "secret")
+		})
+	}
+}
+
+func TestAuth_startLink(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			expReqBody:  oauthURLResponse{URL: "https://shikimori.one/oauth/authorize?state=state"},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartLink(gomock.Any(), testUUID, "shikimori").Return("https://shikimori.one/oauth/authorize?state=state", nil)
+			},
+		},
+		{
+			name:        "Not found: unknown provider",
+			expStatCode: 404,
+			expReqBody:  errorBody(service.ErrUnknownOAuthProvider),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartLink(gomock.Any(), testUUID, "shikimori").Return("", service.ErrUnknownOAuthProvider)
+				l.EXPECT().Error(service.ErrUnknownOAuthProvider)
+			},
+		},
+		{
+			name:        "Conflict: already linked",
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrProviderAlreadyLinked),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartLink(gomock.Any(), testUUID, "shikimori").Return("", service.ErrProviderAlreadyLinked)
+				l.EXPECT().Error(service.ErrProviderAlreadyLinked)
+			},
+		},
+		{
+			name:        "Server error: service start error",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceStartLink),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().StartLink(gomock.Any(), testUUID, "shikimori").Return("", errServiceStartLink)
+				l.EXPECT().Error(errServiceStartLink)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.om, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/linked/shikimori", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAuth_linkCallback(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	linkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	account := core.LinkedAccount{UserId: testUUID, Provider: "shikimori", Username: "cheasezz", CreatedAt: linkedAt}
+	tests := []struct {
+		name         string
+		query        string
+		input        core.OAuthCallback
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			expStatCode: 200,
+			expReqBody:  linkedAccountResponse{Provider: "shikimori", Username: "cheasezz", LinkedAt: linkedAt},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().LinkCallback(gomock.Any(), testUUID, "shikimori", input).Return(account, nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			query:       "?state=state",
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'OAuthCallback.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: invalid state",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			expStatCode: 400,
+			expReqBody:  errorBody(service.ErrInvalidOAuthState),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().LinkCallback(gomock.Any(), testUUID, "shikimori", input).Return(core.LinkedAccount{}, service.ErrInvalidOAuthState)
+				l.EXPECT().Error(service.ErrInvalidOAuthState)
+			},
+		},
+		{
+			name:        "Conflict: linked to other user",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrAccountAlreadyLinked),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().LinkCallback(gomock.Any(), testUUID, "shikimori", input).Return(core.LinkedAccount{}, service.ErrAccountAlreadyLinked)
+				l.EXPECT().Error(service.ErrAccountAlreadyLinked)
+			},
+		},
+		{
+			name:        "Server error: service link error",
+			query:       "?code=code&state=state",
+			input:       core.OAuthCallback{Code: "code", State: "state"},
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceLinkCallback),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger, input core.OAuthCallback) {
+				s.EXPECT().LinkCallback(gomock.Any(), testUUID, "shikimori", input).Return(core.LinkedAccount{}, errServiceLinkCallback)
+				l.EXPECT().Error(errServiceLinkCallback)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.om, mockDeps.lm, tt.input)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/linked/shikimori/callback"+tt.query, nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestAuth_unlink(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockOAuth, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	tests := []struct {
+		name         string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().Unlink(gomock.Any(), testUUID, "shikimori").Return(nil)
+			},
+		},
+		{
+			name:        "Not found: not linked",
+			expStatCode: 404,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrProviderNotLinked),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().Unlink(gomock.Any(), testUUID, "shikimori").Return(service.ErrProviderNotLinked)
+				l.EXPECT().Error(service.ErrProviderNotLinked)
+			},
+		},
+		{
+			name:        "Conflict: last sign in method",
+			expStatCode: 409,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrLastSignInMethod),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().Unlink(gomock.Any(), testUUID, "shikimori").Return(service.ErrLastSignInMethod)
+				l.EXPECT().Error(service.ErrLastSignInMethod)
+			},
+		},
+		{
+			name:        "Server error: service unlink error",
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceUnlink),
+			mockBehavior: func(s *mock_service.MockOAuth, l *mock_logger.MockLogger) {
+				s.EXPECT().Unlink(gomock.Any(), testUUID, "shikimori").Return(errServiceUnlink)
+				l.EXPECT().Error(errServiceUnlink)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.om, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/auth/linked/shikimori", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, w.Body.String(), string(res))
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+// Return cookie set by response or nil.
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -1,29 +1,178 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Cheasezz/anSpace/backend/config"
 	"github.com/Cheasezz/anSpace/backend/internal/core"
 	"github.com/Cheasezz/anSpace/backend/pkg/auth"
 	"github.com/Cheasezz/anSpace/backend/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// ErrorResponse is body of every error. Code is stable and machine readable,
+// message is for humans and may change.
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Code    string `json:"code" example:"invalid_credentials"`
+	Message string `json:"message" example:"invalid email or password"`
 }
 
+// Response types are transport DTOs, handlers never serialize core models directly,
+// so secret fields added to core can't leak into responses.
+
 type userResponse struct {
-	User core.User `json:"user"`
+	User profileResponse `json:"user"`
 	// ...Other entities related with user
 }
 
-const rtCookieName = "RefreshToken"
+type profileResponse struct {
+	Email         string `json:"email" example:"example@gmail.com"`
+	Username      string `json:"username" example:"cheasezz"`
+	Role          string `json:"role" example:"user"`
+	EmailVerified bool   `json:"emailVerified"`
+	TOTPEnabled   bool   `json:"totpEnabled"`
+}
+
+type sessionResponse struct {
+	Id         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Current    bool      `json:"current"`
+}
+
+type sessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+type mfaPendingResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+type oauthURLResponse struct {
+	URL string `json:"url" example:"https://shikimori.one/oauth/authorize?client_id=..."`
+}
+
+type linkedAccountResponse struct {
+	Provider string    `json:"provider" example:"shikimori"`
+	Username string    `json:"username" example:"cheasezz"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+type linkedAccountsResponse struct {
+	Accounts []linkedAccountResponse `json:"accounts"`
+}
+
+type deletionResponse struct {
+	DeleteAt time.Time `json:"deleteAt"`
+}
+
+// Everything stored about user except secrets: password hash, TOTP secret,
+// time step of last accepted TOTP code (replay protection state),
+// code values and tokens of sessions and linked accounts.
+type exportResponse struct {
+	User           exportUserResponse      `json:"user"`
+	Sessions       []sessionResponse       `json:"sessions"`
+	LinkedAccounts []linkedAccountResponse `json:"linkedAccounts"`
+	Codes          []exportCodeResponse    `json:"codes"`
+	ExportedAt     time.Time               `json:"exportedAt"`
+}
+
+type exportUserResponse struct {
+	Id            uuid.UUID `json:"id"`
+	Email         string    `json:"email" example:"example@gmail.com"`
+	Username      string    `json:"username" example:"cheasezz"`
+	Role          string    `json:"role" example:"user"`
+	EmailVerified bool      `json:"emailVerified"`
+	// TOTP secret is issued, it is enabled after first code is confirmed.
+	TOTPEnrolled bool `json:"totpEnrolled"`
+	TOTPEnabled  bool `json:"totpEnabled"`
+	// Not set until user changes default username.
+	UsernameChangedAt *time.Time `json:"usernameChangedAt,omitempty"`
+	// Not set unless deletion is scheduled.
+	DeleteAt *time.Time `json:"deleteAt,omitempty"`
+	// Not set unless user is banned.
+	BannedAt  *time.Time `json:"bannedAt,omitempty"`
+	BanReason string     `json:"banReason,omitempty" example:"spam"`
+}
+
+// Code of user without its value.
+type exportCodeResponse struct {
+	Type string `json:"type" example:"emailChange"`
+	// Not set for code without expiry, e.g. mfa recovery one.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Pending new email, set for email change code only.
+	NewEmail string `json:"newEmail,omitempty" example:"new@gmail.com"`
+}
+
+// User as seen by moderators.
+type adminUserResponse struct {
+	Id            uuid.UUID `json:"id"`
+	Email         string    `json:"email" example:"example@gmail.com"`
+	Username      string    `json:"username" example:"cheasezz"`
+	Role          string    `json:"role" example:"user"`
+	EmailVerified bool      `json:"emailVerified"`
+	TOTPEnabled   bool      `json:"totpEnabled"`
+	// Not set unless user is banned.
+	BannedAt  *time.Time `json:"bannedAt,omitempty"`
+	BanReason string     `json:"banReason,omitempty" example:"spam"`
+	// Not set unless deletion is scheduled.
+	DeleteAt *time.Time `json:"deleteAt,omitempty"`
+}
+
+type adminUsersResponse struct {
+	Users []adminUserResponse `json:"users"`
+}
+
+type lockoutResponse struct {
+	Key           string    `json:"key" example:"signin:email:example@gmail.com"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt"`
+	LockedUntil   time.Time `json:"lockedUntil"`
+}
+
+type lockoutsResponse struct {
+	Lockouts []lockoutResponse `json:"lockouts"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/anspace:example@gmail.com?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=anspace"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+const (
+	rtCookieName = "RefreshToken"
+	// State of oauth sign in started by browser, see startOAuth.
+	oauthStateCookieName = "OAuthState"
+)
 
-func newErrorResponse(c *gin.Context, l logger.Logger, statusCode int, err error) {
+// Log error and respond with status and body translated from it.
+// Rate limited errors that know retry delay set Retry-After header in whole seconds, rounded up.
+func newErrorResponse(c *gin.Context, l logger.Logger, err error) {
 	l.Error(err)
-	c.AbortWithStatusJSON(statusCode, ErrorResponse{err.Error()})
+
+	var retryErr core.Retryable
+	if errors.As(err, &retryErr) {
+		c.Header("Retry-After", ceilSeconds(retryErr.RetryIn()))
+	}
+
+	status, domainErr := translateError(err)
+	c.AbortWithStatusJSON(status, ErrorResponse{Code: domainErr.Code, Message: domainErr.Message})
+}
+
+// Respond to request of unknown route. It isn't logged as error.
+func NoRoute(c *gin.Context) {
+	status, domainErr := translateError(errPageNotFound)
+	c.AbortWithStatusJSON(status, ErrorResponse{Code: domainErr.Code, Message: domainErr.Message})
 }
 
 func newTokenResponse(c *gin.Context, t auth.Tokens, cfg config.HTTP) {
@@ -31,3 +180,144 @@ func newTokenResponse(c *gin.Context, t auth.Tokens, cfg config.HTTP) {
 	c.SetCookie(rtCookieName, t.Refresh.Token, t.Refresh.TTLInSec, "/", cfg.CookieHost, true, true)
 	c.JSON(http.StatusOK, t.Access)
 }
+
+// Session cookie, state itself expires in storage.
+func setOAuthStateCookie(c *gin.Context, state string, cfg config.HTTP) {
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(oauthStateCookieName, state, 0, "/", cfg.CookieHost, true, true)
+}
+
+func clearOAuthStateCookie(c *gin.Context, cfg config.HTTP) {
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(oauthStateCookieName, "", -1, "/", cfg.CookieHost, true, true)
+}
+
+func newUserResponse(u core.User) userResponse {
+	return userResponse{
+		User: profileResponse{
+			Email:         u.Email,
+			Username:      u.Username,
+			Role:          u.Role,
+			EmailVerified: u.EmailVerified,
+			TOTPEnabled:   u.TOTPEnabled,
+		},
+	}
+}
+
+func newAdminUserResponse(u core.User) adminUserResponse {
+	resp := adminUserResponse{
+		Id:            u.Id,
+		Email:         u.Email,
+		Username:      u.Username,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		TOTPEnabled:   u.TOTPEnabled,
+	}
+	if u.Banned() {
+		resp.BannedAt = &u.BannedAt
+		resp.BanReason = u.BanReason
+	}
+	if u.DeletionScheduled() {
+		resp.DeleteAt = &u.DeleteAt
+	}
+	return resp
+}
+
+func newAdminUsersResponse(users []core.User) adminUsersResponse {
+	resp := adminUsersResponse{Users: make([]adminUserResponse, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, newAdminUserResponse(u))
+	}
+	return resp
+}
+
+func newLockoutsResponse(lockouts []core.Lockout) lockoutsResponse {
+	resp := lockoutsResponse{Lockouts: make([]lockoutResponse, 0, len(lockouts))}
+	for _, l := range lockouts {
+		resp.Lockouts = append(resp.Lockouts, lockoutResponse{
+			Key:           l.Key,
+			Failures:      l.Failures,
+			LastFailureAt: l.LastFailureAt,
+			LockedUntil:   l.LockedUntil,
+		})
+	}
+	return resp
+}
+
+func newTOTPEnrollmentResponse(e core.TOTPEnrollment) totpEnrollmentResponse {
+	return totpEnrollmentResponse{
+		Secret: e.Secret,
+		URI:    e.URI,
+	}
+}
+
+func newLinkedAccountResponse(a core.LinkedAccount) linkedAccountResponse {
+	return linkedAccountResponse{
+		Provider: a.Provider,
+		Username: a.Username,
+		LinkedAt: a.CreatedAt,
+	}
+}
+
+func newLinkedAccountsResponse(accounts []core.LinkedAccount) linkedAccountsResponse {
+	resp := linkedAccountsResponse{Accounts: make([]linkedAccountResponse, 0, len(accounts))}
+	for _, a := range accounts {
+		resp.Accounts = append(resp.Accounts, newLinkedAccountResponse(a))
+	}
+	return resp
+}
+
+func newExportResponse(e core.UserExport, exportedAt time.Time) exportResponse {
+	user := exportUserResponse{
+		Id:            e.User.Id,
+		Email:         e.User.Email,
+		Username:      e.User.Username,
+		Role:          e.User.Role,
+		EmailVerified: e.User.EmailVerified,
+		TOTPEnrolled:  e.User.TOTPSecret != "",
+		TOTPEnabled:   e.User.TOTPEnabled,
+		BanReason:     e.User.BanReason,
+	}
+	if e.User.UsernameChangedAt.After(time.Unix(0, 0)) {
+		user.UsernameChangedAt = &e.User.UsernameChangedAt
+	}
+	if e.User.DeletionScheduled() {
+		user.DeleteAt = &e.User.DeleteAt
+	}
+	if e.User.Banned() {
+		user.BannedAt = &e.User.BannedAt
+	}
+
+	codes := make([]exportCodeResponse, 0, len(e.Codes))
+	for _, c := range e.Codes {
+		code := exportCodeResponse{Type: c.CodeType, NewEmail: c.NewEmail}
+		if c.ExpiresAt.After(time.Unix(0, 0)) {
+			code.ExpiresAt = &c.ExpiresAt
+		}
+		codes = append(codes, code)
+	}
+
+	return exportResponse{
+		User:           user,
+		Sessions:       newSessionsResponse(e.Sessions, "").Sessions,
+		LinkedAccounts: newLinkedAccountsResponse(e.LinkedAccounts).Accounts,
+		Codes:          codes,
+		ExportedAt:     exportedAt,
+	}
+}
+
+// Convert sessions into response. Session that refresh token belongs to is marked as current.
+func newSessionsResponse(sessions []core.Session, refreshToken string) sessionsResponse {
+	resp := sessionsResponse{Sessions: make([]sessionResponse, 0, len(sessions))}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionResponse{
+			Id:         s.FamilyId,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Current:    refreshToken != "" && s.RefreshToken == refreshToken,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "s3cr3t-value"
+
+func TestResponses_noSecrets(t *testing.T) {
+	user := core.User{
+		Id:           uuid.New(),
+		Email:        "kappa@gmail.com",
+		Username:     "qwertasd",
+		PasswordHash: secret,
+		TOTPSecret:   secret,
+	}
+	account := core.LinkedAccount{
+		UserId:       uuid.New(),
+		Provider:     "shikimori",
+		Username:     "cheasezz",
+		AccessToken:  secret,
+		RefreshToken: secret,
+		CreatedAt:    time.Now(),
+	}
+	session := core.Session{
+		UserId:       uuid.New(),
+		FamilyId:     uuid.New(),
+		RefreshToken: secret,
+	}
+
+	tests := []struct {
+		name string
+		resp interface{}
+	}{
+		{name: "user", resp: newUserResponse(user)},
+		{name: "linked account", resp: newLinkedAccountResponse(account)},
+		{name: "linked accounts", resp: newLinkedAccountsResponse([]core.LinkedAccount{account})},
+		{name: "sessions", resp: newSessionsResponse([]core.Session{session}, "")},
+		{name: "export", resp: newExportResponse(core.UserExport{
+			User:           user,
+			Sessions:       []core.Session{session},
+			LinkedAccounts: []core.LinkedAccount{account},
+			Codes:          []core.CodeCredentials{{Email: user.Email, Code: secret, CodeType: core.CodeTypeEmailChange}},
+		}, time.Now())},
+		{name: "admin users", resp: newAdminUsersResponse([]core.User{user})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := json.Marshal(tt.resp)
+			require.NoError(t, err)
+			require.NotContains(t, string(res), secret)
+		})
+	}
+}
+
+// Every handler must serialize response DTO built in this package, never core model or
+// other value that can carry secret fields. JSON payload must be "xResponse{...}"
+// literal or "newXResponse(...)" mapper call.
+func TestHandlers_serializeOnlyDTOs(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		// response.go is the mapping layer itself.
+		if strings.HasSuffix(name, "_test.go") || name == "response.go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		require.NoError(t, err)
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !strings.HasSuffix(sel.Sel.Name, "JSON") || len(call.Args) != 2 {
+				return true
+			}
+			require.Truef(t, isDTO(call.Args[1]), "%s: %s payload is not response DTO", fset.Position(call.Pos()), sel.Sel.Name)
+			return true
+		})
+	}
+}
+
+func isDTO(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.CompositeLit:
+		ident, ok := e.Type.(*ast.Ident)
+		return ok && strings.HasSuffix(ident.Name, "Response")
+	case *ast.CallExpr:
+		ident, ok := e.Fun.(*ast.Ident)
+		return ok && strings.HasPrefix(ident.Name, "new") && strings.HasSuffix(ident.Name, "Response")
+	}
+	return false
+}
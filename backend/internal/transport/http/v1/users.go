@@ -0,0 +1,315 @@
+package v1
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	"github.com/Cheasezz/anSpace/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Latin letters, digits, underscore, dot and hyphen, 3-32 characters starting with letter or digit.
+// Non latin letters are rejected, so lookalike names can't impersonate other users.
+var usernameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{2,31}$`)
+
+const exportFileName = "anspace-export.json"
+
+var errInvalidUsername = core.NewError(core.ErrValidation, "invalid_username", "username must be 3-32 latin letters, digits, '_', '.' or '-' starting with letter or digit")
+
+type Users struct {
+	service service.Users
+	log     logger.Logger
+	mdlwrs  *Middlewares
+}
+
+func NewUsersHandler(d Deps, m *Middlewares) *Users {
+	return &Users{
+		service: d.Services.Users,
+		log:     d.Log,
+		mdlwrs:  m,
+	}
+}
+
+func (h *Users) initUsersRoutes(router *gin.RouterGroup) {
+	limitAuth := h.mdlwrs.rateLimit(rateGroupAuth)
+	verified := h.mdlwrs.emailVerified
+
+	// Username, email change and account data stay available to users with
+	// unverified email: username is set right after signup and mistyped
+	// address can be fixed. Password change and deletion need verified one.
+	users := router.Group("/users")
+	{
+		users.PATCH("/me", h.mdlwrs.userIdentity, limitAuth, h.updateMe)
+		users.POST("/me/password", h.mdlwrs.userIdentity, verified, limitAuth, h.changePassword)
+		users.POST("/me/email", h.mdlwrs.userIdentity, limitAuth, h.requestEmailChange)
+		users.POST("/me/email/confirm", h.mdlwrs.userIdentity, limitAuth, h.confirmEmailChange)
+		users.DELETE("/me", h.mdlwrs.userIdentity, verified, limitAuth, h.scheduleDeletion)
+		users.GET("/me/export", h.mdlwrs.userIdentity, limitAuth, h.export)
+		users.POST("/deletion/cancel", limitAuth, h.cancelDeletion)
+	}
+}
+
+// @Tags users
+// @Summary update current user
+// @Description set username of current user. Username is unique ignoring case and can be changed once per interval, first change of default username is free
+// @ID update-me
+// @Accept  json
+// @Produce  json
+// @Param input body core.UserUpdate true "new username"
+// @Success 200 {object} userResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "username is already taken"
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until username can be changed"
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me [patch]
+func (h *Users) updateMe(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.UserUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+	if !usernameRegexp.MatchString(input.Username) {
+		newErrorResponse(c, h.log, errInvalidUsername)
+		return
+	}
+
+	user, err := h.service.UpdateUsername(c, usrId, input.Username)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// @Tags users
+// @Summary change password
+// @Description check current password and set new one. Other sessions of user are deleted, the one refresh token from cookie belongs to stays
+// @ID change-password
+// @Accept  json
+// @Produce  json
+// @Param input body core.PasswordChange true "current and new password"
+// @Param Cookie header string true "refresh token in cookies"
+// @Success 200 "password changed, other sessions deleted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "current password is incorrect or email not verified"
+// @Failure 409 {object} ErrorResponse "user has no password"
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until email or ip is unlocked"
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me/password [post]
+func (h *Users) changePassword(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.PasswordChange
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+	if err := validatePass(input.NewPassword); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	rt, err := c.Cookie(rtCookieName)
+	if err != nil {
+		newErrorResponse(c, h.log, errNoRefreshToken)
+		return
+	}
+
+	if err := h.service.ChangePassword(c, usrId, input, rt, deviceFromCtx(c)); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags users
+// @Summary request email change
+// @Description send email change code on new email. Email is changed after code confirmation. Current password is required if user has one
+// @ID request-email-change
+// @Accept  json
+// @Produce  json
+// @Param input body core.EmailChange true "new email and current password"
+// @Success 200 "code sent on new email"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "current password is incorrect"
+// @Failure 409 {object} ErrorResponse "email is already taken"
+// @Failure 429 {object} ErrorResponse
+// @Header 429 {integer} Retry-After "seconds until email or ip is unlocked"
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me/email [post]
+func (h *Users) requestEmailChange(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.EmailChange
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+	if err := validateEmail(input.Email); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	if err := h.service.RequestEmailChange(c, usrId, input, deviceFromCtx(c)); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags users
+// @Summary confirm email change
+// @Description check email change code sent on new email and set it as verified email of current user
+// @ID confirm-email-change
+// @Accept  json
+// @Produce  json
+// @Param input body core.EmailChangeConfirm true "email change code"
+// @Success 200 {object} userResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "email is already taken"
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me/email/confirm [post]
+func (h *Users) confirmEmailChange(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	var input core.EmailChangeConfirm
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	user, err := h.service.ConfirmEmailChange(c, usrId, input.Code)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// @Tags users
+// @Summary delete current user
+// @Description schedule deletion of current user after grace period and send cancellation link on user email. All sessions are deleted, user can't sign in until deletion is cancelled
+// @ID delete-me
+// @Produce  json
+// @Success 202 {object} deletionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "email not verified"
+// @Failure 409 {object} ErrorResponse "deletion is already scheduled"
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me [delete]
+func (h *Users) scheduleDeletion(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	deleteAt, err := h.service.ScheduleDeletion(c, usrId)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, deletionResponse{DeleteAt: deleteAt})
+}
+
+// @Tags users
+// @Summary cancel user deletion
+// @Description check code from cancellation link and cancel scheduled deletion of user
+// @ID cancel-deletion
+// @Accept  json
+// @Produce  json
+// @Param input body core.DeletionCancel true "email and code from cancellation link"
+// @Success 200 "deletion cancelled"
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Router /api/v1/users/deletion/cancel [post]
+func (h *Users) cancelDeletion(c *gin.Context) {
+	var input core.DeletionCancel
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, h.log, errInvalidRequest(err))
+		return
+	}
+
+	if err := h.service.CancelDeletion(c, input); err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Tags users
+// @Summary export current user data
+// @Description return JSON archive of everything stored about current user: profile, sessions, linked providers and pending codes. Secrets (password hash, TOTP secret, code values and tokens) are left out
+// @ID export-me
+// @Produce  json
+// @Success 200 {object} exportResponse
+// @Header 200 {string} Content-Disposition "attachment; filename=anspace-export.json"
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure default {object} ErrorResponse
+// @Security		bearerAuth
+// @Router /api/v1/users/me/export [get]
+func (h *Users) export(c *gin.Context) {
+	usrId, err := h.mdlwrs.getUserIdFrmCtx(c)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	export, err := h.service.Export(c, usrId)
+	if err != nil {
+		newErrorResponse(c, h.log, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+exportFileName+`"`)
+	c.JSON(http.StatusOK, newExportResponse(export, time.Now().UTC()))
+}
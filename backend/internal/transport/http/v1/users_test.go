@@ -0,0 +1,707 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/internal/core"
+	"github.com/Cheasezz/anSpace/backend/internal/service"
+	mock_service "github.com/Cheasezz/anSpace/backend/internal/service/mocks"
+	mock_logger "github.com/Cheasezz/anSpace/backend/pkg/logger/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	errServiceUpdateUsername     = fmt.Errorf("service updateUsername error")
+	errServiceChangePassword     = fmt.Errorf("service changePassword error")
+	errServiceRequestEmailChange = fmt.Errorf("service requestEmailChange error")
+	errServiceConfirmEmailChange = fmt.Errorf("service confirmEmailChange error")
+	errServiceScheduleDeletion   = fmt.Errorf("service scheduleDeletion error")
+	errServiceCancelDeletion     = fmt.Errorf("service cancelDeletion error")
+	errServiceExport             = fmt.Errorf("service export error")
+)
+
+func TestUsers_updateMe(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "Cheasezz@gmail.com", Username: "cheasezz"}
+	changeErr := &service.UsernameChangeError{RetryAfter: 90*time.Second + time.Millisecond}
+
+	tests := []struct {
+		name          string
+		inputBody     string
+		expStatCode   int
+		expReqBody    interface{}
+		expRetryAfter string
+		// Username is set right after signup, before email is verified.
+		unverified   bool
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"username":"cheasezz"}`,
+			expStatCode: 200,
+			expReqBody:  userResponse{User: profileResponse{Email: "Cheasezz@gmail.com", Username: "cheasezz"}},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "cheasezz").Return(user, nil)
+			},
+		},
+		{
+			name:        "ok with unverified email",
+			inputBody:   `{"username":"cheasezz"}`,
+			expStatCode: 200,
+			expReqBody:  userResponse{User: profileResponse{Email: "Cheasezz@gmail.com", Username: "cheasezz"}},
+			unverified:  true,
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "cheasezz").Return(user, nil)
+			},
+		},
+		{
+			name:        "Bad request: empty username",
+			inputBody:   `{"username":""}`,
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'UserUpdate.Username' Error:Field validation for 'Username' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: short username",
+			inputBody:   `{"username":"ch"}`,
+			expStatCode: 400,
+			expReqBody:  errorBody(errInvalidUsername),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errInvalidUsername)
+			},
+		},
+		{
+			name:        "Bad request: non latin username",
+			inputBody:   `{"username":"сheasezz"}`,
+			expStatCode: 400,
+			expReqBody:  errorBody(errInvalidUsername),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errInvalidUsername)
+			},
+		},
+		{
+			name:        "Bad request: reserved username",
+			inputBody:   `{"username":"Admin"}`,
+			expStatCode: 400,
+			expReqBody:  errorBody(service.ErrUsernameReserved),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "Admin").Return(core.User{}, service.ErrUsernameReserved)
+				l.EXPECT().Error(service.ErrUsernameReserved)
+			},
+		},
+		{
+			name:        "Conflict: username taken",
+			inputBody:   `{"username":"cheasezz"}`,
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrUsernameTaken),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "cheasezz").Return(core.User{}, service.ErrUsernameTaken)
+				l.EXPECT().Error(service.ErrUsernameTaken)
+			},
+		},
+		{
+			name:          "Too many requests: changed recently",
+			inputBody:     `{"username":"cheasezz"}`,
+			expStatCode:   429,
+			expReqBody:    errorBody(service.ErrUsernameChangeTooSoon),
+			expRetryAfter: "91",
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "cheasezz").Return(core.User{}, changeErr)
+				l.EXPECT().Error(changeErr)
+			},
+		},
+		{
+			name:        "Server error: service update error",
+			inputBody:   `{"username":"cheasezz"}`,
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceUpdateUsername),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().UpdateUsername(gomock.Any(), testUUID, "cheasezz").Return(core.User{}, errServiceUpdateUsername)
+				l.EXPECT().Error(errServiceUpdateUsername)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testClaims(testUUID.String())
+			claims.EmailVerified = !tt.unverified
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(claims, nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+			require.Equal(t, tt.expRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRoutes_requireVerifiedEmail(t *testing.T) {
+	mockDeps, r := initMocks(t)
+
+	claims := testClaims(uuid.NewString())
+	claims.EmailVerified = false
+	claims.Roles = []string{core.RoleAdmin}
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/v1/users/me/password"},
+		{http.MethodDelete, "/v1/users/me"},
+		{http.MethodPost, "/v1/auth/2fa/enroll"},
+		{http.MethodPost, "/v1/auth/2fa/confirm"},
+		{http.MethodPost, "/v1/auth/linked/shikimori"},
+		{http.MethodGet, "/v1/admin/users"},
+	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(claims, nil)
+			mockDeps.lm.EXPECT().Error(errEmailNotVerified)
+
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusForbidden, w.Code)
+			res, _ := json.Marshal(errorBody(errEmailNotVerified))
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestUsers_changePassword(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	input := core.PasswordChange{CurrentPassword: "qwerty123456", NewPassword: "qwerty1234567"}
+	lockoutErr := &service.LockoutError{RetryAfter: 30 * time.Second}
+
+	tests := []struct {
+		name          string
+		inputBody     string
+		noCookie      bool
+		expStatCode   int
+		isErr         bool
+		errReqBody    ErrorResponse
+		expRetryAfter string
+		mockBehavior  mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ChangePassword(gomock.Any(), testUUID, input, "rfToken", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: short new password",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(errShortPass),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errShortPass)
+			},
+		},
+		{
+			name:        "Unauthorized: no refresh token",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			noCookie:    true,
+			expStatCode: 401,
+			isErr:       true,
+			errReqBody:  errorBody(errNoRefreshToken),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errNoRefreshToken)
+			},
+		},
+		{
+			name:        "Forbidden: wrong password",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			expStatCode: 403,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrWrongPassword),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ChangePassword(gomock.Any(), testUUID, input, "rfToken", gomock.Any()).Return(service.ErrWrongPassword)
+				l.EXPECT().Error(service.ErrWrongPassword)
+			},
+		},
+		{
+			name:        "Conflict: password not set",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			expStatCode: 409,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrPasswordNotSet),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ChangePassword(gomock.Any(), testUUID, input, "rfToken", gomock.Any()).Return(service.ErrPasswordNotSet)
+				l.EXPECT().Error(service.ErrPasswordNotSet)
+			},
+		},
+		{
+			name:          "Too many requests: locked",
+			inputBody:     `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			expStatCode:   429,
+			isErr:         true,
+			errReqBody:    errorBody(service.ErrTooManyAttempts),
+			expRetryAfter: "30",
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ChangePassword(gomock.Any(), testUUID, input, "rfToken", gomock.Any()).Return(lockoutErr)
+				l.EXPECT().Error(lockoutErr)
+			},
+		},
+		{
+			name:        "Server error: service change error",
+			inputBody:   `{"currentPassword":"qwerty123456","newPassword":"qwerty1234567"}`,
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceChangePassword),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ChangePassword(gomock.Any(), testUUID, input, "rfToken", gomock.Any()).Return(errServiceChangePassword)
+				l.EXPECT().Error(errServiceChangePassword)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/me/password", bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			if !tt.noCookie {
+				req.AddCookie(&http.Cookie{Name: rtCookieName, Value: "rfToken"})
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, string(res), w.Body.String())
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+			require.Equal(t, tt.expRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestUsers_requestEmailChange(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	input := core.EmailChange{Email: "kappa@gmail.com", Password: "qwerty123456"}
+
+	tests := []struct {
+		name         string
+		inputBody    string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"email":"kappa@gmail.com","password":"qwerty123456"}`,
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().RequestEmailChange(gomock.Any(), testUUID, input, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: incorrect email",
+			inputBody:   `{"email":"kappa@gm-ail.com","password":"qwerty123456"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(errIncorrectEmail),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(errIncorrectEmail)
+			},
+		},
+		{
+			name:        "Bad request: same email",
+			inputBody:   `{"email":"kappa@gmail.com","password":"qwerty123456"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrSameEmail),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().RequestEmailChange(gomock.Any(), testUUID, input, gomock.Any()).Return(service.ErrSameEmail)
+				l.EXPECT().Error(service.ErrSameEmail)
+			},
+		},
+		{
+			name:        "Forbidden: wrong password",
+			inputBody:   `{"email":"kappa@gmail.com","password":"qwerty123456"}`,
+			expStatCode: 403,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrWrongPassword),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().RequestEmailChange(gomock.Any(), testUUID, input, gomock.Any()).Return(service.ErrWrongPassword)
+				l.EXPECT().Error(service.ErrWrongPassword)
+			},
+		},
+		{
+			name:        "Conflict: email taken",
+			inputBody:   `{"email":"kappa@gmail.com","password":"qwerty123456"}`,
+			expStatCode: 409,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrEmailTaken),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().RequestEmailChange(gomock.Any(), testUUID, input, gomock.Any()).Return(service.ErrEmailTaken)
+				l.EXPECT().Error(service.ErrEmailTaken)
+			},
+		},
+		{
+			name:        "Server error: service request error",
+			inputBody:   `{"email":"kappa@gmail.com","password":"qwerty123456"}`,
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceRequestEmailChange),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().RequestEmailChange(gomock.Any(), testUUID, input, gomock.Any()).Return(errServiceRequestEmailChange)
+				l.EXPECT().Error(errServiceRequestEmailChange)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/me/email", bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, string(res), w.Body.String())
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUsers_confirmEmailChange(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	user := core.User{Id: testUUID, Email: "kappa@gmail.com", Username: "cheasezz", EmailVerified: true}
+
+	tests := []struct {
+		name         string
+		inputBody    string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"code":"code"}`,
+			expStatCode: 200,
+			expReqBody:  userResponse{User: profileResponse{Email: "kappa@gmail.com", Username: "cheasezz", EmailVerified: true}},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmEmailChange(gomock.Any(), testUUID, "code").Return(user, nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			inputBody:   `{}`,
+			expStatCode: 400,
+			expReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'EmailChangeConfirm.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: expired code",
+			inputBody:   `{"code":"code"}`,
+			expStatCode: 400,
+			expReqBody:  errorBody(service.ErrEmailChangeCodeExpired),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmEmailChange(gomock.Any(), testUUID, "code").Return(core.User{}, service.ErrEmailChangeCodeExpired)
+				l.EXPECT().Error(service.ErrEmailChangeCodeExpired)
+			},
+		},
+		{
+			name:        "Conflict: email taken",
+			inputBody:   `{"code":"code"}`,
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrEmailTaken),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmEmailChange(gomock.Any(), testUUID, "code").Return(core.User{}, service.ErrEmailTaken)
+				l.EXPECT().Error(service.ErrEmailTaken)
+			},
+		},
+		{
+			name:        "Server error: service confirm error",
+			inputBody:   `{"code":"code"}`,
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceConfirmEmailChange),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ConfirmEmailChange(gomock.Any(), testUUID, "code").Return(core.User{}, errServiceConfirmEmailChange)
+				l.EXPECT().Error(errServiceConfirmEmailChange)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/me/email/confirm", bytes.NewBufferString(tt.inputBody))
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestUsers_scheduleDeletion(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	deleteAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		expStatCode  int
+		expReqBody   interface{}
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 202,
+			expReqBody:  deletionResponse{DeleteAt: deleteAt},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ScheduleDeletion(gomock.Any(), testUUID).Return(deleteAt, nil)
+			},
+		},
+		{
+			name:        "Conflict: already scheduled",
+			expStatCode: 409,
+			expReqBody:  errorBody(service.ErrDeletionScheduled),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ScheduleDeletion(gomock.Any(), testUUID).Return(time.Time{}, service.ErrDeletionScheduled)
+				l.EXPECT().Error(service.ErrDeletionScheduled)
+			},
+		},
+		{
+			name:        "Server error: service schedule error",
+			expStatCode: 500,
+			expReqBody:  errorBody(errServiceScheduleDeletion),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().ScheduleDeletion(gomock.Any(), testUUID).Return(time.Time{}, errServiceScheduleDeletion)
+				l.EXPECT().Error(errServiceScheduleDeletion)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/users/me", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			res, _ := json.Marshal(tt.expReqBody)
+			require.Equal(t, string(res), w.Body.String())
+		})
+	}
+}
+
+func TestUsers_cancelDeletion(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	input := core.DeletionCancel{Email: "Cheasezz@gmail.com", Code: "code"}
+
+	tests := []struct {
+		name         string
+		inputBody    string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			inputBody:   `{"email":"Cheasezz@gmail.com","code":"code"}`,
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().CancelDeletion(gomock.Any(), input).Return(nil)
+			},
+		},
+		{
+			name:        "Bad request: empty code",
+			inputBody:   `{"email":"Cheasezz@gmail.com"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  ErrorResponse{Code: codeInvalidRequest, Message: "Key: 'DeletionCancel.Code' Error:Field validation for 'Code' failed on the 'required' tag"},
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				l.EXPECT().Error(gomock.Any())
+			},
+		},
+		{
+			name:        "Bad request: invalid code",
+			inputBody:   `{"email":"Cheasezz@gmail.com","code":"code"}`,
+			expStatCode: 400,
+			isErr:       true,
+			errReqBody:  errorBody(service.ErrInvalidDeletionCancelCode),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().CancelDeletion(gomock.Any(), input).Return(service.ErrInvalidDeletionCancelCode)
+				l.EXPECT().Error(service.ErrInvalidDeletionCancelCode)
+			},
+		},
+		{
+			name:        "Server error: service cancel error",
+			inputBody:   `{"email":"Cheasezz@gmail.com","code":"code"}`,
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceCancelDeletion),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().CancelDeletion(gomock.Any(), input).Return(errServiceCancelDeletion)
+				l.EXPECT().Error(errServiceCancelDeletion)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/deletion/cancel", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, string(res), w.Body.String())
+			} else {
+				require.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUsers_export(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUsers, l *mock_logger.MockLogger)
+
+	mockDeps, r := initMocks(t)
+
+	testUUID := uuid.New()
+	bannedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	export := core.UserExport{
+		User: core.User{Id: testUUID, Email: "Cheasezz@gmail.com", Username: "cheasezz", PasswordHash: "hash",
+			Role: core.RoleUser, TOTPSecret: "totpSecret", BannedAt: bannedAt, BanReason: "spam"},
+		Sessions:       []core.Session{{UserId: testUUID, FamilyId: uuid.New(), RefreshToken: "rfToken"}},
+		LinkedAccounts: []core.LinkedAccount{{UserId: testUUID, Provider: "shikimori", AccessToken: "oauthToken"}},
+		Codes: []core.CodeCredentials{
+			{Email: "Cheasezz@gmail.com", Code: "changeCode", CodeType: core.CodeTypeEmailChange, ExpiresAt: bannedAt.Add(time.Hour), NewEmail: "new@gmail.com"},
+			{Email: "Cheasezz@gmail.com", Code: "recoveryCode", CodeType: core.CodeTypeMFARecovery, ExpiresAt: time.Unix(0, 0).UTC()},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		expStatCode  int
+		isErr        bool
+		errReqBody   ErrorResponse
+		mockBehavior mockBehavior
+	}{
+		{
+			name:        "ok",
+			expStatCode: 200,
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().Export(gomock.Any(), testUUID).Return(export, nil)
+			},
+		},
+		{
+			name:        "Server error: service export error",
+			expStatCode: 500,
+			isErr:       true,
+			errReqBody:  errorBody(errServiceExport),
+			mockBehavior: func(s *mock_service.MockUsers, l *mock_logger.MockLogger) {
+				s.EXPECT().Export(gomock.Any(), testUUID).Return(core.UserExport{}, errServiceExport)
+				l.EXPECT().Error(errServiceExport)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeps.tmm.EXPECT().Parse("acToken").Return(testClaims(testUUID.String()), nil)
+			tt.mockBehavior(mockDeps.um, mockDeps.lm)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/me/export", nil)
+			req.Header.Add(authorizationHeader, "Bearer acToken")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expStatCode, w.Code)
+			if tt.isErr {
+				res, _ := json.Marshal(tt.errReqBody)
+				require.Equal(t, string(res), w.Body.String())
+				return
+			}
+
+			require.Equal(t, `attachment; filename="`+exportFileName+`"`, w.Header().Get("Content-Disposition"))
+			var resp exportResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, newExportResponse(export, resp.ExportedAt), resp)
+			require.False(t, resp.ExportedAt.IsZero())
+			require.Equal(t, core.RoleUser, resp.User.Role)
+			require.Equal(t, "spam", resp.User.BanReason)
+			require.True(t, resp.User.TOTPEnrolled)
+			require.Len(t, resp.Codes, 2)
+			require.Equal(t, "new@gmail.com", resp.Codes[0].NewEmail)
+			require.Nil(t, resp.Codes[1].ExpiresAt)
+			for _, secret := range []string{"hash", "rfToken", "oauthToken", "totpSecret", "changeCode", "recoveryCode"} {
+				require.NotContains(t, w.Body.String(), secret)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// Message is relied on by frontend to refresh tokens, don't change it.
+	ErrTokenExpired = errors.New("Token is expired")
+	ErrTokenInvalid = errors.New("invalid access token")
+)
+
+// Claims of access token. Subject is user id, SessionId is id of session
+// (refresh token family) token was issued for.
+// Issuer, Audience, Id (jti), IssuedAt and ExpiresAt are set by Manager.
+type Claims struct {
+	jwt.StandardClaims
+	SessionId     string   `json:"sid,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+// Audience of mfa pending tokens, access tokens with it are rejected.
+const mfaAudience = "mfa"
+
+// Claims of mfa pending token. It is issued after password check of user
+// with two-factor authentication enabled and is exchanged for tokens
+// with second factor code. Subject is user id.
+type MFAClaims struct {
+	jwt.StandardClaims
+}
@@ -1,26 +1,48 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/rand"
 	"errors"
 	"fmt"
-	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Cheasezz/anSpace/backend/config"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 )
 
+var ErrRefreshTokenExpired = errors.New("refresh token is expired")
+
 type TokenManager interface {
-	NewJWT(userId string) (string, error)
-	Parse(accessToken string) (string, error)
-	NewRefreshToken() (RTknInfo, error)
+	NewJWT(claims Claims) (string, error)
+	Parse(accessToken string) (Claims, error)
+	NewRefreshToken(familyId uuid.UUID) (RTknInfo, error)
 	ValidateRefreshToken(expiresAt time.Time) (int, error)
+	JWKS() JWKS
+	NewMFAToken(userId string) (string, error)
+	ParseMFA(mfaToken string) (MFAClaims, error)
+	NewTOTPSecret() (string, error)
+	TOTPURI(account, secret string) string
+	ValidateTOTP(secret, code string, lastStep int64) (int64, bool)
 }
 
+// Manager signs access tokens with HS256 shared secret or with asymmetric
+// (RS256, EdDSA) private key. Asymmetric tokens carry kid header, so tokens
+// signed by retired keys stay valid while their public keys are configured.
 type Manager struct {
-	signingKey string
+	method     jwt.SigningMethod
+	signKey    interface{}
+	kid        string
+	secret     []byte
+	verifyKeys map[string]verifyKey
+	issuer     string
+	audience   string
 	atTTL      time.Duration
 	rtTTL      time.Duration
+	mfaTTL     time.Duration
 }
 
 type RTknInfo struct {
@@ -39,69 +61,255 @@ type Tokens struct {
 }
 
 func NewManager(cfg config.TokenManager) (*Manager, error) {
-	if cfg.SigningKey == "" {
-		return nil, errors.New("empty signing key")
-	}
-
-	return &Manager{
-		signingKey: cfg.SigningKey,
+	m := &Manager{
+		verifyKeys: make(map[string]verifyKey),
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
 		atTTL:      cfg.AccessTokenTTL,
 		rtTTL:      cfg.RefreshTokenTTL,
-	}, nil
+		mfaTTL:     cfg.MFATokenTTL,
+	}
+	if cfg.SigningKey != "" {
+		m.secret = []byte(cfg.SigningKey)
+	}
+
+	switch cfg.SigningMethod {
+	case MethodHS256:
+		if m.secret == nil {
+			return nil, errors.New("empty signing key")
+		}
+		m.method = jwt.SigningMethodHS256
+		m.signKey = m.secret
+	case MethodRS256, MethodEdDSA:
+		if err := m.setPrivateKey(cfg.SigningMethod, cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSigningMethod, cfg.SigningMethod)
+	}
+
+	for _, path := range cfg.PublicKeyFiles {
+		pub, err := loadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load public key: %w", err)
+		}
+		if err := m.addVerifyKey(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
 }
 
-func (m *Manager) NewJWT(userId string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		ExpiresAt: time.Now().Add(m.atTTL).Unix(),
-		IssuedAt:  time.Now().Unix(),
+func (m *Manager) setPrivateKey(method, path string) error {
+	if path == "" {
+		return fmt.Errorf("empty private key file for %s", method)
+	}
+	priv, err := loadPrivateKey(path)
+	if err != nil {
+		return fmt.Errorf("load private key: %w", err)
+	}
+
+	vk, err := newVerifyKey(priv.Public())
+	if err != nil {
+		return err
+	}
+	if vk.method.Alg() != method {
+		return fmt.Errorf("%w: %s key for %s signing method", ErrUnsupportedKey, vk.method.Alg(), method)
+	}
+
+	m.method = vk.method
+	m.signKey = priv
+	m.kid = vk.jwk.Kid
+	m.verifyKeys[vk.jwk.Kid] = vk
+	return nil
+}
+
+func (m *Manager) addVerifyKey(pub crypto.PublicKey) error {
+	vk, err := newVerifyKey(pub)
+	if err != nil {
+		return err
+	}
+	m.verifyKeys[vk.jwk.Kid] = vk
+	return nil
+}
+
+// Sign access token with given claims. Registered claims are set by manager.
+func (m *Manager) NewJWT(claims Claims) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.Issuer = m.issuer
+	claims.Audience = m.audience
+	claims.Id = fmt.Sprintf("%x", jti)
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(m.atTTL).Unix()
+
+	return m.sign(claims)
+}
+
+// Sign mfa pending token of user. It is accepted only by ParseMFA.
+func (m *Manager) NewMFAToken(userId string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := MFAClaims{StandardClaims: jwt.StandardClaims{
 		Subject:   userId,
-	})
+		Issuer:    m.issuer,
+		Audience:  mfaAudience,
+		Id:        fmt.Sprintf("%x", jti),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.mfaTTL).Unix(),
+	}}
 
-	return token.SignedString([]byte(m.signingKey))
+	return m.sign(claims)
 }
 
-func (m *Manager) Parse(accessToken string) (string, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return "", fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.method, claims)
+	if m.kid != "" {
+		token.Header["kid"] = m.kid
+	}
 
-		return []byte(m.signingKey), nil
-	})
+	return token.SignedString(m.signKey)
+}
+
+// Verify access token signature, expiry, issuer and audience.
+// Return ErrTokenExpired for expired token and ErrTokenInvalid for any other failure.
+func (m *Manager) Parse(accessToken string) (Claims, error) {
+	var claims Claims
+
+	if err := m.parse(accessToken, &claims, &claims.StandardClaims, m.audience); err != nil {
+		return Claims{}, err
+	}
+	if claims.Audience == mfaAudience {
+		return Claims{}, fmt.Errorf("%w: mfa pending token", ErrTokenInvalid)
+	}
+
+	return claims, nil
+}
+
+// Verify mfa pending token the same way as access token.
+func (m *Manager) ParseMFA(mfaToken string) (MFAClaims, error) {
+	var claims MFAClaims
+
+	if err := m.parse(mfaToken, &claims, &claims.StandardClaims, mfaAudience); err != nil {
+		return MFAClaims{}, err
+	}
+
+	return claims, nil
+}
+
+// Parse token into claims and verify registered ones. std must point to
+// registered claims embedded in claims.
+func (m *Manager) parse(token string, claims jwt.Claims, std *jwt.StandardClaims, audience string) error {
+	_, err := jwt.ParseWithClaims(token, claims, m.keyFunc)
 	if err != nil {
-		return "", err
+		var vErr *jwt.ValidationError
+		if errors.As(err, &vErr) && vErr.Errors == jwt.ValidationErrorExpired {
+			return ErrTokenExpired
+		}
+		return fmt.Errorf("%w: %s", ErrTokenInvalid, err)
+	}
+
+	if !std.VerifyIssuer(m.issuer, m.issuer != "") {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrTokenInvalid, std.Issuer)
+	}
+	if !std.VerifyAudience(audience, audience != "") {
+		return fmt.Errorf("%w: unexpected audience %q", ErrTokenInvalid, std.Audience)
+	}
+	if std.Subject == "" {
+		return fmt.Errorf("%w: empty subject", ErrTokenInvalid)
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	return nil
+}
+
+// Pick verification key for token. HS256 tokens are checked with shared secret
+// (if configured), others with public key found by kid, and only with the
+// signing method of that key.
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if m.secret == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return m.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	vk, ok := m.verifyKeys[kid]
 	if !ok {
-		return "", fmt.Errorf("error get user claims from token")
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+	if token.Method.Alg() != vk.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
 
-	return claims["sub"].(string), nil
+	return vk.key, nil
 }
 
-func (m *Manager) NewRefreshToken() (RTknInfo, error) {
-	b := make([]byte, 32)
+// Return public keys used to verify access tokens. Empty for HS256 only setup.
+func (m *Manager) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(m.verifyKeys))}
+	if vk, ok := m.verifyKeys[m.kid]; ok {
+		set.Keys = append(set.Keys, vk.jwk)
+	}
+	retired := make([]string, 0, len(m.verifyKeys))
+	for kid := range m.verifyKeys {
+		if kid != m.kid {
+			retired = append(retired, kid)
+		}
+	}
+	sort.Strings(retired)
+	for _, kid := range retired {
+		set.Keys = append(set.Keys, m.verifyKeys[kid].jwk)
+	}
+	return set
+}
 
-	s := rand.NewSource(time.Now().UTC().Unix() + rand.Int63())
-	r := rand.New(s)
+// Refresh token is rotated on every use, so it must be unpredictable.
+// Token is prefixed with id of its family (session), so requests
+// of session can be told apart without database, see RefreshTokenFamily.
+func (m *Manager) NewRefreshToken(familyId uuid.UUID) (RTknInfo, error) {
+	b := make([]byte, 32)
 
-	if _, err := r.Read(b); err != nil {
+	if _, err := rand.Read(b); err != nil {
 		return RTknInfo{}, err
 	}
 
 	refreshToken := RTknInfo{
-		Token:     fmt.Sprintf("%x", b),
+		Token:     fmt.Sprintf("%s.%x", familyId, b),
 		ExpiresAt: time.Now().Add(m.rtTTL).UTC(),
 		TTLInSec:  int(m.rtTTL.Seconds()),
 	}
 	return refreshToken, nil
 }
 
+// Return family id refresh token claims to belong to. Claim isn't verified,
+// token must still be looked up to be trusted.
+func RefreshTokenFamily(refreshToken string) (uuid.UUID, bool) {
+	prefix, _, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return uuid.UUID{}, false
+	}
+	familyId, err := uuid.Parse(prefix)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	return familyId, true
+}
+
+// Return seconds until refresh token expire or ErrRefreshTokenExpired.
 func (m *Manager) ValidateRefreshToken(expiresAt time.Time) (int, error) {
-	var rtDayUntilExpire = int(time.Until(expiresAt).Hours()) / 24
-	if rtDayUntilExpire <= 0 {
-		return 0, fmt.Errorf("refresh token is expired")
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return 0, ErrRefreshTokenExpired
 	}
-	return rtDayUntilExpire, nil
+	return int(ttl.Seconds()), nil
 }
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserId = "7b5c0a36-3f0e-4d8e-9a43-0f5b8e3b2c11"
+
+func userClaims() Claims {
+	return Claims{
+		StandardClaims: jwt.StandardClaims{Subject: testUserId},
+		SessionId:      "2b1e4c5d-9f7a-4e2b-8c3d-1a2b3c4d5e6f",
+		Roles:          []string{"user"},
+		EmailVerified:  true,
+	}
+}
+
+func writePrivateKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "*.pem")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}))
+	return f.Name()
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return priv
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return priv
+}
+
+func tmConfig(method string) config.TokenManager {
+	return config.TokenManager{
+		SigningMethod:   method,
+		Issuer:          "anspace",
+		Audience:        "anspace",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		MFATokenTTL:     time.Minute,
+	}
+}
+
+func TestManager_SignAndParse(t *testing.T) {
+	hs := tmConfig(MethodHS256)
+	hs.SigningKey = "secret"
+
+	rs := tmConfig(MethodRS256)
+	rs.PrivateKeyFile = writePrivateKey(t, newRSAKey(t))
+
+	ed := tmConfig(MethodEdDSA)
+	ed.PrivateKeyFile = writePrivateKey(t, newEd25519Key(t))
+
+	tests := []struct {
+		name    string
+		cfg     config.TokenManager
+		alg     string
+		hasKid  bool
+		jwksLen int
+	}{
+		{name: "HS256", cfg: hs, alg: "HS256"},
+		{name: "RS256", cfg: rs, alg: "RS256", hasKid: true, jwksLen: 1},
+		{name: "EdDSA", cfg: ed, alg: "EdDSA", hasKid: true, jwksLen: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewManager(tt.cfg)
+			require.NoError(t, err)
+
+			token, err := m.NewJWT(userClaims())
+			require.NoError(t, err)
+
+			parsed, _ := new(jwt.Parser).Parse(token, nil)
+			require.Equal(t, tt.alg, parsed.Header["alg"])
+			_, ok := parsed.Header["kid"]
+			require.Equal(t, tt.hasKid, ok)
+
+			claims, err := m.Parse(token)
+			require.NoError(t, err)
+			require.Equal(t, testUserId, claims.Subject)
+			require.Equal(t, userClaims().SessionId, claims.SessionId)
+			require.Equal(t, userClaims().Roles, claims.Roles)
+			require.True(t, claims.EmailVerified)
+			require.Equal(t, "anspace", claims.Issuer)
+			require.Equal(t, "anspace", claims.Audience)
+			require.NotEmpty(t, claims.Id)
+
+			require.Len(t, m.JWKS().Keys, tt.jwksLen)
+		})
+	}
+}
+
+func TestManager_MFAToken(t *testing.T) {
+	cfg := tmConfig(MethodHS256)
+	cfg.SigningKey = "secret"
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	mfaToken, err := m.NewMFAToken(testUserId)
+	require.NoError(t, err)
+
+	claims, err := m.ParseMFA(mfaToken)
+	require.NoError(t, err)
+	require.Equal(t, testUserId, claims.Subject)
+	require.Equal(t, mfaAudience, claims.Audience)
+
+	// Mfa pending token isn't access token and vice versa.
+	_, err = m.Parse(mfaToken)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+
+	accessToken, err := m.NewJWT(userClaims())
+	require.NoError(t, err)
+	_, err = m.ParseMFA(accessToken)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+
+	// Even when audience of access tokens isn't checked.
+	cfg.Audience = ""
+	m, err = NewManager(cfg)
+	require.NoError(t, err)
+	_, err = m.Parse(mfaToken)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+
+	cfg.MFATokenTTL = -time.Minute
+	m, err = NewManager(cfg)
+	require.NoError(t, err)
+	mfaToken, err = m.NewMFAToken(testUserId)
+	require.NoError(t, err)
+	_, err = m.ParseMFA(mfaToken)
+	require.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestManager_RefreshToken(t *testing.T) {
+	cfg := tmConfig(MethodHS256)
+	cfg.SigningKey = "secret"
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	familyId := uuid.New()
+	first, err := m.NewRefreshToken(familyId)
+	require.NoError(t, err)
+	second, err := m.NewRefreshToken(familyId)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Token, second.Token)
+	require.LessOrEqual(t, len(first.Token), 255)
+	require.Equal(t, int(time.Hour.Seconds()), first.TTLInSec)
+
+	got, ok := RefreshTokenFamily(first.Token)
+	require.True(t, ok)
+	require.Equal(t, familyId, got)
+
+	for _, token := range []string{"", "abc", "abc.def", "." + familyId.String()} {
+		_, ok := RefreshTokenFamily(token)
+		require.False(t, ok, token)
+	}
+}
+
+func TestManager_KeyRotation(t *testing.T) {
+	oldKey := newEd25519Key(t)
+	oldCfg := tmConfig(MethodEdDSA)
+	oldCfg.PrivateKeyFile = writePrivateKey(t, oldKey)
+	oldManager, err := NewManager(oldCfg)
+	require.NoError(t, err)
+	oldToken, err := oldManager.NewJWT(userClaims())
+	require.NoError(t, err)
+
+	newCfg := tmConfig(MethodRS256)
+	newCfg.PrivateKeyFile = writePrivateKey(t, newRSAKey(t))
+	newCfg.PublicKeyFiles = []string{writePublicKey(t, oldKey.Public())}
+	m, err := NewManager(newCfg)
+	require.NoError(t, err)
+
+	claims, err := m.Parse(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, testUserId, claims.Subject)
+
+	jwks := m.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, m.kid, jwks.Keys[0].Kid)
+	require.Equal(t, "RS256", jwks.Keys[0].Alg)
+	require.Equal(t, oldManager.kid, jwks.Keys[1].Kid)
+	require.Equal(t, "OKP", jwks.Keys[1].Kty)
+
+	// Retired key removed from config: its tokens are rejected.
+	newCfg.PublicKeyFiles = nil
+	m, err = NewManager(newCfg)
+	require.NoError(t, err)
+	_, err = m.Parse(oldToken)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestManager_RejectsForeignTokens(t *testing.T) {
+	priv := newRSAKey(t)
+	cfg := tmConfig(MethodRS256)
+	cfg.PrivateKeyFile = writePrivateKey(t, priv)
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// HS256 token signed with public key as secret (algorithm confusion).
+	pubPEM, err := os.ReadFile(writePublicKey(t, priv.Public()))
+	require.NoError(t, err)
+	hsToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: testUserId})
+	hsToken.Header["kid"] = m.kid
+	confused, err := hsToken.SignedString(pubPEM)
+	require.NoError(t, err)
+	_, err = m.Parse(confused)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+
+	// Token signed by unknown key.
+	other := tmConfig(MethodRS256)
+	other.PrivateKeyFile = writePrivateKey(t, newRSAKey(t))
+	om, err := NewManager(other)
+	require.NoError(t, err)
+	foreign, err := om.NewJWT(userClaims())
+	require.NoError(t, err)
+	_, err = m.Parse(foreign)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestManager_ParseErrors(t *testing.T) {
+	cfg := tmConfig(MethodHS256)
+	cfg.SigningKey = "secret"
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	sign := func(claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.SigningKey))
+		require.NoError(t, err)
+		return token
+	}
+	valid := func() jwt.StandardClaims {
+		return jwt.StandardClaims{
+			Subject:   testUserId,
+			Issuer:    cfg.Issuer,
+			Audience:  cfg.Audience,
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	expired := valid()
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	wrongIss := valid()
+	wrongIss.Issuer = "other"
+	wrongAud := valid()
+	wrongAud.Audience = "other"
+	noSub := valid()
+	noSub.Subject = ""
+
+	tests := []struct {
+		name   string
+		token  string
+		expErr error
+	}{
+		{name: "expired", token: sign(expired), expErr: ErrTokenExpired},
+		{name: "wrong issuer", token: sign(wrongIss), expErr: ErrTokenInvalid},
+		{name: "wrong audience", token: sign(wrongAud), expErr: ErrTokenInvalid},
+		{name: "empty subject", token: sign(noSub), expErr: ErrTokenInvalid},
+		{name: "non string subject", token: sign(jwt.MapClaims{"sub": 1, "iss": cfg.Issuer, "aud": cfg.Audience}), expErr: ErrTokenInvalid},
+		{name: "malformed", token: "token", expErr: ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Parse(tt.token)
+			require.ErrorIs(t, err, tt.expErr)
+		})
+	}
+}
+
+func TestNewManager_Errors(t *testing.T) {
+	missing := tmConfig(MethodRS256)
+	missing.PrivateKeyFile = filepath.Join(t.TempDir(), "missing.pem")
+
+	mismatch := tmConfig(MethodRS256)
+	mismatch.PrivateKeyFile = writePrivateKey(t, newEd25519Key(t))
+
+	tests := []struct {
+		name string
+		cfg  config.TokenManager
+	}{
+		{name: "HS256 without secret", cfg: tmConfig(MethodHS256)},
+		{name: "unknown method", cfg: tmConfig("ES512")},
+		{name: "RS256 without private key", cfg: tmConfig(MethodRS256)},
+		{name: "missing key file", cfg: missing},
+		{name: "key type mismatch", cfg: mismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewManager(tt.cfg)
+			require.Error(t, err)
+		})
+	}
+}
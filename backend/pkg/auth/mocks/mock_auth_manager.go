@@ -14,6 +14,7 @@ import (
 	time "time"
 
 	auth "github.com/Cheasezz/anSpace/backend/pkg/auth"
+	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,41 +41,85 @@ func (m *MockTokenManager) EXPECT() *MockTokenManagerMockRecorder {
 	return m.recorder
 }
 
+// JWKS mocks base method.
+func (m *MockTokenManager) JWKS() auth.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(auth.JWKS)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockTokenManagerMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockTokenManager)(nil).JWKS))
+}
+
 // NewJWT mocks base method.
-func (m *MockTokenManager) NewJWT(userId string) (string, error) {
+func (m *MockTokenManager) NewJWT(claims auth.Claims) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewJWT", userId)
+	ret := m.ctrl.Call(m, "NewJWT", claims)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewJWT indicates an expected call of NewJWT.
-func (mr *MockTokenManagerMockRecorder) NewJWT(userId any) *gomock.Call {
+func (mr *MockTokenManagerMockRecorder) NewJWT(claims any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewJWT", reflect.TypeOf((*MockTokenManager)(nil).NewJWT), claims)
+}
+
+// NewMFAToken mocks base method.
+func (m *MockTokenManager) NewMFAToken(userId string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewMFAToken", userId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewMFAToken indicates an expected call of NewMFAToken.
+func (mr *MockTokenManagerMockRecorder) NewMFAToken(userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewJWT", reflect.TypeOf((*MockTokenManager)(nil).NewJWT), userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewMFAToken", reflect.TypeOf((*MockTokenManager)(nil).NewMFAToken), userId)
 }
 
 // NewRefreshToken mocks base method.
-func (m *MockTokenManager) NewRefreshToken() (auth.RTknInfo, error) {
+func (m *MockTokenManager) NewRefreshToken(familyId uuid.UUID) (auth.RTknInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewRefreshToken")
+	ret := m.ctrl.Call(m, "NewRefreshToken", familyId)
 	ret0, _ := ret[0].(auth.RTknInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewRefreshToken indicates an expected call of NewRefreshToken.
-func (mr *MockTokenManagerMockRecorder) NewRefreshToken() *gomock.Call {
+func (mr *MockTokenManagerMockRecorder) NewRefreshToken(familyId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRefreshToken", reflect.TypeOf((*MockTokenManager)(nil).NewRefreshToken), familyId)
+}
+
+// NewTOTPSecret mocks base method.
+func (m *MockTokenManager) NewTOTPSecret() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewTOTPSecret")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewTOTPSecret indicates an expected call of NewTOTPSecret.
+func (mr *MockTokenManagerMockRecorder) NewTOTPSecret() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRefreshToken", reflect.TypeOf((*MockTokenManager)(nil).NewRefreshToken))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewTOTPSecret", reflect.TypeOf((*MockTokenManager)(nil).NewTOTPSecret))
 }
 
 // Parse mocks base method.
-func (m *MockTokenManager) Parse(accessToken string) (string, error) {
+func (m *MockTokenManager) Parse(accessToken string) (auth.Claims, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Parse", accessToken)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(auth.Claims)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -85,6 +130,35 @@ func (mr *MockTokenManagerMockRecorder) Parse(accessToken any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parse", reflect.TypeOf((*MockTokenManager)(nil).Parse), accessToken)
 }
 
+// ParseMFA mocks base method.
+func (m *MockTokenManager) ParseMFA(mfaToken string) (auth.MFAClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseMFA", mfaToken)
+	ret0, _ := ret[0].(auth.MFAClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseMFA indicates an expected call of ParseMFA.
+func (mr *MockTokenManagerMockRecorder) ParseMFA(mfaToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseMFA", reflect.TypeOf((*MockTokenManager)(nil).ParseMFA), mfaToken)
+}
+
+// TOTPURI mocks base method.
+func (m *MockTokenManager) TOTPURI(account, secret string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TOTPURI", account, secret)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// TOTPURI indicates an expected call of TOTPURI.
+func (mr *MockTokenManagerMockRecorder) TOTPURI(account, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TOTPURI", reflect.TypeOf((*MockTokenManager)(nil).TOTPURI), account, secret)
+}
+
 // ValidateRefreshToken mocks base method.
 func (m *MockTokenManager) ValidateRefreshToken(expiresAt time.Time) (int, error) {
 	m.ctrl.T.Helper()
@@ -99,3 +173,18 @@ func (mr *MockTokenManagerMockRecorder) ValidateRefreshToken(expiresAt any) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRefreshToken", reflect.TypeOf((*MockTokenManager)(nil).ValidateRefreshToken), expiresAt)
 }
+
+// ValidateTOTP mocks base method.
+func (m *MockTokenManager) ValidateTOTP(secret, code string, lastStep int64) (int64, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateTOTP", secret, code, lastStep)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// ValidateTOTP indicates an expected call of ValidateTOTP.
+func (mr *MockTokenManagerMockRecorder) ValidateTOTP(secret, code, lastStep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateTOTP", reflect.TypeOf((*MockTokenManager)(nil).ValidateTOTP), secret, code, lastStep)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Secret "12345678901234567890" of RFC 6238 test vectors.
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func Test_validateTOTP(t *testing.T) {
+	// Step of code "081804" at 1111111109 is 37037036.
+	tests := []struct {
+		name     string
+		code     string
+		time     time.Time
+		lastStep int64
+		step     int64
+		valid    bool
+	}{
+		{name: "RFC vector 59", code: "287082", time: time.Unix(59, 0), step: 1, valid: true},
+		{name: "RFC vector 1111111109", code: "081804", time: time.Unix(1111111109, 0), step: 37037036, valid: true},
+		{name: "RFC vector 2000000000", code: "279037", time: time.Unix(2000000000, 0), step: 66666666, valid: true},
+		{name: "previous period", code: "081804", time: time.Unix(1111111109+30, 0), step: 37037036, valid: true},
+		{name: "next period", code: "081804", time: time.Unix(1111111109-30, 0), step: 37037036, valid: true},
+		{name: "out of skew", code: "081804", time: time.Unix(1111111109+90, 0)},
+		{name: "wrong code", code: "000000", time: time.Unix(59, 0)},
+		{name: "wrong length", code: "28708", time: time.Unix(59, 0)},
+		{name: "after last step", code: "081804", time: time.Unix(1111111109, 0), lastStep: 37037035, step: 37037036, valid: true},
+		{name: "replay of last step", code: "081804", time: time.Unix(1111111109, 0), lastStep: 37037036},
+		{name: "before last step", code: "081804", time: time.Unix(1111111109+30, 0), lastStep: 37037037},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid := validateTOTP(rfcTOTPSecret, tt.code, tt.time, tt.lastStep)
+			require.Equal(t, tt.valid, valid)
+			require.Equal(t, tt.step, step)
+		})
+	}
+
+	_, valid := validateTOTP("not base32!", "287082", time.Unix(59, 0), 0)
+	require.False(t, valid)
+}
+
+func TestManager_TOTP(t *testing.T) {
+	m := &Manager{issuer: "anspace"}
+
+	secret, err := m.NewTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now().Unix() / 30
+	code, err := totpCode(secret, now)
+	require.NoError(t, err)
+	step, valid := m.ValidateTOTP(secret, code, 0)
+	require.True(t, valid)
+	require.Equal(t, now, step)
+	_, valid = m.ValidateTOTP(secret, code, step)
+	require.False(t, valid)
+
+	uri, err := url.Parse(m.TOTPURI("user@example.com", secret))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/anspace:user@example.com", uri.Path)
+	require.Equal(t, secret, uri.Query().Get("secret"))
+	require.Equal(t, "anspace", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+}
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(alg string) config.Hasher {
+	return config.Hasher{
+		Algorithm:         alg,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        4,
+		Salt:              "salt",
+	}
+}
+
+func TestHasher_HashVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		alg    string
+		prefix string
+	}{
+		{name: "argon2id", alg: AlgArgon2id, prefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{name: "bcrypt", alg: AlgBcrypt, prefix: "$2a$04$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHasher(testConfig(tt.alg))
+			require.NoError(t, err)
+
+			hash, err := h.Hash("qwerty123456")
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(hash, tt.prefix))
+
+			otherHash, err := h.Hash("qwerty123456")
+			require.NoError(t, err)
+			require.NotEqual(t, hash, otherHash, "salt must be unique per hash")
+
+			ok, err := h.Verify("qwerty123456", hash)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			ok, err = h.Verify("qwerty1234567", hash)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			require.False(t, h.NeedsRehash(hash))
+		})
+	}
+}
+
+func TestHasher_LegacySHA1(t *testing.T) {
+	cfg := testConfig(AlgArgon2id)
+	h, err := NewHasher(cfg)
+	require.NoError(t, err)
+
+	legacy, err := NewSHA1Hasher(cfg).Hash("qwerty123456")
+	require.NoError(t, err)
+
+	ok, err := h.Verify("qwerty123456", legacy)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = h.Verify("wrong password", legacy)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.True(t, h.NeedsRehash(legacy))
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	weak, err := NewHasher(testConfig(AlgArgon2id))
+	require.NoError(t, err)
+	weakHash, err := weak.Hash("qwerty123456")
+	require.NoError(t, err)
+
+	strongCfg := testConfig(AlgArgon2id)
+	strongCfg.Argon2Iterations = 2
+	strong, err := NewHasher(strongCfg)
+	require.NoError(t, err)
+	require.True(t, strong.NeedsRehash(weakHash))
+
+	bcrypt, err := NewHasher(testConfig(AlgBcrypt))
+	require.NoError(t, err)
+	require.True(t, bcrypt.NeedsRehash(weakHash))
+
+	ok, err := bcrypt.Verify("qwerty123456", weakHash)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestHasher_Errors(t *testing.T) {
+	_, err := NewHasher(testConfig("md5"))
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	h, err := NewHasher(testConfig(AlgArgon2id))
+	require.NoError(t, err)
+
+	_, err = h.Verify("qwerty123456", "$scrypt$ln=16,r=8,p=1$c2FsdA$aGFzaA")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = h.Verify("qwerty123456", "$argon2id$v=19$m=1024$c2FsdA$aGFzaA")
+	require.ErrorIs(t, err, ErrMalformedHash)
+}
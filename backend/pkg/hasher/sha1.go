@@ -0,0 +1,46 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+)
+
+// SHA1Hasher is a legacy hasher. It is kept only to verify
+// old hashes and upgrade them on successful sign in.
+type SHA1Hasher struct {
+	salt string
+}
+
+func NewSHA1Hasher(cfg config.Hasher) *SHA1Hasher {
+	return &SHA1Hasher{salt: cfg.Salt}
+}
+
+func (h *SHA1Hasher) Hash(password string) (string, error) {
+	hash := sha1.New()
+
+	if _, err := hash.Write([]byte(password)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(h.salt))), nil
+}
+
+func (h *SHA1Hasher) Verify(password, encoded string) (bool, error) {
+	hash, err := h.Hash(password)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1, nil
+}
+
+func (h *SHA1Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+func (h *SHA1Hasher) match(encoded string) bool {
+	return !strings.HasPrefix(encoded, "$")
+}
@@ -0,0 +1,63 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cheasezz/anSpace/backend/config"
+)
+
+// algorithm is a single hashing scheme that can recognize its own encoded strings.
+type algorithm interface {
+	PasswordHasher
+	match(encoded string) bool
+}
+
+// Hasher hash new passwords with configured algorithm and verify passwords
+// against any known format, including legacy SHA-1 hex digests.
+type Hasher struct {
+	current algorithm
+	known   []algorithm
+}
+
+func NewHasher(cfg config.Hasher) (*Hasher, error) {
+	argon := newArgon2idHasher(cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism)
+	bcrypt := newBcryptHasher(cfg.BcryptCost)
+
+	h := &Hasher{
+		known: []algorithm{argon, bcrypt, NewSHA1Hasher(cfg)},
+	}
+
+	switch strings.ToLower(cfg.Algorithm) {
+	case AlgArgon2id, "":
+		h.current = argon
+	case AlgBcrypt:
+		h.current = bcrypt
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, cfg.Algorithm)
+	}
+
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(password, encoded string) (bool, error) {
+	for _, alg := range h.known {
+		if alg.match(encoded) {
+			return alg.Verify(password, encoded)
+		}
+	}
+	return false, ErrUnknownAlgorithm
+}
+
+// Report whether encoded hash was produced by another algorithm
+// or with other params than current one.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if !h.current.match(encoded) {
+		return true
+	}
+	return h.current.NeedsRehash(encoded)
+}
@@ -0,0 +1,122 @@
+// Package iofs is golang-migrate source driver reading migrations from fs.FS,
+// e.g. embed.FS compiled into binary. Migrations are files named as for file source:
+// {version}_{title}.up.sql and {version}_{title}.down.sql.
+package iofs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/golang-migrate/migrate/source"
+)
+
+type Driver struct {
+	fsys       fs.FS
+	dir        string
+	migrations *source.Migrations
+}
+
+// Return driver of migrations in dir of fsys. Files that aren't migrations are ignored.
+func New(fsys fs.FS, dir string) (*Driver, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Driver{
+		fsys:       fsys,
+		dir:        dir,
+		migrations: source.NewMigrations(),
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m, err := source.DefaultParse(e.Name())
+		if err != nil {
+			continue
+		}
+		if !d.migrations.Append(m) {
+			return nil, fmt.Errorf("duplicate migration file: %s", e.Name())
+		}
+	}
+
+	return d, nil
+}
+
+// Driver is made by New, it can't be opened by url.
+func (d *Driver) Open(url string) (source.Driver, error) {
+	return nil, errors.New("iofs driver can't be opened by url, use iofs.New")
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+func (d *Driver) First() (uint, error) {
+	v, ok := d.migrations.First()
+	if !ok {
+		return 0, d.notExist("first")
+	}
+	return v, nil
+}
+
+func (d *Driver) Prev(version uint) (uint, error) {
+	v, ok := d.migrations.Prev(version)
+	if !ok {
+		return 0, d.notExist(fmt.Sprintf("prev for version %d", version))
+	}
+	return v, nil
+}
+
+func (d *Driver) Next(version uint) (uint, error) {
+	v, ok := d.migrations.Next(version)
+	if !ok {
+		return 0, d.notExist(fmt.Sprintf("next for version %d", version))
+	}
+	return v, nil
+}
+
+func (d *Driver) ReadUp(version uint) (io.ReadCloser, string, error) {
+	m, ok := d.migrations.Up(version)
+	if !ok {
+		return nil, "", d.notExist(fmt.Sprintf("read up version %d", version))
+	}
+	return d.open(m)
+}
+
+func (d *Driver) ReadDown(version uint) (io.ReadCloser, string, error) {
+	m, ok := d.migrations.Down(version)
+	if !ok {
+		return nil, "", d.notExist(fmt.Sprintf("read down version %d", version))
+	}
+	return d.open(m)
+}
+
+// Return sorted versions of migrations.
+func (d *Driver) Versions() []uint {
+	var versions []uint
+	v, ok := d.migrations.First()
+	for ok {
+		versions = append(versions, v)
+		v, ok = d.migrations.Next(v)
+	}
+	return versions
+}
+
+func (d *Driver) open(m *source.Migration) (io.ReadCloser, string, error) {
+	f, err := d.fsys.Open(path.Join(d.dir, m.Raw))
+	if err != nil {
+		return nil, "", err
+	}
+	return f, m.Identifier, nil
+}
+
+// Migrate expects os.ErrNotExist when there is no such migration.
+func (d *Driver) notExist(op string) error {
+	return &os.PathError{Op: op, Path: d.dir, Err: os.ErrNotExist}
+}
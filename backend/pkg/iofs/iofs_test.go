@@ -0,0 +1,64 @@
+package iofs
+
+import (
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema/000001_init.up.sql":     {Data: []byte("CREATE TABLE users ();")},
+		"schema/000001_init.down.sql":   {Data: []byte("DROP TABLE users;")},
+		"schema/000003_codes.up.sql":    {Data: []byte("CREATE TABLE codes ();")},
+		"schema/000003_codes.down.sql":  {Data: []byte("DROP TABLE codes;")},
+		"schema/schema.go":              {Data: []byte("package schema")},
+		"schema/000004_no_down.up.sql":  {Data: []byte("SELECT 1;")},
+		"schema/nested/000005_x.up.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	d, err := New(fsys, "schema")
+	require.NoError(t, err)
+	require.Equal(t, []uint{1, 3, 4}, d.Versions())
+
+	first, err := d.First()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, first)
+
+	next, err := d.Next(1)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, next)
+	_, err = d.Next(4)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	prev, err := d.Prev(3)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, prev)
+	_, err = d.Prev(1)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	r, identifier, err := d.ReadUp(3)
+	require.NoError(t, err)
+	body, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, "codes", identifier)
+	require.Equal(t, "CREATE TABLE codes ();", string(body))
+
+	_, _, err = d.ReadDown(4)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = New(fsys, "missing")
+	require.Error(t, err)
+}
+
+func TestDriverEmpty(t *testing.T) {
+	d, err := New(fstest.MapFS{"schema/README.md": {}}, "schema")
+	require.NoError(t, err)
+
+	_, err = d.First()
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,202 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const _defaultTimeout = 10 * time.Second
+
+var ErrTokenRequest = errors.New("oauth token request failed")
+
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	RedirectURL  string
+	Scopes       []string
+	// Some providers reject requests without application name in User-Agent.
+	UserAgent string
+}
+
+// Client of OAuth2 authorization code flow with PKCE (RFC 6749, RFC 7636).
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// Token of provider. ExpiresAt is zero when provider didn't send expires_in.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	ExpiresAt    time.Time
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Error        string `json:"error"`
+	ErrorDesc    string `json:"error_description"`
+}
+
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: _defaultTimeout}
+	}
+	return &Client{cfg: cfg, http: httpClient}
+}
+
+// Return url of provider authorization page. State and code challenge
+// are checked on callback.
+func (c *Client) AuthCodeURL(state, codeChallenge string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	if len(c.cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(c.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.cfg.AuthURL + sep + q.Encode()
+}
+
+// Exchange authorization code and PKCE verifier for token.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	return c.token(ctx, form)
+}
+
+// Get new token by refresh token. Provider may keep old refresh token,
+// then it is returned in result too.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+
+	token, err := c.token(ctx, form)
+	if err != nil {
+		return Token{}, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (c *Client) token(ctx context.Context, form url.Values) (Token, error) {
+	form.Set("client_id", c.cfg.ClientID)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var resp tokenResponse
+	status, err := c.do(req, &resp)
+	if err != nil {
+		return Token{}, err
+	}
+	if status != http.StatusOK || resp.AccessToken == "" {
+		if resp.Error != "" {
+			return Token{}, fmt.Errorf("%w: %s %s", ErrTokenRequest, resp.Error, resp.ErrorDesc)
+		}
+		return Token{}, fmt.Errorf("%w: status %d", ErrTokenRequest, status)
+	}
+
+	token := Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		TokenType:    resp.TokenType,
+	}
+	if resp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().UTC().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// Send GET request to provider api with token and decode JSON response into dst.
+func (c *Client) Get(ctx context.Context, url string, token Token, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	status, err := c.do(req, dst)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("oauth api request failed: status %d", status)
+	}
+	return nil
+}
+
+func (c *Client) do(req *http.Request, dst interface{}) (int, error) {
+	if c.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", c.cfg.UserAgent)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	// Error responses may be not JSON, their status is enough.
+	if err := json.Unmarshal(body, dst); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("oauth: decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// Return random state or PKCE code verifier, 43 url safe characters.
+func NewRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Return S256 code challenge of PKCE code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *Client) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthURL:      srv.URL + "/oauth/authorize",
+		TokenURL:     srv.URL + "/oauth/token",
+		RedirectURL:  "http://localhost:5173/oauth/callback",
+		Scopes:       []string{"user_rates", "comments"},
+		UserAgent:    "anSpace",
+	}, srv.Client())
+	return srv, client
+}
+
+func TestClient_AuthCodeURL(t *testing.T) {
+	_, client := newTestServer(t, nil)
+
+	u, err := url.Parse(client.AuthCodeURL("state", "challenge"))
+	require.NoError(t, err)
+	require.Equal(t, "/oauth/authorize", u.Path)
+
+	q := u.Query()
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, "client", q.Get("client_id"))
+	require.Equal(t, "http://localhost:5173/oauth/callback", q.Get("redirect_uri"))
+	require.Equal(t, "user_rates comments", q.Get("scope"))
+	require.Equal(t, "state", q.Get("state"))
+	require.Equal(t, "challenge", q.Get("code_challenge"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+}
+
+func TestClient_Exchange(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/oauth/token", r.URL.Path)
+		require.Equal(t, "anSpace", r.UserAgent())
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+		require.Equal(t, "client", r.PostForm.Get("client_id"))
+		require.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		require.Equal(t, "http://localhost:5173/oauth/callback", r.PostForm.Get("redirect_uri"))
+
+		if r.PostForm.Get("code") != "code" || r.PostForm.Get("code_verifier") != "verifier" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access",
+			"refresh_token": "refresh",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	})
+
+	token, err := client.Exchange(context.Background(), "code", "verifier")
+	require.NoError(t, err)
+	require.Equal(t, "access", token.AccessToken)
+	require.Equal(t, "refresh", token.RefreshToken)
+	require.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+
+	_, err = client.Exchange(context.Background(), "code", "wrong verifier")
+	require.ErrorIs(t, err, ErrTokenRequest)
+	require.Contains(t, err.Error(), "invalid_grant")
+}
+
+func TestClient_Refresh(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		require.Equal(t, "refresh", r.PostForm.Get("refresh_token"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "new access"})
+	})
+
+	token, err := client.Refresh(context.Background(), "refresh")
+	require.NoError(t, err)
+	require.Equal(t, "new access", token.AccessToken)
+	require.Equal(t, "refresh", token.RefreshToken)
+	require.True(t, token.ExpiresAt.IsZero())
+}
+
+func TestClient_Get(t *testing.T) {
+	srv, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id":1,"nickname":"user"}`))
+	})
+
+	var profile struct {
+		Id       int    `json:"id"`
+		Nickname string `json:"nickname"`
+	}
+	require.NoError(t, client.Get(context.Background(), srv.URL+"/api/users/whoami", Token{AccessToken: "access"}, &profile))
+	require.Equal(t, 1, profile.Id)
+	require.Equal(t, "user", profile.Nickname)
+
+	require.Error(t, client.Get(context.Background(), srv.URL+"/api/users/whoami", Token{AccessToken: "wrong"}, &profile))
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example of RFC 7636 appendix B.
+	require.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	verifier, err := NewRandomString()
+	require.NoError(t, err)
+	require.Len(t, verifier, 43)
+}
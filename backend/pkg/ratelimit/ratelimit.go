@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Full buckets are dropped not more often than this, so idle keys don't pile up in memory.
+const _defaultCleanupInterval = time.Minute
+
+// Result of one Allow call.
+type Result struct {
+	Allowed bool
+	// Bucket capacity.
+	Limit int
+	// Tokens left after this request.
+	Remaining int
+	// Time until next token, zero when request is allowed.
+	RetryAfter time.Duration
+	// Time until bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Limiter is in memory token bucket per key.
+// Every key may spend burst requests at once, bucket refills with rate tokens per second.
+type Limiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       int
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take one token from bucket of key.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+
+	res := Result{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.durationFor(1 - b.tokens)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.ResetAfter = l.durationFor(float64(l.burst) - b.tokens)
+
+	return res
+}
+
+func (b *bucket) refill(now time.Time, rate float64, burst int) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+}
+
+// Time needed to refill given amount of tokens.
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 || l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// Drop buckets that are full by now, new bucket for key will be the same.
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < _defaultCleanupInterval {
+		return
+	}
+	l.lastCleanup = now
+
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(rate, burst)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(1, 3)
+
+	for i := 2; i >= 0; i-- {
+		res := l.Allow("key")
+		require.True(t, res.Allowed)
+		require.Equal(t, 3, res.Limit)
+		require.Equal(t, i, res.Remaining)
+		require.Zero(t, res.RetryAfter)
+	}
+
+	res := l.Allow("key")
+	require.False(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+	require.Equal(t, time.Second, res.RetryAfter)
+	require.Equal(t, 3*time.Second, res.ResetAfter)
+
+	// Other keys have own bucket.
+	require.True(t, l.Allow("other").Allowed)
+
+	*now = now.Add(1500 * time.Millisecond)
+	res = l.Allow("key")
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+	require.Equal(t, 2500*time.Millisecond, res.ResetAfter)
+
+	res = l.Allow("key")
+	require.False(t, res.Allowed)
+	require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// Bucket doesn't grow over burst.
+	*now = now.Add(time.Hour)
+	res = l.Allow("key")
+	require.True(t, res.Allowed)
+	require.Equal(t, 2, res.Remaining)
+}
+
+func TestLimiter_cleanup(t *testing.T) {
+	l, now := newTestLimiter(1, 2)
+
+	l.Allow("idle")
+	*now = now.Add(_defaultCleanupInterval)
+	l.Allow("busy")
+	l.Allow("busy")
+
+	require.NotContains(t, l.buckets, "idle")
+	require.Contains(t, l.buckets, "busy")
+}
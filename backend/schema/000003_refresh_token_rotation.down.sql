@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_sessions_family_id_idx;
+
+ALTER TABLE users_sessions
+  DROP COLUMN IF EXISTS rotated,
+  DROP COLUMN IF EXISTS parent_token,
+  DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,6 @@
+ALTER TABLE users_sessions
+  ADD COLUMN IF NOT EXISTS family_id    UUID         NOT NULL DEFAULT UUID_GENERATE_V4(),
+  ADD COLUMN IF NOT EXISTS parent_token VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS rotated      BOOLEAN      NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS users_sessions_family_id_idx ON users_sessions (family_id);
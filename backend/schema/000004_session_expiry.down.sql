@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS codes_expires_at_idx;
+DROP INDEX IF EXISTS users_sessions_expires_at_idx;
+
+ALTER TABLE users_sessions
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS created_at;
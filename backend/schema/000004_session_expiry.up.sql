@@ -0,0 +1,8 @@
+ALTER TABLE users_sessions
+  ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc') + INTERVAL '30 days';
+
+ALTER TABLE users_sessions ALTER COLUMN expires_at DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS users_sessions_expires_at_idx ON users_sessions (expires_at);
+CREATE INDEX IF NOT EXISTS codes_expires_at_idx ON codes (expires_at);
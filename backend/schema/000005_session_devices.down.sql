@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_sessions_user_id_idx;
+
+ALTER TABLE users_sessions
+  DROP COLUMN IF EXISTS ip,
+  DROP COLUMN IF EXISTS user_agent,
+  DROP COLUMN IF EXISTS last_used_at;
@@ -0,0 +1,6 @@
+ALTER TABLE users_sessions
+  ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP    NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+  ADD COLUMN IF NOT EXISTS user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS ip           VARCHAR(45)  NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS users_sessions_user_id_idx ON users_sessions (user_id);
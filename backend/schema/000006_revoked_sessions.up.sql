@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS revoked_sessions
+(
+  session_id UUID      PRIMARY KEY,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_sessions_expires_at_idx ON revoked_sessions (expires_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login_lockouts
+(
+  key             VARCHAR(512) PRIMARY KEY,
+  failures        INTEGER      NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP    NOT NULL,
+  locked_until    TIMESTAMP    NOT NULL DEFAULT 'epoch',
+  expires_at      TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_lockouts_expires_at_idx ON login_lockouts (expires_at);
@@ -0,0 +1,5 @@
+DELETE FROM codes WHERE code_type = 'mfaRecovery';
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS totp_secret,
+  DROP COLUMN IF EXISTS totp_enabled;
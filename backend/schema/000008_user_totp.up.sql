@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret  VARCHAR(64) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN     NOT NULL DEFAULT FALSE;
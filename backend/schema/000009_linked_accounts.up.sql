@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS linked_accounts
+(
+  user_id          UUID         REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+  provider         VARCHAR(32)  NOT NULL,
+  provider_user_id VARCHAR(255) NOT NULL,
+  username         VARCHAR(255) NOT NULL DEFAULT '',
+  access_token     TEXT         NOT NULL DEFAULT '',
+  refresh_token    TEXT         NOT NULL DEFAULT '',
+  token_expires_at TIMESTAMP    NOT NULL DEFAULT 'epoch',
+  created_at       TIMESTAMP    NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+  updated_at       TIMESTAMP    NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+  PRIMARY KEY (provider, provider_user_id),
+  UNIQUE (user_id, provider)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_states
+(
+  state         VARCHAR(64)  PRIMARY KEY,
+  provider      VARCHAR(32)  NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  expires_at    TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS oauth_states_expires_at_idx ON oauth_states (expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP NOT NULL DEFAULT 'epoch';
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(username));
@@ -0,0 +1,9 @@
+DELETE FROM codes WHERE code_type = 'emailChange';
+
+ALTER TABLE codes
+  DROP CONSTRAINT IF EXISTS codes_user_email_fkey,
+  ADD CONSTRAINT codes_user_email_fkey FOREIGN KEY (user_email)
+    REFERENCES users (email) ON DELETE CASCADE;
+
+ALTER TABLE codes
+  DROP COLUMN IF EXISTS new_email;
@@ -0,0 +1,10 @@
+-- Pending new email of emailChange code. Code is bound to current email
+-- of user and sent to new one.
+ALTER TABLE codes
+  ADD COLUMN IF NOT EXISTS new_email CITEXT NOT NULL DEFAULT '';
+
+-- Codes follow user when email is changed.
+ALTER TABLE codes
+  DROP CONSTRAINT IF EXISTS codes_user_email_fkey,
+  ADD CONSTRAINT codes_user_email_fkey FOREIGN KEY (user_email)
+    REFERENCES users (email) ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,6 @@
+DELETE FROM codes WHERE code_type = 'deletionCancel';
+
+DROP INDEX IF EXISTS users_delete_at_idx;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS delete_at;
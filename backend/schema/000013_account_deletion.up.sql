@@ -0,0 +1,6 @@
+-- Epoch unless user scheduled account deletion. Sweeper deletes user after delete_at,
+-- so every table referencing users must cascade on delete.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS delete_at TIMESTAMP NOT NULL DEFAULT 'epoch';
+
+CREATE INDEX IF NOT EXISTS users_delete_at_idx ON users (delete_at) WHERE delete_at > 'epoch';
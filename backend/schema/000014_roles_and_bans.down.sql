@@ -0,0 +1,4 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS ban_reason,
+  DROP COLUMN IF EXISTS banned_at,
+  DROP COLUMN IF EXISTS role;
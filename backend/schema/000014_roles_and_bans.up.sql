@@ -0,0 +1,7 @@
+-- Role is carried in access token claims, so change takes effect on next token refresh.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+    CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator', 'admin')),
+  -- Epoch unless user is banned.
+  ADD COLUMN IF NOT EXISTS banned_at TIMESTAMP NOT NULL DEFAULT 'epoch',
+  ADD COLUMN IF NOT EXISTS ban_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- Last accepted TOTP time step, codes of it and earlier steps are rejected.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- Ids (jti) of exchanged mfa pending tokens, kept until tokens expire.
+CREATE TABLE IF NOT EXISTS used_mfa_tokens
+(
+  jti        TEXT      PRIMARY KEY,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS used_mfa_tokens_expires_at_idx ON used_mfa_tokens (expires_at);
@@ -0,0 +1,7 @@
+// Package schema embeds SQL migrations, so binary doesn't depend on working directory.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS